@@ -0,0 +1,390 @@
+package cli
+
+import (
+  "context"
+  "encoding/json"
+  "errors"
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "path"
+  "strings"
+  "time"
+  reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/werkt/bf-client/client"
+  "google.golang.org/genproto/googleapis/longrunning"
+)
+
+type env struct {
+  a *client.App
+  out *printer
+  name string
+  filter string
+  count int64
+  prequeue bool
+  listen string
+  alertListen string
+  interval time.Duration
+}
+
+type command struct {
+  words []string
+  args []string
+  // list commands print a json array, or one ndjson line per entry
+  list bool
+  redis bool
+  // offline commands need no connections
+  offline bool
+  run func(e *env, args []string) error
+}
+
+var commands = []*command {
+  { words: []string { "status" }, run: status },
+  { words: []string { "ops", "list" }, list: true, run: listOps },
+  { words: []string { "op", "get" }, args: []string { "name" }, run: getOp },
+  { words: []string { "worker", "profile" }, args: []string { "host" }, run: workerProfile },
+  { words: []string { "blob", "cat" }, args: []string { "digest" }, run: catBlob },
+  { words: []string { "tree" }, args: []string { "digest" }, list: true, run: tree },
+  { words: []string { "queue", "peek" }, list: true, redis: true, run: peekQueue },
+  { words: []string { "serve-metrics" }, run: serveMetrics },
+  { words: []string { "alert-listen" }, offline: true, run: listenAlerts },
+}
+
+// IsCommand reports whether name starts a non-interactive command
+func IsCommand(name string) bool {
+  for _, c := range commands {
+    if c.words[0] == name {
+      return true
+    }
+  }
+  return false
+}
+
+func lookup(args []string) (*command, []string) {
+  for _, c := range commands {
+    if len(args) >= len(c.words) && strings.Join(args[:len(c.words)], " ") == strings.Join(c.words, " ") {
+      return c, args[len(c.words):]
+    }
+  }
+  return nil, nil
+}
+
+func usage(w io.Writer) {
+  fmt.Fprintln(w, "usage: bf-client <command> [flags] [args]")
+  fmt.Fprintln(w, "commands:")
+  for _, c := range commands {
+    var args []string
+    for _, arg := range c.args {
+      args = append(args, "<" + arg + ">")
+    }
+    fmt.Fprintf(w, "  %s\n", strings.Join(append(c.words, args...), " "))
+  }
+}
+
+// parse collects positional arguments interleaved with flags
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+  var positional []string
+  for {
+    if err := fs.Parse(args); err != nil {
+      return nil, err
+    }
+    args = fs.Args()
+    if len(args) == 0 {
+      return positional, nil
+    }
+    positional = append(positional, args[0])
+    args = args[1:]
+  }
+}
+
+// Run executes the command named by args, returning the process exit code
+func Run(args []string) int {
+  c, args := lookup(args)
+  if c == nil {
+    usage(os.Stderr)
+    return 2
+  }
+  name := strings.Join(c.words, " ")
+
+  fs := flag.NewFlagSet(name, flag.ContinueOnError)
+  redisHost := fs.String("redis", os.Getenv("BF_REDIS"), "redis backplane host")
+  reapiHost := fs.String("reapi", os.Getenv("BF_REAPI"), "reapi host, grpcs:// for tls")
+  ca := fs.String("ca", os.Getenv("BF_CA"), "ca certificate for tls")
+  instance := fs.String("instance", "shard", "instance name")
+  format := fs.String("o", "table", "output format: table, json or ndjson")
+  e := &env {}
+  fs.StringVar(&e.name, "name", "executions", "operations collection")
+  fs.StringVar(&e.filter, "filter", "", "operations filter")
+  fs.Int64Var(&e.count, "count", 10, "entries to peek from each queue")
+  fs.BoolVar(&e.prequeue, "prequeue", false, "peek the prequeue instead of the operation queue")
+  fs.StringVar(&e.listen, "listen", ":9090", "metrics listen address")
+  fs.StringVar(&e.alertListen, "alert-listen", ":9095", "alert webhook listen address")
+  fs.DurationVar(&e.interval, "interval", 15 * time.Second, "metrics polling interval")
+
+  positional, err := parse(fs, args)
+  if err == flag.ErrHelp {
+    return 0
+  }
+  if err != nil {
+    return 2
+  }
+  if e.interval <= 0 {
+    fmt.Fprintf(os.Stderr, "%s: -interval must be positive, got %v\n", name, e.interval)
+    return 2
+  }
+  if len(positional) != len(c.args) {
+    fmt.Fprintf(os.Stderr, "%s: expected %d arguments, got %d\n", name, len(c.args), len(positional))
+    usage(os.Stderr)
+    return 2
+  }
+  if !c.offline && (*reapiHost == "" || (c.redis && *redisHost == "")) {
+    fmt.Fprintf(os.Stderr, "%s: -reapi (and -redis for queues) or BF_REAPI and BF_REDIS are required\n", name)
+    return 2
+  }
+
+  e.out, err = newPrinter(os.Stdout, *format, c.list)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+    return 2
+  }
+
+  e.a = client.NewApp(*redisHost, *reapiHost, *ca)
+  e.a.Instance = *instance
+  if c.redis {
+    e.a.Connect()
+  } else if !c.offline {
+    e.a.ConnectReapi()
+  }
+  if e.a.Conn != nil {
+    defer e.a.Conn.Close()
+  }
+
+  if err = c.run(e, positional); err == nil {
+    err = e.out.flush()
+  }
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+    return 1
+  }
+  return 0
+}
+
+func status(e *env, args []string) error {
+  st, err := client.BackplaneStatus(context.Background(), e.a.Conn, e.a.Instance)
+  if err != nil {
+    return err
+  }
+  if e.out.json() {
+    return e.out.emit(st)
+  }
+  e.out.row("QUEUE", "SIZE")
+  e.out.row("prequeue", st.Prequeue.Size)
+  e.out.row("queue", st.OperationQueue.Size)
+  for _, provision := range st.OperationQueue.Provisions {
+    e.out.row("  " + provision.Name, provision.Size)
+  }
+  e.out.row("dispatched", st.DispatchedSize)
+  e.out.row("execute workers", len(st.ActiveExecuteWorkers))
+  e.out.row("storage workers", len(st.ActiveStorageWorkers))
+  return nil
+}
+
+func stageName(op *longrunning.Operation) string {
+  if op.Done {
+    return "COMPLETED"
+  }
+  em, err := client.ExecuteOperationMetadata(op)
+  if err != nil || em == nil {
+    return "UNKNOWN"
+  }
+  return em.Stage.String()
+}
+
+func opRow(e *env, op *longrunning.Operation) {
+  rm := client.RequestMetadata(op)
+  if rm == nil {
+    rm = &reapi.RequestMetadata{}
+  }
+  e.out.row(op.Name, stageName(op), rm.ToolInvocationId, rm.TargetId, rm.ActionMnemonic)
+}
+
+func listOps(e *env, args []string) error {
+  if !e.out.json() {
+    e.out.row("NAME", "STAGE", "INVOCATION", "TARGET", "MNEMONIC")
+  }
+  return client.ListOperations(e.a.Conn, fmt.Sprintf("%s/%s", e.a.Instance, e.name), e.filter, func(op *longrunning.Operation) error {
+    if e.out.json() {
+      return e.out.emit(op)
+    }
+    opRow(e, op)
+    return nil
+  })
+}
+
+func getOp(e *env, args []string) error {
+  op, err := client.GetOperation(e.a.Conn, args[0])
+  if err != nil {
+    return err
+  }
+  if e.out.json() {
+    return e.out.emit(op)
+  }
+  e.out.row("NAME", "STAGE", "INVOCATION", "TARGET", "MNEMONIC")
+  opRow(e, op)
+  return nil
+}
+
+func workerProfile(e *env, args []string) error {
+  profile, err := client.WorkerProfile(context.Background(), e.a.GetWorkerConn(args[0], e.a.CA))
+  if err != nil {
+    return err
+  }
+  if e.out.json() {
+    return e.out.emit(profile)
+  }
+  e.out.row("STAGE", "USED", "CONFIGURED", "OPERATIONS")
+  for _, stage := range profile.Stages {
+    e.out.row(stage.Name, stage.SlotsUsed, stage.SlotsConfigured, len(stage.OperationNames))
+  }
+  e.out.row()
+  e.out.row("CAS", "ENTRIES", "SIZE", "MAX")
+  e.out.row("", profile.CasEntryCount, profile.CasSize, profile.CasMaxSize)
+  return nil
+}
+
+func parseDigest(s string) (d bfpb.Digest, err error) {
+  if strings.Count(s, "/") < 1 || strings.Count(s, "/") > 2 {
+    return d, errors.New("digest must be [function/]hash/size: " + s)
+  }
+  return client.ParseDigest(s), nil
+}
+
+func catBlob(e *env, args []string) error {
+  d, err := parseDigest(args[0])
+  if err != nil {
+    return err
+  }
+  b, err := client.ReadBlob(e.a.Conn, d)
+  if err != nil {
+    return err
+  }
+  _, err = os.Stdout.Write(b)
+  return err
+}
+
+type treeEntry struct {
+  Path string `json:"path"`
+  Type string `json:"type"`
+  Digest string `json:"digest,omitempty"`
+  Target string `json:"target,omitempty"`
+  Executable bool `json:"executable,omitempty"`
+}
+
+func walk(dirs map[string]*reapi.Directory, d bfpb.Digest, prefix string, cb func(treeEntry) error) error {
+  dir := dirs[client.DigestString(d)]
+  if dir == nil {
+    return errors.New("missing directory " + client.DigestString(d))
+  }
+  for _, f := range dir.Files {
+    entry := treeEntry {
+      Path: path.Join(prefix, f.Name),
+      Type: "file",
+      Digest: client.DigestString(client.ToDigest(*f.Digest, d.DigestFunction)),
+      Executable: f.IsExecutable,
+    }
+    if err := cb(entry); err != nil {
+      return err
+    }
+  }
+  for _, s := range dir.Symlinks {
+    if err := cb(treeEntry { Path: path.Join(prefix, s.Name), Type: "symlink", Target: s.Target }); err != nil {
+      return err
+    }
+  }
+  for _, sub := range dir.Directories {
+    subDigest := client.ToDigest(*sub.Digest, d.DigestFunction)
+    entry := treeEntry {
+      Path: path.Join(prefix, sub.Name),
+      Type: "directory",
+      Digest: client.DigestString(subDigest),
+    }
+    if err := cb(entry); err != nil {
+      return err
+    }
+    if err := walk(dirs, subDigest, entry.Path, cb); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+func tree(e *env, args []string) error {
+  d, err := parseDigest(args[0])
+  if err != nil {
+    return err
+  }
+  dirs := make(map[string]*reapi.Directory)
+  if err := client.FetchTree(d, dirs, e.a.Conn); err != nil {
+    return err
+  }
+  if !e.out.json() {
+    e.out.row("TYPE", "DIGEST", "PATH")
+  }
+  return walk(dirs, d, "", func(entry treeEntry) error {
+    if e.out.json() {
+      return e.out.emit(entry)
+    }
+    p := entry.Path
+    if entry.Type == "symlink" {
+      p += " -> " + entry.Target
+    } else if entry.Executable {
+      p += "*"
+    }
+    e.out.row(entry.Type, entry.Digest, p)
+    return nil
+  })
+}
+
+func peekQueue(e *env, args []string) error {
+  st, err := client.BackplaneStatus(context.Background(), e.a.Conn, e.a.Instance)
+  if err != nil {
+    return err
+  }
+  parse := client.ParseQueueName
+  if e.prequeue {
+    parse = client.ParsePrequeueName
+  }
+  if !e.out.json() {
+    e.out.row("QUEUE", "NAME", "INVOCATION", "TARGET", "MNEMONIC")
+  }
+  ctx := context.Background()
+  for _, name := range client.QueueNames(st, e.prequeue) {
+    q := client.NewQueue(ctx, e.a.Client, name)
+    entries, err := q.Slice(ctx, e.a.Client, 0, e.count - 1, parse)
+    if err != nil {
+      return err
+    }
+    for _, entry := range entries {
+      if entry.Err != nil {
+        // the rest of the queue is still worth listing
+        fmt.Fprintf(os.Stderr, "%s: %s: %v\n", name, entry.String(), entry.Err)
+        continue
+      }
+      if e.out.json() {
+        if err := e.out.emit(json.RawMessage(entry.Value)); err != nil {
+          return err
+        }
+        continue
+      }
+      op := entry.Operation
+      rm := op.Metadata
+      if rm == nil {
+        rm = &reapi.RequestMetadata{}
+      }
+      e.out.row(name, op.Name, rm.ToolInvocationId, rm.TargetId, rm.ActionMnemonic)
+    }
+  }
+  return nil
+}
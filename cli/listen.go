@@ -0,0 +1,32 @@
+package cli
+
+import (
+  "bytes"
+  "encoding/json"
+  "io"
+  "log"
+  "net/http"
+  "sync"
+)
+
+// listenAlerts stands in for an alert webhook, printing every notification
+// posted to it
+func listenAlerts(e *env, args []string) error {
+  // handlers run concurrently, printing one notification at a time
+  var mutex sync.Mutex
+  mux := http.NewServeMux()
+  mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+    body, err := io.ReadAll(r.Body)
+    if err != nil || !json.Valid(body) {
+      http.Error(w, "expected a json notification", http.StatusBadRequest)
+      return
+    }
+    mutex.Lock()
+    defer mutex.Unlock()
+    if err := e.out.emit(json.RawMessage(bytes.TrimSpace(body))); err != nil {
+      log.Printf("alert: %v", err)
+    }
+  })
+  log.Printf("listening for alerts on %s", e.alertListen)
+  return http.ListenAndServe(e.alertListen, mux)
+}
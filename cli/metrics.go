@@ -0,0 +1,188 @@
+package cli
+
+import (
+  "context"
+  "fmt"
+  "io"
+  "log"
+  "net/http"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/werkt/bf-client/client"
+  "google.golang.org/grpc"
+)
+
+type sample struct {
+  labels string
+  value float64
+}
+
+type metric struct {
+  name string
+  help string
+  samples []sample
+}
+
+// metrics is a snapshot of the backplane and its workers, rendered in the
+// prometheus text exposition format
+type metrics struct {
+  mutex sync.Mutex
+  families []*metric
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(kv ...string) string {
+  var l []string
+  for i := 0; i + 1 < len(kv); i += 2 {
+    l = append(l, fmt.Sprintf(`%s="%s"`, kv[i], labelEscaper.Replace(kv[i + 1])))
+  }
+  if len(l) == 0 {
+    return ""
+  }
+  return "{" + strings.Join(l, ",") + "}"
+}
+
+type gatherer struct {
+  families []*metric
+  byName map[string]*metric
+}
+
+func (g *gatherer) add(name string, help string, value float64, kv ...string) {
+  if g.byName == nil {
+    g.byName = make(map[string]*metric)
+  }
+  m := g.byName[name]
+  if m == nil {
+    m = &metric { name: name, help: help }
+    g.byName[name] = m
+    g.families = append(g.families, m)
+  }
+  m.samples = append(m.samples, sample { labels: labels(kv...), value: value })
+}
+
+func gatherStatus(g *gatherer, st *bfpb.BackplaneStatus) {
+  g.add("bf_prequeue_size", "Operations in the prequeue.", float64(st.Prequeue.Size))
+  g.add("bf_queue_size", "Operations in the operation queue.", float64(st.OperationQueue.Size))
+  for _, provision := range st.OperationQueue.Provisions {
+    g.add("bf_provision_queue_size", "Operations in each provision of the operation queue.", float64(provision.Size), "provision", provision.Name)
+    for i, size := range provision.InternalSizes {
+      g.add("bf_provision_internal_queue_size", "Operations in each internal queue of a provision.", float64(size), "provision", provision.Name, "queue", fmt.Sprint(i))
+    }
+  }
+  g.add("bf_dispatched_size", "Dispatched operations.", float64(st.DispatchedSize))
+  g.add("bf_active_execute_workers", "Execute workers registered with the backplane.", float64(len(st.ActiveExecuteWorkers)))
+  g.add("bf_active_storage_workers", "Storage workers registered with the backplane.", float64(len(st.ActiveStorageWorkers)))
+}
+
+func gatherProfile(g *gatherer, worker string, profile *bfpb.WorkerProfileMessage) {
+  for _, stage := range profile.Stages {
+    g.add("bf_worker_stage_slots_used", "Slots in use for each worker pipeline stage.", float64(stage.SlotsUsed), "worker", worker, "stage", stage.Name)
+    g.add("bf_worker_stage_slots_configured", "Slots configured for each worker pipeline stage.", float64(stage.SlotsConfigured), "worker", worker, "stage", stage.Name)
+  }
+  g.add("bf_worker_cas_size_bytes", "Bytes stored in the worker CAS.", float64(profile.CasSize), "worker", worker)
+  g.add("bf_worker_cas_max_size_bytes", "Capacity of the worker CAS in bytes.", float64(profile.CasMaxSize), "worker", worker)
+  g.add("bf_worker_cas_entries", "Entries in the worker CAS.", float64(profile.CasEntryCount), "worker", worker)
+  g.add("bf_worker_cas_unreferenced_entries", "Unreferenced entries in the worker CAS.", float64(profile.CasUnreferencedEntryCount), "worker", worker)
+  g.add("bf_worker_cas_directory_entries", "Directory entries in the worker CAS.", float64(profile.CasDirectoryEntryCount), "worker", worker)
+  g.add("bf_worker_cas_evicted_entries", "Entries evicted from the worker CAS.", float64(profile.CasEvictedEntryCount), "worker", worker)
+  g.add("bf_worker_cas_evicted_bytes", "Bytes evicted from the worker CAS.", float64(profile.CasEvictedEntrySize), "worker", worker)
+}
+
+type profileResult struct {
+  worker string
+  profile *bfpb.WorkerProfileMessage
+  err error
+}
+
+func (m *metrics) poll(e *env, timeout time.Duration) {
+  g := &gatherer {}
+  start := time.Now()
+  // a stuck backplane fails the poll instead of holding every later one
+  ctx, cancel := context.WithTimeout(context.Background(), timeout)
+  st, err := client.BackplaneStatus(ctx, e.a.Conn, e.a.Instance)
+  cancel()
+  g.add("bf_status_latency_seconds", "Duration of the backplane status request.", time.Since(start).Seconds())
+  if err != nil {
+    log.Printf("status: %v", err)
+    g.add("bf_status_up", "Whether the backplane status request succeeded.", 0)
+    m.set(g)
+    return
+  }
+  g.add("bf_status_up", "Whether the backplane status request succeeded.", 1)
+  gatherStatus(g, st)
+  // workers that left the backplane are not polled again
+  e.a.CloseWorkerConns(st.ActiveExecuteWorkers)
+
+  results := make([]profileResult, len(st.ActiveExecuteWorkers))
+  var wg sync.WaitGroup
+  for i, worker := range st.ActiveExecuteWorkers {
+    wg.Add(1)
+    results[i].worker = worker
+    go func(r *profileResult, conn *grpc.ClientConn) {
+      defer wg.Done()
+      ctx, cancel := context.WithTimeout(context.Background(), timeout)
+      defer cancel()
+      r.profile, r.err = client.WorkerProfile(ctx, conn)
+    }(&results[i], e.a.GetWorkerConn(worker, e.a.CA))
+  }
+  wg.Wait()
+  sort.Slice(results, func(i, j int) bool { return results[i].worker < results[j].worker })
+  for _, r := range results {
+    if r.err != nil {
+      g.add("bf_worker_up", "Whether the worker answered its profile request.", 0, "worker", r.worker)
+    } else {
+      g.add("bf_worker_up", "Whether the worker answered its profile request.", 1, "worker", r.worker)
+    }
+  }
+  for _, r := range results {
+    if r.err == nil {
+      gatherProfile(g, r.worker, r.profile)
+    }
+  }
+  m.set(g)
+}
+
+func (m *metrics) set(g *gatherer) {
+  m.mutex.Lock()
+  m.families = g.families
+  m.mutex.Unlock()
+}
+
+func (m *metrics) write(w io.Writer) {
+  m.mutex.Lock()
+  defer m.mutex.Unlock()
+  for _, f := range m.families {
+    fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+    fmt.Fprintf(w, "# TYPE %s gauge\n", f.name)
+    for _, s := range f.samples {
+      fmt.Fprintf(w, "%s%s %v\n", f.name, s.labels, s.value)
+    }
+  }
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+  m.write(w)
+}
+
+// serveMetrics polls the backplane and every execute worker each interval,
+// serving the last results on /metrics
+func serveMetrics(e *env, args []string) error {
+  m := &metrics {}
+  timeout := e.interval / 2
+  m.poll(e, timeout)
+  go func() {
+    for range time.Tick(e.interval) {
+      m.poll(e, timeout)
+    }
+  }()
+
+  mux := http.NewServeMux()
+  mux.Handle("/metrics", m)
+  log.Printf("serving metrics on %s/metrics", e.listen)
+  return http.ListenAndServe(e.listen, mux)
+}
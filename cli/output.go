@@ -0,0 +1,104 @@
+package cli
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "io"
+  "strings"
+  "text/tabwriter"
+  "github.com/golang/protobuf/jsonpb"
+  "github.com/golang/protobuf/proto"
+)
+
+// printer writes command results as an aligned table, a json document or
+// newline delimited json
+type printer struct {
+  format string
+  list bool
+  w io.Writer
+  tw *tabwriter.Writer
+  values []json.RawMessage
+}
+
+func newPrinter(w io.Writer, format string, list bool) (*printer, error) {
+  switch format {
+  case "table", "json", "ndjson":
+  default:
+    return nil, fmt.Errorf("unknown output format %q", format)
+  }
+  return &printer {
+    format: format,
+    list: list,
+    w: w,
+    tw: tabwriter.NewWriter(w, 0, 8, 2, ' ', 0),
+  }, nil
+}
+
+func (p *printer) json() bool {
+  return p.format != "table"
+}
+
+func marshal(v interface{}) (json.RawMessage, error) {
+  if m, ok := v.(proto.Message); ok {
+    s, err := (&jsonpb.Marshaler{}).MarshalToString(m)
+    return json.RawMessage(s), err
+  }
+  if r, ok := v.(json.RawMessage); ok {
+    return r, nil
+  }
+  return json.Marshal(v)
+}
+
+// emit writes v as a json value, protos through jsonpb
+func (p *printer) emit(v interface{}) error {
+  b, err := marshal(v)
+  if err != nil {
+    return err
+  }
+  if p.format == "json" && p.list {
+    p.values = append(p.values, b)
+    return nil
+  }
+  if p.format == "json" {
+    return p.indent(b)
+  }
+  _, err = fmt.Fprintf(p.w, "%s\n", compact(b))
+  return err
+}
+
+func compact(b json.RawMessage) []byte {
+  var buf bytes.Buffer
+  if err := json.Compact(&buf, b); err != nil {
+    return b
+  }
+  return buf.Bytes()
+}
+
+func (p *printer) indent(v interface{}) error {
+  b, err := json.MarshalIndent(v, "", "  ")
+  if err != nil {
+    return err
+  }
+  _, err = fmt.Fprintf(p.w, "%s\n", b)
+  return err
+}
+
+// row writes one line of the table output
+func (p *printer) row(columns ...interface{}) {
+  var s []string
+  for _, c := range columns {
+    s = append(s, fmt.Sprint(c))
+  }
+  fmt.Fprintln(p.tw, strings.Join(s, "\t"))
+}
+
+func (p *printer) flush() error {
+  if p.format == "json" && p.list {
+    if p.values == nil {
+      p.values = []json.RawMessage{}
+    }
+    return p.indent(p.values)
+  }
+  return p.tw.Flush()
+}
@@ -0,0 +1,396 @@
+package client
+
+import (
+  "bytes"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "net/http"
+  "os"
+  "os/exec"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+)
+
+type ruleKind int
+
+const (
+  thresholdRule ruleKind = iota
+  staleRule
+  pausedRule
+  stalledRule
+)
+
+// Rule is one alert condition, held for For before it fires:
+//
+//   <series> <op> <value> [for <duration>]    a sampled series, e.g. queue
+//   worker [<name>] stale [for <duration>]    a worker not answering profiles
+//   stage <stage> paused [on <name>]          a worker pipeline stage paused
+//   operation <stage> > <duration>            an operation held in a stage
+type Rule struct {
+  Text string
+  For time.Duration
+  kind ruleKind
+  series string
+  op string
+  threshold float64
+  worker string
+  stage string
+  stall time.Duration
+}
+
+var ruleOps = []string{">", ">=", "<", "<=", "=="}
+
+func ParseRule(text string) (*Rule, error) {
+  r := &Rule { Text: text }
+  fields := strings.Fields(text)
+  // trailing hold duration
+  if n := len(fields); n > 2 && fields[n - 2] == "for" {
+    d, err := time.ParseDuration(fields[n - 1])
+    if err != nil {
+      return nil, err
+    }
+    r.For = d
+    fields = fields[:n - 2]
+  }
+  invalid := errors.New("invalid rule: " + text)
+  if len(fields) == 0 {
+    return nil, invalid
+  }
+  switch {
+  case fields[0] == "worker" && fields[len(fields) - 1] == "stale":
+    r.kind = staleRule
+    if len(fields) == 3 {
+      r.worker = fields[1]
+    } else if len(fields) != 2 {
+      return nil, invalid
+    }
+  case fields[0] == "stage" && len(fields) >= 3 && fields[2] == "paused":
+    r.kind = pausedRule
+    r.stage = fields[1]
+    if len(fields) == 5 && fields[3] == "on" {
+      r.worker = fields[4]
+    } else if len(fields) != 3 {
+      return nil, invalid
+    }
+  case fields[0] == "operation" && len(fields) == 4 && fields[2] == ">":
+    r.kind = stalledRule
+    r.stage = fields[1]
+    d, err := time.ParseDuration(fields[3])
+    if err != nil {
+      return nil, err
+    }
+    r.stall = d
+  case len(fields) == 3:
+    r.kind = thresholdRule
+    r.series = fields[0]
+    r.op = fields[1]
+    if !contains(ruleOps, r.op) {
+      return nil, invalid
+    }
+    v, err := strconv.ParseFloat(fields[2], 64)
+    if err != nil {
+      return nil, err
+    }
+    r.threshold = v
+  default:
+    return nil, invalid
+  }
+  return r, nil
+}
+
+func contains(s []string, v string) bool {
+  for _, e := range s {
+    if e == v {
+      return true
+    }
+  }
+  return false
+}
+
+func (r *Rule) matchesWorker(name string) bool {
+  return r.worker == "" || r.worker == "*" || r.worker == name
+}
+
+type WorkerObservation struct {
+  // Stale workers failed their last health probe
+  Stale bool
+  Profile *bfpb.WorkerProfileMessage
+  // Paused stages, when the pipeline was queried
+  Paused map[string]bool
+}
+
+// Observation is the data collected by one update that rules are checked
+// against. Workers is nil when they were not profiled, leaving worker rules
+// as they were.
+type Observation struct {
+  Values map[string]float64
+  Workers map[string]*WorkerObservation
+}
+
+type Alert struct {
+  Rule *Rule
+  // Since the condition first held
+  Since time.Time
+  Firing bool
+  Detail string
+}
+
+type Alerts struct {
+  config *Config
+  alerts map[*Rule]*Alert
+  // entered records when each worker/stage/operation was first seen
+  entered map[string]time.Time
+  // Bell rings the terminal bell as an alert fires, set by the caller that
+  // owns the terminal, without blocking
+  Bell func()
+  mutex sync.Mutex
+  lastError error
+}
+
+func NewAlerts(c *Config) *Alerts {
+  return &Alerts {
+    config: c,
+    alerts: make(map[*Rule]*Alert),
+    entered: make(map[string]time.Time),
+  }
+}
+
+// NeedsWorkers reports whether any rule reads worker profiles
+func (a *Alerts) NeedsWorkers() bool {
+  for _, r := range a.config.Rules {
+    if r.kind != thresholdRule {
+      return true
+    }
+  }
+  return false
+}
+
+// NeedsHealth reports whether any rule reads the health of the workers
+func (a *Alerts) NeedsHealth() bool {
+  for _, r := range a.config.Rules {
+    if r.kind == staleRule {
+      return true
+    }
+  }
+  return false
+}
+
+// NeedsPipeline reports whether any rule reads paused stages
+func (a *Alerts) NeedsPipeline() bool {
+  for _, r := range a.config.Rules {
+    if r.kind == pausedRule {
+      return true
+    }
+  }
+  return false
+}
+
+func compare(v float64, op string, threshold float64) bool {
+  switch op {
+  case ">": return v > threshold
+  case ">=": return v >= threshold
+  case "<": return v < threshold
+  case "<=": return v <= threshold
+  }
+  return v == threshold
+}
+
+func sortedWorkers(workers map[string]*WorkerObservation) []string {
+  var names []string
+  for name := range workers {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+// check reports whether r holds in o, and which values made it hold
+func (a *Alerts) check(r *Rule, o Observation, now time.Time) (bool, string) {
+  switch r.kind {
+  case thresholdRule:
+    v, ok := o.Values[r.series]
+    if ok && compare(v, r.op, r.threshold) {
+      return true, fmt.Sprintf("%s = %s", r.series, strconv.FormatFloat(v, 'f', -1, 64))
+    }
+  case staleRule:
+    var stale []string
+    for _, name := range sortedWorkers(o.Workers) {
+      if r.matchesWorker(name) && o.Workers[name].Stale {
+        stale = append(stale, name)
+      }
+    }
+    if len(stale) > 0 {
+      return true, strings.Join(stale, ", ")
+    }
+  case pausedRule:
+    var paused []string
+    for _, name := range sortedWorkers(o.Workers) {
+      if r.matchesWorker(name) && o.Workers[name].Paused[r.stage] {
+        paused = append(paused, name)
+      }
+    }
+    if len(paused) > 0 {
+      return true, strings.Join(paused, ", ")
+    }
+  case stalledRule:
+    var stalled []string
+    for _, name := range sortedWorkers(o.Workers) {
+      for _, op := range a.stageOperations(name, o.Workers[name], r.stage, now) {
+        if now.Sub(a.entered[name + "/" + r.stage + "/" + op]) > r.stall {
+          stalled = append(stalled, op + " on " + name)
+        }
+      }
+    }
+    if len(stalled) > 0 {
+      return true, strings.Join(stalled, ", ")
+    }
+  }
+  return false, ""
+}
+
+func (a *Alerts) stageOperations(worker string, w *WorkerObservation, stage string, now time.Time) []string {
+  if w.Profile == nil {
+    return nil
+  }
+  for _, s := range w.Profile.Stages {
+    if s.Name == stage {
+      for _, op := range s.OperationNames {
+        key := worker + "/" + stage + "/" + op
+        if _, ok := a.entered[key]; !ok {
+          a.entered[key] = now
+        }
+      }
+      return s.OperationNames
+    }
+  }
+  return nil
+}
+
+// forget drops the entry times of operations no longer in their stage
+func (a *Alerts) forget(o Observation) {
+  present := make(map[string]bool)
+  for name, w := range o.Workers {
+    if w.Profile == nil {
+      continue
+    }
+    for _, s := range w.Profile.Stages {
+      for _, op := range s.OperationNames {
+        present[name + "/" + s.Name + "/" + op] = true
+      }
+    }
+  }
+  for key := range a.entered {
+    if !present[key] {
+      delete(a.entered, key)
+    }
+  }
+}
+
+// Evaluate checks every rule against o, notifying as alerts fire and resolve
+func (a *Alerts) Evaluate(now time.Time, o Observation) {
+  a.mutex.Lock()
+  defer a.mutex.Unlock()
+  for _, r := range a.config.Rules {
+    if r.kind != thresholdRule && o.Workers == nil {
+      continue
+    }
+    held, detail := a.check(r, o, now)
+    alert := a.alerts[r]
+    if !held {
+      if alert != nil && alert.Firing {
+        a.notify(alert, "resolved")
+      }
+      delete(a.alerts, r)
+      continue
+    }
+    if alert == nil {
+      alert = &Alert { Rule: r, Since: now }
+      a.alerts[r] = alert
+    }
+    alert.Detail = detail
+    if !alert.Firing && now.Sub(alert.Since) >= r.For {
+      alert.Firing = true
+      a.notify(alert, "firing")
+    }
+  }
+  if o.Workers != nil {
+    a.forget(o)
+  }
+}
+
+// Firing lists the alerts currently firing in the order of their rules
+func (a *Alerts) Firing() []*Alert {
+  a.mutex.Lock()
+  defer a.mutex.Unlock()
+  var firing []*Alert
+  for _, r := range a.config.Rules {
+    if alert := a.alerts[r]; alert != nil && alert.Firing {
+      firing = append(firing, alert)
+    }
+  }
+  return firing
+}
+
+type notification struct {
+  Alert string `json:"alert"`
+  State string `json:"state"`
+  Since time.Time `json:"since"`
+  Detail string `json:"detail"`
+}
+
+func (a *Alerts) notify(alert *Alert, state string) {
+  if a.config.Bell && state == "firing" && a.Bell != nil {
+    a.Bell()
+  }
+  n := notification {
+    Alert: alert.Rule.Text,
+    State: state,
+    Since: alert.Since,
+    Detail: alert.Detail,
+  }
+  for _, command := range a.config.Commands {
+    cmd := exec.Command("sh", "-c", command)
+    cmd.Env = append(os.Environ(), "BF_ALERT=" + n.Alert, "BF_ALERT_STATE=" + n.State, "BF_ALERT_DETAIL=" + n.Detail)
+    go func() {
+      a.report(cmd.Run())
+    }()
+  }
+  body, err := json.Marshal(n)
+  if err != nil {
+    a.lastError = err
+    return
+  }
+  for _, url := range a.config.Webhooks {
+    go func(url string) {
+      c := &http.Client { Timeout: 5 * time.Second }
+      r, err := c.Post(url, "application/json", bytes.NewReader(body))
+      if err == nil {
+        r.Body.Close()
+        if r.StatusCode >= 300 {
+          err = fmt.Errorf("webhook %s: %s", url, r.Status)
+        }
+      }
+      a.report(err)
+    }(url)
+  }
+}
+
+func (a *Alerts) report(err error) {
+  if err != nil {
+    a.mutex.Lock()
+    a.lastError = err
+    a.mutex.Unlock()
+  }
+}
+
+// LastError is the last failure to run a command or call a webhook
+func (a *Alerts) LastError() error {
+  a.mutex.Lock()
+  defer a.mutex.Unlock()
+  return a.lastError
+}
@@ -26,18 +26,48 @@ type App struct {
   Client *UnifiedRedis
   Conn *grpc.ClientConn
   workerConns map[string]*grpc.ClientConn
+  // connsMutex guards workerConns, which probes reach from their own
+  // goroutines
+  connsMutex sync.Mutex
   Ops map[string]*longrunning.Operation
   Metadatas map[string]*reapi.RequestMetadata
   Invocations map[string][]string
   Fetches uint
   Mutex *sync.Mutex
+  History *History
+  Alerts *Alerts
+  // DialOptions apply to the reapi and every worker connection
+  DialOptions []grpc.DialOption
+  // Offline apps have no redis
+  Offline bool
+  // Events follow the operation changes published by the backplane, once
+  // watched
+  Events *Events
+  // Utilization remembers the slots each worker has used, as profiled
+  Utilization *Utilization
+  // Health follows whether each worker answers for its profile
+  Health *Health
+  // Clock replaces the time of day for a replay
+  Clock func() time.Time
 
   FrameLimit int
   SkipFrames int
   UpdateCountdown int
 }
 
+// Now is the time the views show state as of, that of the recording while
+// one is replayed
+func (a *App) Now() time.Time {
+  if a.Clock != nil {
+    return a.Clock()
+  }
+  return time.Now()
+}
+
 func NewApp(redisHost string, reapiHost string, ca string) *App {
+  if !strings.Contains(redisHost, ":") {
+    redisHost += ":6379"
+  }
   return &App {
     Instance: "shard",
     RedisHost: redisHost,
@@ -50,23 +80,62 @@ func NewApp(redisHost string, reapiHost string, ca string) *App {
     workerConns: make(map[string]*grpc.ClientConn),
     Client: &UnifiedRedis{},
     Mutex: &sync.Mutex{},
+    Utilization: NewUtilization(utilizationStep, utilizationSize),
+    Health: NewHealth(),
     FrameLimit: 60,
   }
 }
 
 func (a *App) GetWorkerConn(worker string, ca string) *grpc.ClientConn {
+  a.connsMutex.Lock()
+  defer a.connsMutex.Unlock()
   if a.workerConns[worker] == nil {
-    a.workerConns[worker] = connect(worker, ca)
+    a.workerConns[worker] = connect(worker, ca, a.DialOptions...)
   }
   return a.workerConns[worker]
 }
 
+// CloseWorkerConns closes and forgets the connections to every worker not in
+// workers
+func (a *App) CloseWorkerConns(workers []string) {
+  a.connsMutex.Lock()
+  defer a.connsMutex.Unlock()
+  keep := make(map[string]bool, len(workers))
+  for _, worker := range workers {
+    keep[worker] = true
+  }
+  for worker, conn := range a.workerConns {
+    if !keep[worker] {
+      conn.Close()
+      delete(a.workerConns, worker)
+    }
+  }
+}
+
 func (a *App) Connect() {
-  a.Client.connect(a.RedisHost)
-  a.Conn = connect(a.ReapiHost, a.CA)
+  if a.Offline {
+    a.Client.offline()
+  } else {
+    a.Client.connect(a.RedisHost)
+  }
+  a.ConnectReapi()
+}
+
+// Watch subscribes to the operation changes published by the backplane
+func (a *App) Watch() {
+  if a.Offline || a.Events != nil {
+    return
+  }
+  a.Events = &Events{}
+  go a.Events.follow(a)
+}
+
+// ConnectReapi connects only to the reapi host, for uses without redis
+func (a *App) ConnectReapi() {
+  a.Conn = connect(a.ReapiHost, a.CA, a.DialOptions...)
 }
 
-func connect(host string, ca string) *grpc.ClientConn {
+func connect(host string, ca string, dialOptions ...grpc.DialOption) *grpc.ClientConn {
   var opts []grpc.DialOption
   if strings.HasPrefix(host, "grpcs://") {
     host = host[8:]
@@ -81,7 +150,7 @@ func connect(host string, ca string) *grpc.ClientConn {
   } else {
     opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
   }
-  conn, err := grpc.Dial(host, opts...)
+  conn, err := grpc.Dial(host, append(opts, dialOptions...)...)
   if err != nil {
     panic(err)
   }
@@ -0,0 +1,36 @@
+package client
+
+import (
+  "context"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "google.golang.org/grpc"
+)
+
+// DispatchedOperationsHash is the default redis hash of dispatched operations
+// by name
+const DispatchedOperationsHash = "DispatchedOperations"
+
+func BackplaneStatus(ctx context.Context, c *grpc.ClientConn, instance string) (*bfpb.BackplaneStatus, error) {
+  oq := bfpb.NewOperationQueueClient(c)
+  return oq.Status(ctx, &bfpb.BackplaneStatusRequest {
+    InstanceName: instance,
+  })
+}
+
+// QueueNames lists the redis keys of the prequeue or of every provision of
+// the operation queue
+func QueueNames(status *bfpb.BackplaneStatus, prequeue bool) []string {
+  if prequeue {
+    return []string { status.Prequeue.Name }
+  }
+  var names []string
+  for _, provision := range status.OperationQueue.Provisions {
+    names = append(names, provision.Name)
+  }
+  return names
+}
+
+func WorkerProfile(ctx context.Context, c *grpc.ClientConn) (*bfpb.WorkerProfileMessage, error) {
+  wp := bfpb.NewWorkerProfileClient(c)
+  return wp.GetWorkerProfile(ctx, &bfpb.WorkerProfileRequest {})
+}
@@ -10,32 +10,37 @@ import (
 )
 
 func Expect(c *grpc.ClientConn, d bfpb.Digest, m proto.Message) error {
+  b, err := ReadBlob(c, d)
+  if err != nil {
+    return err
+  }
+  return proto.Unmarshal(b, m)
+}
+
+// ReadBlob reads the whole content of d
+func ReadBlob(c *grpc.ClientConn, d bfpb.Digest) ([]byte, error) {
   bs := bytestream.NewByteStreamClient(c)
 
   bsrc, err := bs.Read(context.Background(), &bytestream.ReadRequest {
     ResourceName: "/blobs/" + DigestString(d),
   })
   if err != nil {
-    return err
+    return nil, err
   }
 
-  var b []byte
+  b := make([]byte, 0, d.Size)
   for ;; {
     br, err := bsrc.Recv()
     if err == io.EOF {
       break
     }
     if err != nil {
-      return err
-    }
-    if int64(len(br.Data)) == d.Size {
-      b = br.Data
+      return nil, err
     }
-    err = proto.Unmarshal(b, m)
-    if err != nil {
-      return err
-    }
-    return nil
+    b = append(b, br.Data...)
+  }
+  if int64(len(b)) != d.Size {
+    return nil, io.ErrUnexpectedEOF
   }
-  return io.EOF
+  return b, nil
 }
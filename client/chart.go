@@ -0,0 +1,224 @@
+package client
+
+import (
+  "fmt"
+  "image"
+  "math"
+  "strings"
+
+  ui "github.com/gizak/termui/v3"
+)
+
+type Series struct {
+  Name string
+  Data []float64
+  Color ui.Color
+}
+
+// Chart plots several series as braille lines over each other, or as stacked
+// areas in the order given, with a legend and labeled axes
+type Chart struct {
+  ui.Block
+  Series []Series
+  Stacked bool
+  // XLabels are spread evenly along the x axis
+  XLabels []string
+  AxesStyle ui.Style
+}
+
+func NewChart() *Chart {
+  return &Chart{
+    Block: *ui.NewBlock(),
+    AxesStyle: ui.NewStyle(ui.ColorWhite),
+  }
+}
+
+// FormatValue abbreviates v with a metric suffix
+func FormatValue(v float64) string {
+  switch {
+  case v >= 1e9:
+    return strings.TrimSuffix(fmt.Sprintf("%.1f", v / 1e9), ".0") + "G"
+  case v >= 1e6:
+    return strings.TrimSuffix(fmt.Sprintf("%.1f", v / 1e6), ".0") + "M"
+  case v >= 1e3:
+    return strings.TrimSuffix(fmt.Sprintf("%.1f", v / 1e3), ".0") + "k"
+  case v == math.Trunc(v):
+    return fmt.Sprintf("%.0f", v)
+  }
+  return fmt.Sprintf("%.2f", v)
+}
+
+// niceMax rounds v up to 1, 2 or 5 times a power of ten
+func niceMax(v float64) float64 {
+  if v <= 0 {
+    return 1
+  }
+  p := math.Pow(10, math.Floor(math.Log10(v)))
+  for _, m := range []float64{1, 2, 5, 10} {
+    if v <= m * p {
+      return m * p
+    }
+  }
+  return 10 * p
+}
+
+func (self *Chart) maxValue() float64 {
+  max := 0.0
+  if self.Stacked {
+    for i := 0; i < self.length(); i++ {
+      sum := 0.0
+      for _, s := range self.Series {
+        if i < len(s.Data) {
+          sum += s.Data[i]
+        }
+      }
+      max = math.Max(max, sum)
+    }
+  } else {
+    for _, s := range self.Series {
+      for _, v := range s.Data {
+        max = math.Max(max, v)
+      }
+    }
+  }
+  return niceMax(max)
+}
+
+func (self *Chart) length() int {
+  n := 0
+  for _, s := range self.Series {
+    if len(s.Data) > n {
+      n = len(s.Data)
+    }
+  }
+  return n
+}
+
+func (self *Chart) drawLegend(buf *ui.Buffer, p image.Point) {
+  for i := range self.Series {
+    s := self.Series[i]
+    // stacked areas read top down
+    if self.Stacked {
+      s = self.Series[len(self.Series) - 1 - i]
+    }
+    if p.X + len(s.Name) + 2 > self.Inner.Max.X {
+      break
+    }
+    buf.SetCell(ui.NewCell('■', ui.NewStyle(s.Color)), p)
+    buf.SetString(s.Name, self.AxesStyle, p.Add(image.Pt(2, 0)))
+    p.X += len(s.Name) + 4
+  }
+}
+
+func (self *Chart) drawAxes(buf *ui.Buffer, area image.Rectangle, max float64) {
+  for y := area.Min.Y; y < area.Max.Y; y++ {
+    buf.SetCell(ui.NewCell(ui.VERTICAL_LINE, self.AxesStyle), image.Pt(area.Min.X - 1, y))
+  }
+  for x := area.Min.X; x < area.Max.X; x++ {
+    buf.SetCell(ui.NewCell(ui.HORIZONTAL_LINE, self.AxesStyle), image.Pt(x, area.Max.Y))
+  }
+  buf.SetCell(ui.NewCell(ui.BOTTOM_LEFT, self.AxesStyle), image.Pt(area.Min.X - 1, area.Max.Y))
+
+  // y labels at the top, middle and bottom of the plot
+  for _, f := range []float64{1, 0.5, 0} {
+    label := FormatValue(max * f)
+    y := area.Max.Y - 1 - int(f * float64(area.Dy() - 1))
+    buf.SetString(label, self.AxesStyle, image.Pt(area.Min.X - 2 - len(label), y))
+  }
+
+  n := len(self.XLabels)
+  for i, label := range self.XLabels {
+    x := area.Min.X
+    if n > 1 {
+      x += i * (area.Dx() - 1) / (n - 1) - i * len(label) / (n - 1)
+    }
+    buf.SetString(label, self.AxesStyle, image.Pt(x, area.Max.Y + 1))
+  }
+}
+
+func (self *Chart) drawLines(buf *ui.Buffer, area image.Rectangle, max float64) {
+  canvas := ui.NewCanvas()
+  canvas.Rectangle = area
+  dots := area.Dx() * 2 - 1
+  height := float64(area.Dy() * 4 - 1)
+  for _, s := range self.Series {
+    var last image.Point
+    for i, v := range s.Data {
+      x := area.Min.X * 2
+      if len(s.Data) > 1 {
+        x += i * dots / (len(s.Data) - 1)
+      }
+      p := image.Pt(x, area.Max.Y * 4 - 1 - int(v / max * height))
+      if i == 0 {
+        canvas.SetPoint(p, s.Color)
+      } else {
+        canvas.SetLine(last, p, s.Color)
+      }
+      last = p
+    }
+  }
+  canvas.Draw(buf)
+}
+
+var eighths = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+func (self *Chart) drawStacked(buf *ui.Buffer, area image.Rectangle, max float64) {
+  n := self.length()
+  if n == 0 {
+    return
+  }
+  for x := area.Min.X; x < area.Max.X; x++ {
+    i := (x - area.Min.X) * n / area.Dx()
+    // the top of each series in eighths of a row
+    var tops []int
+    sum := 0.0
+    for _, s := range self.Series {
+      if i < len(s.Data) {
+        sum += s.Data[i]
+      }
+      tops = append(tops, int(sum / max * float64(area.Dy() * 8)))
+    }
+    for row := 0; row < area.Dy(); row++ {
+      p := image.Pt(x, area.Max.Y - 1 - row)
+      // the series covering the middle of the cell fills it
+      for j, top := range tops {
+        if top >= row * 8 + 4 {
+          buf.SetCell(ui.NewCell('█', ui.NewStyle(self.Series[j].Color)), p)
+          break
+        }
+      }
+      // partial cap on the total
+      if total := tops[len(tops) - 1]; total > row * 8 && total < row * 8 + 4 {
+        j := len(tops) - 1
+        for j > 0 && tops[j - 1] >= total {
+          j--
+        }
+        buf.SetCell(ui.NewCell(eighths[total - row * 8], ui.NewStyle(self.Series[j].Color)), p)
+      }
+    }
+  }
+}
+
+func (self *Chart) Draw(buf *ui.Buffer) {
+  self.Block.Draw(buf)
+
+  max := self.maxValue()
+  labelWidth := 0
+  for _, f := range []float64{1, 0.5, 0} {
+    if w := len(FormatValue(max * f)) + 1; w > labelWidth {
+      labelWidth = w
+    }
+  }
+  // a legend line on top, the x axis and its labels below
+  area := image.Rect(self.Inner.Min.X + labelWidth + 1, self.Inner.Min.Y + 1, self.Inner.Max.X, self.Inner.Max.Y - 2)
+  if area.Dx() < 2 || area.Dy() < 2 {
+    return
+  }
+  self.drawLegend(buf, image.Pt(area.Min.X, self.Inner.Min.Y))
+  self.drawAxes(buf, area, max)
+  if self.Stacked {
+    self.drawStacked(buf, area, max)
+  } else {
+    self.drawLines(buf, area, max)
+  }
+}
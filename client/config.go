@@ -0,0 +1,84 @@
+package client
+
+import (
+  "bufio"
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+)
+
+// Config is read from a file of directives, one per line:
+//
+//   # comments and blank lines are ignored
+//   alert queue > 5000 for 2m
+//   alert worker stale
+//   alert stage ExecuteActionStage paused
+//   alert operation InputFetchStage > 10m
+//   notify command notify-send bf-client "$BF_ALERT"
+//   notify webhook http://localhost:9099/
+//   notify bell off
+type Config struct {
+  Rules []*Rule
+  Commands []string
+  Webhooks []string
+  Bell bool
+}
+
+func DefaultConfigPath() (string, error) {
+  if p := os.Getenv("BF_CONFIG"); p != "" {
+    return p, nil
+  }
+  dir, err := os.UserConfigDir()
+  if err != nil {
+    return "", err
+  }
+  return filepath.Join(dir, "bf-client", "config"), nil
+}
+
+// LoadConfig reads path, a missing file is an empty config
+func LoadConfig(path string) (*Config, error) {
+  c := &Config { Bell: true }
+  f, err := os.Open(path)
+  if os.IsNotExist(err) {
+    return c, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+
+  scanner := bufio.NewScanner(f)
+  for n := 1; scanner.Scan(); n++ {
+    line := strings.TrimSpace(scanner.Text())
+    if line == "" || strings.HasPrefix(line, "#") {
+      continue
+    }
+    directive, rest, _ := strings.Cut(line, " ")
+    rest = strings.TrimSpace(rest)
+    switch directive {
+    case "alert":
+      rule, err := ParseRule(rest)
+      if err != nil {
+        return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+      }
+      c.Rules = append(c.Rules, rule)
+    case "notify":
+      kind, arg, _ := strings.Cut(rest, " ")
+      arg = strings.TrimSpace(arg)
+      switch kind {
+      case "command":
+        c.Commands = append(c.Commands, arg)
+      case "webhook":
+        c.Webhooks = append(c.Webhooks, arg)
+      case "bell":
+        c.Bell = arg != "off"
+      default:
+        return nil, fmt.Errorf("%s:%d: unknown notification %q", path, n, kind)
+      }
+    default:
+      return nil, fmt.Errorf("%s:%d: unknown directive %q", path, n, directive)
+    }
+  }
+  return c, scanner.Err()
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+  "context"
+  "sort"
+  "strconv"
+  redis "github.com/redis/go-redis/v9"
+)
+
+// keyStart is where a page starts in one key of a queue: just past anchor,
+// the member last read from the key, or offset entries from the dequeue end
+// before any has been read
+type keyStart struct {
+  anchor string
+  score float64
+  offset int64
+}
+
+// Cursor pages through a queue in the order its entries are dequeued,
+// merging the keys of every shard. Each page starts after the entries last
+// read from each key, so paging holds its place while entries are queued
+// and dequeued underneath.
+type Cursor struct {
+  q *Queue
+  size int64
+  // the starts of the page, of those before it and of the next
+  start []keyStart
+  previous [][]keyStart
+  next []keyStart
+  more bool
+}
+
+// positioned is an entry at pos from the dequeue end of the key'th key
+type positioned struct {
+  e *Entry
+  pos int64
+  key int
+}
+
+// Cursor pages through the queue size entries at a time, from its head
+func (q *Queue) Cursor(size int64) *Cursor {
+  return &Cursor {
+    q: q,
+    size: size,
+    start: make([]keyStart, len(q.keys)),
+  }
+}
+
+// locate is the offset from the dequeue end of key where s starts now
+func locate(ctx context.Context, c *UnifiedRedis, key string, s keyStart) (int64, error) {
+  if s.anchor == "" {
+    return s.offset, nil
+  }
+  if isPriority(key) {
+    rank, err := c.ZRank(ctx, key, s.anchor).Result()
+    if err == nil {
+      return rank + 1, nil
+    }
+    if err != redis.Nil {
+      return 0, err
+    }
+    // the anchor is gone, start with the first member scored after it
+    return c.ZCount(ctx, key, "-inf", "(" + strconv.FormatFloat(s.score, 'g', -1, 64)).Result()
+  }
+  // lists are pushed at the head and dequeued from the tail
+  i, err := c.LPos(ctx, key, s.anchor, redis.LPosArgs { Rank: -1 }).Result()
+  if err == redis.Nil {
+    // the anchor has been dequeued, as has everything ahead of it
+    return 0, nil
+  }
+  if err != nil {
+    return 0, err
+  }
+  n, err := c.LLen(ctx, key).Result()
+  if err != nil {
+    return 0, err
+  }
+  // the anchor is n - 1 - i from the tail
+  return n - i, nil
+}
+
+// window reads n entries of key from offset, in dequeue order
+func window(ctx context.Context, c *UnifiedRedis, key string, offset int64, n int64) ([]*Entry, error) {
+  if isPriority(key) {
+    return rrange(ctx, c, key, offset, offset + n - 1)
+  }
+  entries, err := rrange(ctx, c, key, -(offset + n), -(offset + 1))
+  for i, j := 0, len(entries) - 1; i < j; i, j = i + 1, j - 1 {
+    entries[i], entries[j] = entries[j], entries[i]
+  }
+  return entries, err
+}
+
+// dequeuedBefore orders priority entries by score, and takes from each
+// list key in turn, as the balanced queue does
+func dequeuedBefore(a, b positioned) bool {
+  if a.e.Priority && b.e.Priority {
+    if a.e.Score != b.e.Score {
+      return a.e.Score < b.e.Score
+    }
+    return a.e.Member < b.e.Member
+  }
+  if a.pos != b.pos {
+    return a.pos < b.pos
+  }
+  return a.key < b.key
+}
+
+// Read reads the page again, as the queue is now
+func (cur *Cursor) Read(ctx context.Context, c *UnifiedRedis) ([]*Entry, error) {
+  var merged []positioned
+  next := make([]keyStart, len(cur.start))
+  for i, key := range cur.q.keys {
+    offset, err := locate(ctx, c, key, cur.start[i])
+    if err != nil {
+      return nil, err
+    }
+    next[i] = keyStart { anchor: cur.start[i].anchor, score: cur.start[i].score, offset: offset }
+    // one more than a page tells whether there is another
+    entries, err := window(ctx, c, key, offset, cur.size + 1)
+    if err != nil {
+      return nil, err
+    }
+    for j, e := range entries {
+      cur.q.fill(e, i)
+      merged = append(merged, positioned { e: e, pos: offset + int64(j), key: i })
+    }
+  }
+  sort.SliceStable(merged, func(i, j int) bool {
+    return dequeuedBefore(merged[i], merged[j])
+  })
+  cur.more = int64(len(merged)) > cur.size
+  if cur.more {
+    merged = merged[:cur.size]
+  }
+  var page []*Entry
+  for _, p := range merged {
+    page = append(page, p.e)
+    next[p.key] = keyStart { anchor: p.e.Member, score: p.e.Score, offset: p.pos + 1 }
+  }
+  cur.next = next
+  return page, nil
+}
+
+// Next moves on to the page after the one last read, if there is one
+func (cur *Cursor) Next(ctx context.Context, c *UnifiedRedis) ([]*Entry, error) {
+  if cur.more {
+    cur.previous = append(cur.previous, cur.start)
+    cur.start = cur.next
+  }
+  return cur.Read(ctx, c)
+}
+
+// Previous moves back to the page before, if there is one
+func (cur *Cursor) Previous(ctx context.Context, c *UnifiedRedis) ([]*Entry, error) {
+  if n := len(cur.previous); n > 0 {
+    cur.start = cur.previous[n - 1]
+    cur.previous = cur.previous[:n - 1]
+  }
+  return cur.Read(ctx, c)
+}
+
+// Page numbers the page from 1
+func (cur *Cursor) Page() int {
+  return len(cur.previous) + 1
+}
+
+// More reports whether entries followed the page last read
+func (cur *Cursor) More() bool {
+  return cur.more
+}
@@ -0,0 +1,75 @@
+package client_test
+
+import (
+  "context"
+  "slices"
+  "testing"
+  "github.com/werkt/bf-client/fake"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func TestCursorPages(t *testing.T) {
+  s, a := faketest.Start(t)
+  names := faketest.SubmitN(t, s, 5)
+  ctx := context.Background()
+  cur := newQueue(a, fake.PrequeueName).Cursor(2)
+
+  pages := []struct {
+    want []string
+    more bool
+  } {
+    { want: names[0:2], more: true },
+    { want: names[2:4], more: true },
+    { want: names[4:5], more: false },
+  }
+  e, err := cur.Read(ctx, a.Client)
+  for i, p := range pages {
+    if err != nil {
+      t.Fatal(err)
+    }
+    if got := entryNames(e); !slices.Equal(got, p.want) || cur.Page() != i + 1 || cur.More() != p.more {
+      t.Errorf("page %d: got %v on page %d, more %t, want %v, more %t",
+          i + 1, got, cur.Page(), cur.More(), p.want, p.more)
+    }
+    e, err = cur.Next(ctx, a.Client)
+  }
+  // the last page holds
+  if got := entryNames(e); err != nil || !slices.Equal(got, names[4:5]) || cur.Page() != 3 {
+    t.Errorf("next past the end: got %v on page %d, %v", got, cur.Page(), err)
+  }
+
+  e, err = cur.Previous(ctx, a.Client)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := entryNames(e); !slices.Equal(got, names[2:4]) || cur.Page() != 2 {
+    t.Errorf("previous: got %v on page %d, want %v on page 2", got, cur.Page(), names[2:4])
+  }
+}
+
+func TestCursorHoldsPlace(t *testing.T) {
+  s, a := faketest.Start(t)
+  names := faketest.SubmitN(t, s, 4)
+  ctx := context.Background()
+  cur := newQueue(a, fake.PrequeueName).Cursor(2)
+  if _, err := cur.Read(ctx, a.Client); err != nil {
+    t.Fatal(err)
+  }
+  if _, err := cur.Next(ctx, a.Client); err != nil {
+    t.Fatal(err)
+  }
+
+  // the head is dequeued and another entry queued behind the page
+  if _, ok := s.Redis.RPop(fake.PrequeueName); !ok {
+    t.Fatal("prequeue is empty")
+  }
+  names = append(names, faketest.SubmitN(t, s, 1)...)
+
+  e, err := cur.Read(ctx, a.Client)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := entryNames(e); !slices.Equal(got, names[2:4]) || !cur.More() {
+    t.Errorf("got %v, more %t, want %v, more", got, cur.More(), names[2:4])
+  }
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+  "context"
+  "strings"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/golang/protobuf/jsonpb"
+)
+
+// Dispatched is an operation held by a worker, as recorded in the
+// dispatched hash
+type Dispatched struct {
+  Name string
+  // RequeueAt is the deadline for the worker to report on the operation
+  // before it is requeued
+  RequeueAt time.Time
+  QueueEntry *bfpb.QueueEntry
+  Err error
+}
+
+// Overdue reports whether the deadline has passed, most likely with the
+// worker holding the operation gone
+func (d *Dispatched) Overdue(now time.Time) bool {
+  return d.Err == nil && !d.RequeueAt.IsZero() && now.After(d.RequeueAt)
+}
+
+func parseDispatched(name string, json string) *Dispatched {
+  d := &Dispatched { Name: name }
+  m := &bfpb.DispatchedOperation{}
+  if err := jsonpb.Unmarshal(strings.NewReader(json), m); err != nil {
+    d.Err = err
+    return d
+  }
+  d.QueueEntry = m.QueueEntry
+  if m.RequeueAt != 0 {
+    d.RequeueAt = time.UnixMilli(m.RequeueAt)
+  }
+  return d
+}
+
+// ScanDispatched reads the whole dispatched hash, count fields at a time
+func ScanDispatched(ctx context.Context, c *UnifiedRedis, count int64) ([]*Dispatched, error) {
+  var dispatched []*Dispatched
+  // a scan may return a field more than once
+  seen := make(map[string]bool)
+  var cursor uint64
+  for {
+    fields, next, err := c.HScan(ctx, DispatchedOperationsHash, cursor, "*", count).Result()
+    if err != nil {
+      return dispatched, err
+    }
+    for i := 0; i + 1 < len(fields); i += 2 {
+      if seen[fields[i]] {
+        continue
+      }
+      seen[fields[i]] = true
+      dispatched = append(dispatched, parseDispatched(fields[i], fields[i + 1]))
+    }
+    if next == 0 {
+      return dispatched, nil
+    }
+    cursor = next
+  }
+}
@@ -0,0 +1,55 @@
+package client_test
+
+import (
+  "context"
+  "slices"
+  "sort"
+  "testing"
+  "time"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func TestScanDispatched(t *testing.T) {
+  s, a := faketest.Start(t)
+  if _, err := s.AddWorker(3); err != nil {
+    t.Fatal(err)
+  }
+  // the match stage takes one operation each step
+  names := faketest.SubmitN(t, s, 3)
+  for i := 0; i < 4; i++ {
+    s.Step()
+  }
+  s.Redis.HSet(client.DispatchedOperationsHash, "corrupt", "{")
+
+  dispatched, err := client.ScanDispatched(context.Background(), a.Client, 1)
+  if err != nil {
+    t.Fatal(err)
+  }
+  var got []string
+  now := time.Now()
+  for _, d := range dispatched {
+    if d.Name == "corrupt" {
+      if d.Err == nil {
+        t.Error("parsed a corrupt dispatched operation")
+      }
+      continue
+    }
+    if d.Err != nil {
+      t.Errorf("%s: %v", d.Name, d.Err)
+      continue
+    }
+    got = append(got, d.QueueEntry.ExecuteEntry.OperationName)
+    if d.Name != d.QueueEntry.ExecuteEntry.OperationName {
+      t.Errorf("%s holds the entry of %s", d.Name, d.QueueEntry.ExecuteEntry.OperationName)
+    }
+    if d.Overdue(now) || !d.Overdue(now.Add(fake.DispatchTimeout + time.Second)) {
+      t.Errorf("%s is due at %v, want within %v", d.Name, d.RequeueAt, fake.DispatchTimeout)
+    }
+  }
+  sort.Strings(got)
+  if !slices.Equal(got, names) {
+    t.Errorf("scanned %v, want %v", got, names)
+  }
+}
@@ -6,6 +6,7 @@ import (
   "slices"
   "strconv"
   "strings"
+  "unicode/utf8"
 
   "github.com/ericchiang/css"
   "golang.org/x/net/html"
@@ -16,14 +17,23 @@ type styleNode struct {
   properties map[string]string
 }
 
+type anchorSpan struct {
+  node *html.Node
+  start int
+  end int
+}
+
 type Document struct {
   root *html.Node
   styles []styleNode
+  anchors []anchorSpan
 }
 
 type render struct {
   d *Document
   blocked bool
+  // count of cells emitted so far, for locating anchors
+  pos int
 }
 
 var defaultTermuiStyles = []styleNode {
@@ -146,9 +156,20 @@ func (d *Document) Render() string {
     d: d,
     blocked: false,
   }
+  d.anchors = d.anchors[:0]
   return r.run()
 }
 
+// AnchorAt returns the anchor covering cell i of the last Render, or nil.
+func (d *Document) AnchorAt(i int) *html.Node {
+  for _, span := range d.anchors {
+    if i >= span.start && i < span.end {
+      return span.node
+    }
+  }
+  return nil
+}
+
 func (d *Document) SetRoot(n *html.Node) {
   d.root = n
 }
@@ -360,12 +381,20 @@ func (r *render) node(n *html.Node, styles []styleNode, x int) string {
     return ""
   }
   s := r.styled(n, styles)
+  if n.Type == html.TextNode {
+    r.pos += utf8.RuneCountInString(n.Data)
+  }
+  start := r.pos
   for c := n.FirstChild; c != nil; c = c.NextSibling {
     if block && (display != "list-item" || c == n.FirstChild) && c.Type == html.TextNode && len(c.Data) != 0 {
       s += "\n" + strings.Repeat(" ", x)
+      r.pos += 1 + x
     }
     s += r.node(c, styles, x)
   }
+  if n.Type == html.ElementNode && n.Data == "a" {
+    r.d.anchors = append(r.d.anchors, anchorSpan { node: n, start: start, end: r.pos })
+  }
   return s
 }
 
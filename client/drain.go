@@ -0,0 +1,134 @@
+package client
+
+import (
+  "context"
+  "sync"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "google.golang.org/genproto/googleapis/longrunning"
+  "google.golang.org/grpc"
+  "google.golang.org/grpc/codes"
+  "google.golang.org/grpc/status"
+)
+
+// drainedStages hold the operations a worker has matched
+var drainedStages = []string { "InputFetchStage", "ExecuteActionStage", "ReportResultStage" }
+
+// Drain takes a worker out of service: its match stage is paused and the
+// operations it holds are followed until there are none
+type Drain struct {
+  Worker string
+  Started time.Time
+  // Initial is the most operations held since the drain started, and
+  // Remaining those held at the last check, by stage in Operations
+  Initial int
+  Remaining int
+  Operations map[string][]string
+  // Cancelled are the operations left over that have been cancelled
+  Cancelled int
+  Err error
+  paused bool
+  checked bool
+}
+
+func NewDrain(worker string) *Drain {
+  return &Drain {
+    Worker: worker,
+    Operations: make(map[string][]string),
+  }
+}
+
+// Idle reports whether the worker holds no operations, so that it is safe
+// to stop
+func (d *Drain) Idle() bool {
+  return d.paused && d.checked && d.Remaining == 0
+}
+
+// Progress is the fraction of the operations held at the start that are done
+func (d *Drain) Progress() float64 {
+  if d.Initial == 0 {
+    if d.Idle() {
+      return 1
+    }
+    return 0
+  }
+  return float64(d.Initial - d.Remaining) / float64(d.Initial)
+}
+
+func (d *Drain) check(ctx context.Context, conn *grpc.ClientConn) {
+  if !d.paused {
+    if d.Err = ChangePipeline(ctx, conn, PipelineAction { Stage: "MatchStage", Paused: true }); d.Err != nil {
+      return
+    }
+    d.paused = true
+    d.Started = time.Now()
+  }
+  profile, err := bfpb.NewWorkerProfileClient(conn).GetWorkerProfile(ctx, &bfpb.WorkerProfileRequest {})
+  if err != nil {
+    d.Err = err
+    return
+  }
+  d.Err = nil
+  remaining := 0
+  operations := make(map[string][]string)
+  for _, stage := range profile.Stages {
+    for _, name := range drainedStages {
+      if stage.Name == name {
+        operations[name] = stage.OperationNames
+        // the names are not reported by every stage
+        remaining += max(len(stage.OperationNames), int(stage.SlotsUsed))
+      }
+    }
+  }
+  d.Operations, d.Remaining = operations, remaining
+  d.Initial = max(d.Initial, remaining)
+  d.checked = true
+}
+
+// CheckDrains pauses the match stage of each worker not yet paused and
+// counts the operations each holds, all at once
+func CheckDrains(a *App, drains []*Drain) {
+  conns := make([]*grpc.ClientConn, len(drains))
+  for i, d := range drains {
+    conns[i] = a.GetWorkerConn(d.Worker, a.CA)
+  }
+  var wg sync.WaitGroup
+  for i, d := range drains {
+    wg.Add(1)
+    go func(d *Drain, conn *grpc.ClientConn) {
+      defer wg.Done()
+      ctx, cancel := context.WithTimeout(context.Background(), pipelineTimeout)
+      defer cancel()
+      d.check(ctx, conn)
+    }(d, conns[i])
+  }
+  wg.Wait()
+}
+
+// CancelStragglers cancels the operations the worker still holds through
+// the server at conn
+func (d *Drain) CancelStragglers(ctx context.Context, conn *grpc.ClientConn) error {
+  ops := longrunning.NewOperationsClient(conn)
+  for _, stage := range drainedStages {
+    for _, name := range d.Operations[stage] {
+      _, err := ops.CancelOperation(ctx, &longrunning.CancelOperationRequest { Name: name })
+      if err != nil {
+        // buildfarm spits out an unknown for already-done
+        if st, ok := status.FromError(err); !ok || st.Code() != codes.Unknown {
+          return err
+        }
+      }
+      d.Cancelled++
+    }
+  }
+  return nil
+}
+
+// Resume unpauses the match stage, putting the worker back in service
+func (d *Drain) Resume(ctx context.Context, a *App) error {
+  err := ChangePipeline(ctx, a.GetWorkerConn(d.Worker, a.CA), PipelineAction { Stage: "MatchStage" })
+  if err == nil {
+    d.paused = false
+  }
+  return err
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+  "context"
+  "strings"
+  "sync"
+  "time"
+  reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/golang/protobuf/jsonpb"
+  "github.com/golang/protobuf/ptypes"
+  "google.golang.org/genproto/googleapis/longrunning"
+)
+
+// OperationChannelPrefix is the default prefix of the redis channel each
+// operation's changes are published on, as <prefix>:<name>
+const OperationChannelPrefix = "OperationChannel"
+
+// events kept for views opened after they arrived
+const eventHistory = 10000
+
+// OperationEvent is a change to an operation published by the backplane
+type OperationEvent struct {
+  Name string
+  Time time.Time
+  Source string
+  // State is one of queued, dispatched, executing and completed, or
+  // expired when the operation is no longer watched
+  State string
+  // Operation is the operation as changed, nil when expired
+  Operation *longrunning.Operation
+  Metadata *reapi.RequestMetadata
+  Worker string
+  Err error
+}
+
+// operationWorker is the worker of an operation, while executing or after
+func operationWorker(o *longrunning.Operation) string {
+  if r, ok := o.Result.(*longrunning.Operation_Response); ok {
+    er := &reapi.ExecuteResponse{}
+    if r.Response.MessageIs(er) && ptypes.UnmarshalAny(r.Response, er) == nil {
+      return er.GetResult().GetExecutionMetadata().GetWorker()
+    }
+  }
+  if em, err := ExecuteOperationMetadata(o); err == nil {
+    return em.GetPartialExecutionMetadata().GetWorker()
+  }
+  return ""
+}
+
+// OperationState names the stage an operation has reached
+func OperationState(o *longrunning.Operation) string {
+  if o.Done {
+    return "completed"
+  }
+  em, err := ExecuteOperationMetadata(o)
+  if err != nil || em == nil {
+    return "unknown"
+  }
+  switch em.Stage {
+  case reapi.ExecutionStage_CACHE_CHECK:
+    return "cache check"
+  case reapi.ExecutionStage_QUEUED:
+    // a worker has taken the operation before it starts executing
+    if em.GetPartialExecutionMetadata().GetWorker() != "" {
+      return "dispatched"
+    }
+    return "queued"
+  case reapi.ExecutionStage_EXECUTING:
+    return "executing"
+  case reapi.ExecutionStage_COMPLETED:
+    return "completed"
+  }
+  return "unknown"
+}
+
+// ParseOperationEvent decodes an OperationChange published on channel
+func ParseOperationEvent(channel string, payload string) *OperationEvent {
+  ev := &OperationEvent {
+    Name: strings.TrimPrefix(channel, OperationChannelPrefix + ":"),
+    Time: time.Now(),
+  }
+  change := &bfpb.OperationChange{}
+  if err := jsonpb.Unmarshal(strings.NewReader(payload), change); err != nil {
+    ev.Err = err
+    return ev
+  }
+  if change.EffectiveAt != nil && change.EffectiveAt.IsValid() {
+    ev.Time = change.EffectiveAt.AsTime()
+  }
+  ev.Source = change.Source
+  if reset := change.GetReset_(); reset != nil && reset.Operation != nil {
+    o := reset.Operation
+    ev.Name = o.Name
+    ev.Operation = o
+    ev.State = OperationState(o)
+    ev.Metadata = RequestMetadata(o)
+    ev.Worker = operationWorker(o)
+  } else {
+    ev.State = "expired"
+  }
+  return ev
+}
+
+// Events holds the latest operation events from the backplane, numbered in
+// order of arrival
+type Events struct {
+  mutex sync.Mutex
+  events []*OperationEvent
+  // count is the number of the next event
+  count uint64
+  live bool
+  err error
+}
+
+func (e *Events) add(ev *OperationEvent) {
+  e.mutex.Lock()
+  defer e.mutex.Unlock()
+  e.events = append(e.events, ev)
+  if len(e.events) > eventHistory {
+    e.events = e.events[len(e.events) - eventHistory:]
+  }
+  e.count++
+}
+
+// Since returns the events from number n on that are still held, and the
+// number to ask for next
+func (e *Events) Since(n uint64) ([]*OperationEvent, uint64) {
+  e.mutex.Lock()
+  defer e.mutex.Unlock()
+  first := e.count - uint64(len(e.events))
+  if n < first {
+    n = first
+  }
+  if n >= e.count {
+    return nil, e.count
+  }
+  return append([]*OperationEvent{}, e.events[n - first:]...), e.count
+}
+
+// Live reports whether events are arriving, so that operations need not be
+// polled for changes
+func (e *Events) Live() bool {
+  if e == nil {
+    return false
+  }
+  e.mutex.Lock()
+  defer e.mutex.Unlock()
+  return e.live
+}
+
+func (e *Events) Err() error {
+  if e == nil {
+    return nil
+  }
+  e.mutex.Lock()
+  defer e.mutex.Unlock()
+  return e.err
+}
+
+func (e *Events) setLive(live bool, err error) {
+  e.mutex.Lock()
+  defer e.mutex.Unlock()
+  e.live = live
+  e.err = err
+}
+
+// follow receives every operation change until the subscription fails,
+// refreshing those of the operations held in Ops
+func (e *Events) follow(a *App) {
+  ctx := context.Background()
+  pubsub := a.Client.PSubscribe(ctx, OperationChannelPrefix + ":*")
+  defer pubsub.Close()
+  if _, err := pubsub.Receive(ctx); err != nil {
+    e.setLive(false, err)
+    return
+  }
+  e.setLive(true, nil)
+  for m := range pubsub.Channel() {
+    ev := ParseOperationEvent(m.Channel, m.Payload)
+    if ev.Operation != nil {
+      a.Mutex.Lock()
+      if _, ok := a.Ops[ev.Name]; ok {
+        a.Ops[ev.Name] = ev.Operation
+      }
+      a.Mutex.Unlock()
+    }
+    e.add(ev)
+  }
+  e.setLive(false, nil)
+}
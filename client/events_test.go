@@ -0,0 +1,69 @@
+package client_test
+
+import (
+  "testing"
+  "time"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake/faketest"
+  "google.golang.org/genproto/googleapis/longrunning"
+)
+
+// waitFor polls the events until one for name reaches state
+func waitFor(t *testing.T, a *client.App, name string, state string) *client.OperationEvent {
+  deadline := time.Now().Add(5 * time.Second)
+  for time.Now().Before(deadline) {
+    events, _ := a.Events.Since(0)
+    for _, ev := range events {
+      if ev.Err != nil {
+        t.Fatal(ev.Err)
+      }
+      if ev.Name == name && ev.State == state {
+        return ev
+      }
+    }
+    time.Sleep(10 * time.Millisecond)
+  }
+  t.Fatalf("no %s event for %s", state, name)
+  return nil
+}
+
+func TestEventsFollow(t *testing.T) {
+  s, a := faketest.Start(t)
+  a.Watch()
+  deadline := time.Now().Add(5 * time.Second)
+  for !a.Events.Live() {
+    if err := a.Events.Err(); err != nil {
+      t.Fatal(err)
+    }
+    if time.Now().After(deadline) {
+      t.Fatal("events never went live")
+    }
+    time.Sleep(10 * time.Millisecond)
+  }
+  if _, err := s.AddWorker(1); err != nil {
+    t.Fatal(err)
+  }
+  name := faketest.SubmitN(t, s, 1)[0]
+  // held operations are replaced as they change
+  a.Mutex.Lock()
+  a.Ops[name] = &longrunning.Operation { Name: name }
+  a.Mutex.Unlock()
+
+  ev := waitFor(t, a, name, "queued")
+  if ev.Source != "fake" || ev.Metadata.GetTargetId() != "//test:0" {
+    t.Errorf("queued event from %q for %q", ev.Source, ev.Metadata.GetTargetId())
+  }
+  s.Step()
+  s.Step()
+  ev = waitFor(t, a, name, "dispatched")
+  if ev.Worker == "" {
+    t.Error("dispatched event has no worker")
+  }
+
+  a.Mutex.Lock()
+  o := a.Ops[name]
+  a.Mutex.Unlock()
+  if state := client.OperationState(o); state != "dispatched" {
+    t.Errorf("held operation is %s, want dispatched", state)
+  }
+}
@@ -0,0 +1,371 @@
+package client
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+  "math"
+  "os"
+  "path/filepath"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+)
+
+// steps older than fineSpan are averaged in memory into coarseStep steps, no
+// coarser than the points of a plot of the wider windows
+const fineSpan = 6 * time.Hour
+const coarseStep = 2 * time.Minute
+
+type accumulator struct {
+  sum float64
+  n int
+}
+
+// History is an append-only file of samples averaged over a fixed step, one
+// line per step of the form "<unix seconds> name=value name=value..."
+type History struct {
+  path string
+  file *os.File
+  // lock is held while the file is appended to
+  lock *os.File
+  step time.Duration
+  retention time.Duration
+  times []int64
+  series map[string][]float64
+  // compacted leading steps are coarse
+  compacted int
+  bucket int64
+  pending map[string]*accumulator
+  // err is the first failure to append to the file, after which steps are
+  // only kept in memory
+  err error
+  mutex sync.Mutex
+}
+
+func DefaultHistoryPath(host string) (string, error) {
+  if p := os.Getenv("BF_HISTORY"); p != "" {
+    return p, nil
+  }
+  dir, err := os.UserCacheDir()
+  if err != nil {
+    return "", err
+  }
+  host = strings.NewReplacer("/", "_", ":", "_").Replace(host)
+  return filepath.Join(dir, "bf-client", host + ".history"), nil
+}
+
+// OpenHistory loads the samples in path newer than retention, and appends
+// new ones to it. Steps older than fineSpan are kept coarse, in memory and
+// in the file once it is rewritten.
+func OpenHistory(path string, step time.Duration, retention time.Duration) (*History, error) {
+  h := &History {
+    path: path,
+    step: step,
+    retention: retention,
+    series: make(map[string][]float64),
+    pending: make(map[string]*accumulator),
+  }
+  if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+    return nil, err
+  }
+  // locked before it is loaded, a rewrite cannot drop another's steps
+  lock, err := os.OpenFile(path + ".lock", os.O_CREATE | os.O_WRONLY, 0644)
+  if err != nil {
+    return nil, err
+  }
+  if err := lockHistory(lock); err != nil {
+    lock.Close()
+    return nil, err
+  }
+  h.lock = lock
+  expired, err := h.load(time.Now().Add(-retention).Unix())
+  if err == nil && expired {
+    err = h.rewrite()
+  }
+  if err == nil {
+    h.file, err = os.OpenFile(path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0644)
+  }
+  if err != nil {
+    lock.Close()
+    return nil, err
+  }
+  return h, nil
+}
+
+func (h *History) load(since int64) (bool, error) {
+  f, err := os.Open(h.path)
+  if os.IsNotExist(err) {
+    return false, nil
+  }
+  if err != nil {
+    return false, err
+  }
+  defer f.Close()
+
+  expired := false
+  fine := time.Now().Add(-fineSpan).Unix()
+  // lines grow with the workers, read whole however long
+  r := bufio.NewReader(f)
+  for {
+    line, err := r.ReadString('\n')
+    if err != nil && err != io.EOF {
+      return expired, err
+    }
+    if err == io.EOF && line == "" {
+      break
+    }
+    fields := strings.Fields(line)
+    if len(fields) == 0 {
+      continue
+    }
+    t, err := strconv.ParseInt(fields[0], 10, 64)
+    // skip lines torn by a crash, along with expired ones
+    if err != nil || t < since {
+      expired = true
+      continue
+    }
+    values := make(map[string]float64)
+    for _, field := range fields[1:] {
+      i := strings.LastIndex(field, "=")
+      if i == -1 {
+        continue
+      }
+      if v, err := strconv.ParseFloat(field[i + 1:], 64); err == nil {
+        values[field[:i]] = v
+      }
+    }
+    h.append(t, values)
+    h.compact(fine)
+  }
+  return expired, nil
+}
+
+func (h *History) rewrite() error {
+  tmp := h.path + ".tmp"
+  f, err := os.Create(tmp)
+  if err != nil {
+    return err
+  }
+  w := bufio.NewWriter(f)
+  for i, t := range h.times {
+    values := make(map[string]float64)
+    for name, s := range h.series {
+      if !math.IsNaN(s[i]) {
+        values[name] = s[i]
+      }
+    }
+    if _, err := w.WriteString(formatLine(t, values)); err != nil {
+      f.Close()
+      return err
+    }
+  }
+  if err := w.Flush(); err != nil {
+    f.Close()
+    return err
+  }
+  if err := f.Close(); err != nil {
+    return err
+  }
+  return os.Rename(tmp, h.path)
+}
+
+var nameEscaper = strings.NewReplacer(" ", "_", "\t", "_", "\n", "_")
+
+func formatLine(t int64, values map[string]float64) string {
+  names := make([]string, 0, len(values))
+  for name := range values {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  line := strconv.FormatInt(t, 10)
+  for _, name := range names {
+    line += fmt.Sprintf(" %s=%s", nameEscaper.Replace(name), strconv.FormatFloat(values[name], 'g', -1, 64))
+  }
+  return line + "\n"
+}
+
+// append adds a step, keeping every series aligned with times
+func (h *History) append(t int64, values map[string]float64) {
+  n := len(h.times)
+  h.times = append(h.times, t)
+  for name, v := range values {
+    s, ok := h.series[name]
+    if !ok {
+      s = make([]float64, n, n + 1)
+      for i := range s {
+        s[i] = math.NaN()
+      }
+    }
+    h.series[name] = append(s, v)
+  }
+  for name, s := range h.series {
+    if len(s) == n {
+      h.series[name] = append(s, math.NaN())
+    }
+  }
+}
+
+// expire drops the steps before since from memory, the file is trimmed
+// when next opened
+func (h *History) expire(since int64) {
+  n := sort.Search(len(h.times), func(i int) bool { return h.times[i] >= since })
+  if n == 0 {
+    return
+  }
+  h.times = h.times[n:]
+  for name, s := range h.series {
+    h.series[name] = s[n:]
+  }
+  h.compacted = max(h.compacted - n, 0)
+}
+
+// compact averages the steps before the time into coarse steps, leaving the
+// coarse step of the last step open for those that follow
+func (h *History) compact(before int64) {
+  if len(h.times) == 0 {
+    return
+  }
+  coarse := int64(coarseStep / time.Second)
+  before = min(before, h.times[len(h.times) - 1]) / coarse * coarse
+  j := sort.Search(len(h.times), func(i int) bool { return h.times[i] >= before })
+  if j <= h.compacted {
+    return
+  }
+  // the first step of each coarse step, and the end
+  var bounds []int
+  for i := h.compacted; i < j; i++ {
+    if i == h.compacted || h.times[i] / coarse != h.times[i - 1] / coarse {
+      bounds = append(bounds, i)
+    }
+  }
+  bounds = append(bounds, j)
+  n := h.compacted + len(bounds) - 1
+  // each coarse step is written over steps already averaged
+  for name, s := range h.series {
+    for b := 0; b + 1 < len(bounds); b++ {
+      var a accumulator
+      for _, v := range s[bounds[b]:bounds[b + 1]] {
+        if !math.IsNaN(v) {
+          a.sum += v
+          a.n++
+        }
+      }
+      s[h.compacted + b] = math.NaN()
+      if a.n > 0 {
+        s[h.compacted + b] = a.sum / float64(a.n)
+      }
+    }
+    h.series[name] = append(s[:n], s[j:]...)
+  }
+  for b := 0; b + 1 < len(bounds); b++ {
+    h.times[h.compacted + b] = h.times[bounds[b]] / coarse * coarse
+  }
+  h.times = append(h.times[:n], h.times[j:]...)
+  h.compacted = n
+}
+
+func (h *History) flush() {
+  if len(h.pending) == 0 {
+    return
+  }
+  values := make(map[string]float64)
+  for name, a := range h.pending {
+    values[name] = a.sum / float64(a.n)
+  }
+  h.pending = make(map[string]*accumulator)
+  h.append(h.bucket, values)
+  h.expire(h.bucket - int64(h.retention / time.Second))
+  h.compact(h.bucket - int64(fineSpan / time.Second))
+  if h.file != nil && h.err == nil {
+    if _, err := h.file.WriteString(formatLine(h.bucket, values)); err != nil {
+      h.err = err
+    }
+  }
+}
+
+// Err reports why steps are no longer appended to the file
+func (h *History) Err() error {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+  return h.err
+}
+
+// Record adds values observed at t to the average of its step
+func (h *History) Record(t time.Time, values map[string]float64) {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+  bucket := t.Truncate(h.step).Unix()
+  if bucket != h.bucket {
+    h.flush()
+    h.bucket = bucket
+  }
+  for name, v := range values {
+    a := h.pending[name]
+    if a == nil {
+      a = &accumulator{}
+      h.pending[name] = a
+    }
+    a.sum += v
+    a.n++
+  }
+}
+
+func (h *History) Names() []string {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+  var names []string
+  for name := range h.series {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+// Range averages the named series into n buckets spanning from to to. Steps
+// without a sample carry the previous bucket forward, and are zero before
+// the first.
+func (h *History) Range(name string, from time.Time, to time.Time, n int) []float64 {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+  data := make([]float64, n)
+  s := h.series[name]
+  if s == nil || n == 0 {
+    return data
+  }
+  start, end := from.Unix(), to.Unix()
+  width := float64(end - start) / float64(n)
+  i := sort.Search(len(h.times), func(i int) bool { return h.times[i] >= start })
+  last := 0.0
+  for j := i - 1; j >= 0; j-- {
+    if !math.IsNaN(s[j]) {
+      last = s[j]
+      break
+    }
+  }
+  for b := 0; b < n; b++ {
+    limit := start + int64(width * float64(b + 1))
+    var a accumulator
+    for ; i < len(h.times) && h.times[i] < limit; i++ {
+      if !math.IsNaN(s[i]) {
+        a.sum += s[i]
+        a.n++
+      }
+    }
+    if a.n > 0 {
+      last = a.sum / float64(a.n)
+    }
+    data[b] = last
+  }
+  return data
+}
+
+func (h *History) Close() error {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+  h.flush()
+  defer h.lock.Close()
+  return h.file.Close()
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package client
+
+import (
+  "errors"
+  "os"
+  "strings"
+  "syscall"
+)
+
+// lockHistory keeps a second client from appending to the same history
+func lockHistory(f *os.File) error {
+  err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX | syscall.LOCK_NB)
+  if errors.Is(err, syscall.EWOULDBLOCK) {
+    return errors.New(strings.TrimSuffix(f.Name(), ".lock") + " is in use by another client")
+  }
+  return err
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package client
+
+import "os"
+
+func lockHistory(f *os.File) error {
+  return nil
+}
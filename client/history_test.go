@@ -0,0 +1,44 @@
+package client_test
+
+import (
+  "fmt"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+  "github.com/werkt/bf-client/client"
+)
+
+func TestHistoryLoad(t *testing.T) {
+  path := filepath.Join(t.TempDir(), "history")
+  // hours align with the coarse steps
+  now := time.Now().Truncate(time.Hour)
+  var lines []string
+  // a step of every 10s for the last 12 hours
+  for d := 12 * time.Hour; d > 0; d -= 10 * time.Second {
+    lines = append(lines, fmt.Sprintf("%d queue=%d", now.Add(-d).Unix(), int((12 * time.Hour - d) / time.Hour)))
+  }
+  // a torn line, and one longer than any buffer
+  lines = append(lines, "17000", "torn=1")
+  lines = append(lines, fmt.Sprintf("%d long=1 %s=1", now.Unix(), strings.Repeat("x", 2 << 20)))
+  if err := os.WriteFile(path, []byte(strings.Join(lines, "\n") + "\n"), 0644); err != nil {
+    t.Fatal(err)
+  }
+
+  h, err := client.OpenHistory(path, 10 * time.Second, 7 * 24 * time.Hour)
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer h.Close()
+  // the averages of each hour survive the older steps being kept coarse
+  data := h.Range("queue", now.Add(-12 * time.Hour), now, 12)
+  for i, v := range data {
+    if want := float64(i); v != want {
+      t.Errorf("hour %d averaged %v, want %v", i, v, want)
+    }
+  }
+  if data := h.Range("long", now, now.Add(time.Minute), 1); data[0] != 1 {
+    t.Errorf("read long from the long line as %v, want 1", data[0])
+  }
+}
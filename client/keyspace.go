@@ -0,0 +1,249 @@
+package client
+
+import (
+  "context"
+  "fmt"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+  reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/golang/protobuf/jsonpb"
+  "github.com/golang/protobuf/proto"
+  redis "github.com/redis/go-redis/v9"
+  "google.golang.org/genproto/googleapis/longrunning"
+)
+
+// RedisKey is a redis key held by the primary at Node
+type RedisKey struct {
+  Name string
+  Node string
+  Type string
+  // Size counts the elements of lists, zsets, hashes and sets, and the
+  // bytes of strings
+  Size int64
+  // TTL is negative for keys without an expiry
+  TTL time.Duration
+  Err error
+}
+
+// Field is one element of a key: a hash field, a zset member with its score
+// as Name, a list value with its index as Name, or a string value
+type Field struct {
+  Name string
+  Value string
+}
+
+// keyMessage is the message buildfarm stores under keys of a prefix
+type keyMessage struct {
+  prefix string
+  message func() proto.Message
+}
+
+// keyMessages are tried in order on values of any other prefix, including
+// the entries of queues, which are named by configuration
+var keyMessages = []keyMessage {
+  keyMessage { "", func() proto.Message { return &bfpb.QueueEntry{} } },
+  keyMessage { "", func() proto.Message { return &bfpb.ExecuteEntry{} } },
+  keyMessage { "DispatchedOperations", func() proto.Message { return &bfpb.DispatchedOperation{} } },
+  keyMessage { "Workers", func() proto.Message { return &bfpb.ShardWorker{} } },
+  keyMessage { "Operation", func() proto.Message { return &longrunning.Operation{} } },
+  keyMessage { "ActionCache", func() proto.Message { return &reapi.ActionResult{} } },
+}
+
+// KeyPrefix groups a key by its name past any hash tag, up to the first
+// colon, so that the shards of a queue share a prefix
+func KeyPrefix(name string) string {
+  if strings.HasPrefix(name, "{") {
+    if i := strings.Index(name, "}"); i != -1 {
+      name = name[i + 1:]
+    }
+  }
+  if i := strings.Index(name, ":"); i != -1 {
+    return name[:i]
+  }
+  return name
+}
+
+// primaries are the addresses of the node of each shard
+func primaries(ctx context.Context, c *UnifiedRedis) []string {
+  result := c.ClusterShards(ctx)
+  if result.Err() != nil {
+    return []string { c.Addr() }
+  }
+  var addrs []string
+  for _, shard := range result.Val() {
+    if addr := shardAddr(shard); addr != "" {
+      addrs = append(addrs, addr)
+    }
+  }
+  return addrs
+}
+
+func ksize(ctx context.Context, p redis.Cmdable, key string, t string) *redis.IntCmd {
+  switch t {
+  case "list":
+    return p.LLen(ctx, key)
+  case "zset":
+    return p.ZCard(ctx, key)
+  case "hash":
+    return p.HLen(ctx, key)
+  case "set":
+    return p.SCard(ctx, key)
+  case "string":
+    return p.StrLen(ctx, key)
+  }
+  return nil
+}
+
+// describe fills in the type, size and ttl of keys, all held by node
+func describe(ctx context.Context, node *redis.Client, keys []*RedisKey) error {
+  types := make([]*redis.StatusCmd, len(keys))
+  ttls := make([]*redis.DurationCmd, len(keys))
+  _, err := node.Pipelined(ctx, func(p redis.Pipeliner) error {
+    for i, k := range keys {
+      types[i] = p.Type(ctx, k.Name)
+      ttls[i] = p.TTL(ctx, k.Name)
+    }
+    return nil
+  })
+  if err != nil && err != redis.Nil {
+    return err
+  }
+  sizes := make([]*redis.IntCmd, len(keys))
+  _, err = node.Pipelined(ctx, func(p redis.Pipeliner) error {
+    for i, k := range keys {
+      k.Type, k.Err = types[i].Result()
+      k.TTL = ttls[i].Val()
+      if k.Err == nil {
+        sizes[i] = ksize(ctx, p, k.Name, k.Type)
+      }
+    }
+    return nil
+  })
+  for i, k := range keys {
+    if sizes[i] != nil {
+      k.Size, k.Err = sizes[i].Result()
+    }
+  }
+  if err == redis.Nil {
+    return nil
+  }
+  return err
+}
+
+// ScanKeys finds up to limit keys matching pattern on the primary of every
+// shard, in order of name
+func ScanKeys(ctx context.Context, c *UnifiedRedis, pattern string, limit int) ([]*RedisKey, error) {
+  var keys []*RedisKey
+  for _, addr := range primaries(ctx, c) {
+    node := c.Node(addr)
+    // a scan may return a key more than once
+    seen := make(map[string]bool)
+    var found []*RedisKey
+    var cursor uint64
+    for len(keys) + len(found) < limit {
+      names, next, err := node.Scan(ctx, cursor, pattern, 1000).Result()
+      if err != nil {
+        return keys, fmt.Errorf("%s: %v", addr, err)
+      }
+      for _, name := range names {
+        if !seen[name] && len(keys) + len(found) < limit {
+          seen[name] = true
+          found = append(found, &RedisKey { Name: name, Node: addr })
+        }
+      }
+      if next == 0 {
+        break
+      }
+      cursor = next
+    }
+    if err := describe(ctx, node, found); err != nil {
+      return keys, fmt.Errorf("%s: %v", addr, err)
+    }
+    keys = append(keys, found...)
+  }
+  sort.Slice(keys, func(i, j int) bool {
+    return keys[i].Name < keys[j].Name
+  })
+  return keys, nil
+}
+
+// Contents reads up to limit elements of the key
+func (k *RedisKey) Contents(ctx context.Context, c *UnifiedRedis, limit int64) ([]Field, error) {
+  node := c.Node(k.Node)
+  var fields []Field
+  switch k.Type {
+  case "list":
+    values, err := node.LRange(ctx, k.Name, 0, limit - 1).Result()
+    for i, value := range values {
+      fields = append(fields, Field { strconv.Itoa(i), value })
+    }
+    return fields, err
+  case "zset":
+    members, err := node.ZRangeWithScores(ctx, k.Name, 0, limit - 1).Result()
+    for _, m := range members {
+      fields = append(fields, Field { strconv.FormatFloat(m.Score, 'f', -1, 64), fmt.Sprint(m.Member) })
+    }
+    return fields, err
+  case "hash":
+    var cursor uint64
+    for int64(len(fields)) < limit {
+      values, next, err := node.HScan(ctx, k.Name, cursor, "*", 1000).Result()
+      if err != nil {
+        return fields, err
+      }
+      for i := 0; i + 1 < len(values) && int64(len(fields)) < limit; i += 2 {
+        fields = append(fields, Field { values[i], values[i + 1] })
+      }
+      if next == 0 {
+        break
+      }
+      cursor = next
+    }
+    return fields, nil
+  case "set":
+    values, _, err := node.SScan(ctx, k.Name, 0, "*", limit).Result()
+    for _, value := range values {
+      fields = append(fields, Field { "", value })
+    }
+    return fields, err
+  case "string":
+    value, err := node.Get(ctx, k.Name).Result()
+    return []Field { Field { "", value } }, err
+  }
+  return nil, fmt.Errorf("cannot read a %s", k.Type)
+}
+
+func decodeMessage(m proto.Message, value string) (string, error) {
+  if err := jsonpb.Unmarshal(strings.NewReader(value), m); err != nil {
+    return "", err
+  }
+  return (&jsonpb.Marshaler{ Indent: "  " }).MarshalToString(m)
+}
+
+// DecodeValue renders a value held under key as the message buildfarm
+// stored, indented, or as is when it is not one
+func DecodeValue(key string, value string) string {
+  if isPriority(key) {
+    value = priorityValue(value)
+  }
+  if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+    return value
+  }
+  prefix := KeyPrefix(key)
+  for _, km := range keyMessages {
+    if km.prefix != "" && strings.HasSuffix(prefix, km.prefix) {
+      if s, err := decodeMessage(km.message(), value); err == nil {
+        return s
+      }
+    }
+  }
+  for _, km := range keyMessages {
+    if s, err := decodeMessage(km.message(), value); err == nil {
+      return s
+    }
+  }
+  return value
+}
@@ -0,0 +1,24 @@
+package client_test
+
+import (
+  "strings"
+  "testing"
+  "github.com/werkt/bf-client/client"
+)
+
+func TestDecodeValue(t *testing.T) {
+  entry := `{"executeEntry":{"operationName":"shard/executions/1"}}`
+  for _, c := range []struct {
+    key string
+    value string
+  } {
+    { key: "{Execution}:QueuedOperations", value: entry },
+    // members of _priority zsets are prefixed by the time they were queued
+    { key: "{Execution}:QueuedOperations_priority", value: "1700000000000:" + entry },
+  } {
+    got := client.DecodeValue(c.key, c.value)
+    if got == c.value || !strings.Contains(got, `"operationName": "shard/executions/1"`) {
+      t.Errorf("%s: decoded %s as %s", c.key, c.value, got)
+    }
+  }
+}
@@ -23,6 +23,9 @@ type List struct {
   SelectedRowStyle ui.Style
   SubTitle         fmt.Stringer
   SubTitleStyle    ui.Style
+
+  // lines maps each drawn inner line to the row it belongs to
+  lines []int
 }
 
 type emptyStringer struct {
@@ -68,7 +71,9 @@ func (self *List) Draw(buf *ui.Buffer) {
   }
 
   // draw rows
+  self.lines = self.lines[:0]
   for row := self.topRow; row < len(self.Rows) && point.Y < self.Inner.Max.Y; row++ {
+    startY := point.Y
     cells := ui.ParseStyles(self.Rows[row].String(), self.TextStyle)
     if self.WrapText {
       cells = ui.WrapCells(cells, uint(self.Inner.Dx()))
@@ -91,6 +96,9 @@ func (self *List) Draw(buf *ui.Buffer) {
       }
     }
     point = image.Pt(self.Inner.Min.X, point.Y+1)
+    for y := startY; y < point.Y && y < self.Inner.Max.Y; y++ {
+      self.lines = append(self.lines, row)
+    }
   }
 
   // draw UP_ARROW if needed
@@ -110,6 +118,19 @@ func (self *List) Draw(buf *ui.Buffer) {
   }
 }
 
+// RowAt returns the index of the row drawn at the screen point p, or -1 if
+// there is none. Only valid for the most recent Draw.
+func (self *List) RowAt(p image.Point) int {
+  if !p.In(self.Inner) {
+    return -1
+  }
+  line := p.Y - self.Inner.Min.Y
+  if line < len(self.lines) {
+    return self.lines[line]
+  }
+  return -1
+}
+
 // ScrollAmount scrolls by amount given. If amount is < 0, then scroll up.
 // There is no need to set self.topRow, as this will be set automatically when drawn,
 // since if the selected item is off screen then the topRow variable will change accordingly.
@@ -1,12 +1,14 @@
 package client
 
 import (
+  "context"
   "errors"
   reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
   bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
   "github.com/golang/protobuf/ptypes"
   "github.com/golang/protobuf/proto"
   "google.golang.org/genproto/googleapis/longrunning"
+  "google.golang.org/grpc"
 )
 
 type Operation struct {
@@ -78,3 +80,37 @@ func ExecutedActionMetadata(o *longrunning.Operation) (*reapi.ExecutedActionMeta
   }
   return em.PartialExecutionMetadata, nil
 }
+
+func GetOperation(c *grpc.ClientConn, name string) (*longrunning.Operation, error) {
+  ops := longrunning.NewOperationsClient(c)
+  return ops.GetOperation(context.Background(), &longrunning.GetOperationRequest {
+    Name: name,
+  })
+}
+
+// ListOperations pages through every operation in the named collection that
+// matches filter
+func ListOperations(c *grpc.ClientConn, name string, filter string, cb func(*longrunning.Operation) error) error {
+  ops := longrunning.NewOperationsClient(c)
+  pageToken := ""
+  for {
+    r, err := ops.ListOperations(context.Background(), &longrunning.ListOperationsRequest {
+      Name: name,
+      Filter: filter,
+      PageSize: 100,
+      PageToken: pageToken,
+    })
+    if err != nil {
+      return err
+    }
+    for _, op := range r.Operations {
+      if err := cb(op); err != nil {
+        return err
+      }
+    }
+    pageToken = r.NextPageToken
+    if pageToken == "" {
+      return nil
+    }
+  }
+}
@@ -4,6 +4,7 @@ import (
   "image"
 
   ui "github.com/gizak/termui/v3"
+  rw "github.com/mattn/go-runewidth"
 )
 
 type Paragraph struct {
@@ -32,9 +33,7 @@ func rawCells(s string, defaultStyle ui.Style) []ui.Cell {
   return cells
 }
 
-func (self *Paragraph) Draw(buf *ui.Buffer) {
-  self.Block.Draw(buf)
-
+func (self *Paragraph) cells() []ui.Cell {
   var cells []ui.Cell
   if self.Raw {
     cells = rawCells(self.Text, self.TextStyle)
@@ -44,8 +43,13 @@ func (self *Paragraph) Draw(buf *ui.Buffer) {
   if self.WrapText {
     cells = ui.WrapCells(cells, uint(self.Inner.Dx()))
   }
+  return cells
+}
+
+func (self *Paragraph) Draw(buf *ui.Buffer) {
+  self.Block.Draw(buf)
 
-  rows := ui.SplitCells(cells, '\n')
+  rows := ui.SplitCells(self.cells(), '\n')
 
   for y, row := range rows {
     if y+self.Inner.Min.Y >= self.Inner.Max.Y {
@@ -58,3 +62,29 @@ func (self *Paragraph) Draw(buf *ui.Buffer) {
     }
   }
 }
+
+// CellAt returns the index into the styled text of the cell drawn at the
+// screen point p, or -1 if there is none. Wrapping replaces spaces with
+// newlines, so indices line up with the unwrapped text.
+func (self *Paragraph) CellAt(p image.Point) int {
+  if !p.In(self.Inner) {
+    return -1
+  }
+  rows := ui.SplitCells(self.cells(), '\n')
+  i := 0
+  for y, row := range rows {
+    if y == p.Y-self.Inner.Min.Y {
+      row = ui.TrimCells(row, self.Inner.Dx())
+      for j, cx := range ui.BuildCellWithXArray(row) {
+        x := cx.X + self.Inner.Min.X
+        if p.X >= x && p.X < x+rw.RuneWidth(cx.Cell.Rune) {
+          return i + j
+        }
+      }
+      return -1
+    }
+    // account for the newline consumed by the split
+    i += len(row) + 1
+  }
+  return -1
+}
@@ -11,7 +11,30 @@ import (
 )
 
 type Queue struct {
+  Name string
   keys []string
+  // shards holds the address of the node serving each key
+  shards []string
+}
+
+// Entry is one value held in a queue
+type Entry struct {
+  // Member is the value as stored, prefixed for _priority zsets
+  Member string
+  Value string
+  // Queue is the name of the queue of Key, which holds the entry on the
+  // node at Shard
+  Queue string
+  Key string
+  Shard string
+  // Score orders the entries of _priority zsets
+  Score float64
+  Priority bool
+  // QueueEntry wraps the ExecuteEntry of prequeued values
+  QueueEntry *bfpb.QueueEntry
+  // Operation is the value as decoded by Slice
+  Operation *Operation
+  Err error
 }
 
 func slotRangesContainsSlot(slots []redis.SlotRange, n int) bool {
@@ -40,25 +63,50 @@ func createName(name string, slots []redis.SlotRange) string {
   return fmt.Sprintf("{%s}%s", hash, name)
 }
 
+func nodeAddr(node redis.Node) string {
+  host := node.Endpoint
+  if host == "" || host == "?" {
+    host = node.IP
+  }
+  return fmt.Sprintf("%s:%d", host, node.Port)
+}
+
+func shardAddr(shard redis.ClusterShard) string {
+  for _, node := range shard.Nodes {
+    if node.Role == "master" {
+      return nodeAddr(node)
+    }
+  }
+  return ""
+}
+
 func NewQueue(ctx context.Context, c *UnifiedRedis, name string) *Queue {
-  var keys []string
+  var keys, addrs []string
   result := c.ClusterShards(ctx)
   if result.Err() != nil {
     keys = append(keys, name)
+    addrs = append(addrs, c.Addr())
   } else {
     shards := result.Val()
     for _, shard := range shards {
       keys = append(keys, createName(name, shard.Slots))
+      addrs = append(addrs, shardAddr(shard))
     }
   }
   return &Queue {
+    Name: name,
     keys: keys,
+    shards: addrs,
   }
 }
 
+// Keys are the redis keys of the queue, one for each shard of a cluster
+func (q *Queue) Keys() []string {
+  return q.keys
+}
+
 func rlen(ctx context.Context, c *UnifiedRedis, key string) *redis.IntCmd {
-  // hacks
-  if strings.HasSuffix(key, "_priority") {
+  if isPriority(key) {
     return c.ZCard(ctx, key)
   }
   return c.LLen(ctx, key)
@@ -100,36 +148,71 @@ func ParseQueueName(json string) (*Operation, error) {
   }, nil
 }
 
-func rrange(ctx context.Context, c *UnifiedRedis, key string, start int64, stop int64) ([]string, error) {
+// ParseEntry decodes a queued QueueEntry, or a prequeued ExecuteEntry into
+// an otherwise empty QueueEntry
+func ParseEntry(json string) (*bfpb.QueueEntry, error) {
+  qe := &bfpb.QueueEntry{}
+  if err := jsonpb.Unmarshal(strings.NewReader(json), qe); err == nil && qe.ExecuteEntry != nil {
+    return qe, nil
+  }
+  ee := &bfpb.ExecuteEntry{}
+  if err := jsonpb.Unmarshal(strings.NewReader(json), ee); err != nil {
+    return nil, err
+  }
+  return &bfpb.QueueEntry { ExecuteEntry: ee }, nil
+}
+
+func isPriority(key string) bool {
   // hacks
-  if strings.HasSuffix(key, "_priority") {
-    var entries []string
-    z := c.ZRange(ctx, key, start, stop)
+  return strings.HasSuffix(key, "_priority")
+}
+
+// priorityValue is the value of a _priority zset member, without the
+// time prefix that makes it unique
+func priorityValue(member string) string {
+  return member[strings.Index(member, ":") + 1:]
+}
+
+func rrange(ctx context.Context, c *UnifiedRedis, key string, start int64, stop int64) ([]*Entry, error) {
+  var entries []*Entry
+  if isPriority(key) {
+    z := c.ZRangeWithScores(ctx, key, start, stop)
     for _, e := range z.Val() {
-      entries = append(entries, e[strings.Index(e, ":") + 1:])
+      member := fmt.Sprint(e.Member)
+      entries = append(entries, &Entry {
+        Member: member,
+        Value: priorityValue(member),
+        Key: key,
+        Score: e.Score,
+        Priority: true,
+      })
     }
     return entries, z.Err()
   }
   l := c.LRange(ctx, key, start, stop)
-  return l.Val(), l.Err()
-}
-
-func (q *Queue) Slice(ctx context.Context, c *UnifiedRedis, start int64, stop int64, cb func(string) (*Operation, error)) []*Operation {
-  var ops []*Operation
-  for _, key := range q.keys {
-    if entries, err := rrange(ctx, c, key, start, stop); err == nil {
-      for _, entry := range entries {
-        name, err := cb(entry)
-        if err != nil {
-          panic(err)
-        }
-        ops = append(ops, name)
-      }
-      stop -= int64(len(ops))
-      if stop <= 0 {
-        break
-      }
-    }
+  for _, value := range l.Val() {
+    entries = append(entries, &Entry { Member: value, Value: value, Key: key })
+  }
+  return entries, l.Err()
+}
+
+// fill sets the queue, shard and decoded entry of e, read from the i'th key
+func (q *Queue) fill(e *Entry, i int) {
+  e.Queue = q.Name
+  e.Shard = q.shards[i]
+  e.QueueEntry, e.Err = ParseEntry(e.Value)
+}
+
+// Slice reads the entries from start to stop, inclusive, in the order they
+// will be dequeued, decoding each into Operation with cb. An entry cb cannot
+// decode keeps the error in Err.
+func (q *Queue) Slice(ctx context.Context, c *UnifiedRedis, start int64, stop int64, cb func(string) (*Operation, error)) ([]*Entry, error) {
+  entries, err := q.Cursor(stop + 1).Read(ctx, c)
+  if err != nil || int64(len(entries)) <= start {
+    return nil, err
+  }
+  for _, entry := range entries[start:] {
+    entry.Operation, entry.Err = cb(entry.Value)
   }
-  return ops
+  return entries[start:], nil
 }
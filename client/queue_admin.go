@@ -0,0 +1,223 @@
+package client
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "strings"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/golang/protobuf/jsonpb"
+  redis "github.com/redis/go-redis/v9"
+)
+
+// Command is one redis command of a change to the queues, described for a
+// dry run before it is applied
+type Command struct {
+  Description string
+  apply func(ctx context.Context, c *UnifiedRedis) error
+}
+
+func (cmd Command) String() string {
+  return cmd.Description
+}
+
+func (cmd Command) Apply(ctx context.Context, c *UnifiedRedis) error {
+  return cmd.apply(ctx, c)
+}
+
+// Apply runs commands in order, stopping at the first failure, and returns
+// the number applied
+func Apply(ctx context.Context, c *UnifiedRedis, commands []Command) (int, error) {
+  for i, cmd := range commands {
+    if err := cmd.Apply(ctx, c); err != nil {
+      return i, err
+    }
+  }
+  return len(commands), nil
+}
+
+// removed fails a removal that found nothing, so that a change to an entry
+// taken meanwhile goes no further
+func removed(cmd *redis.IntCmd, what string) error {
+  if err := cmd.Err(); err != nil {
+    return err
+  }
+  if cmd.Val() == 0 {
+    return fmt.Errorf("%s is already gone", what)
+  }
+  return nil
+}
+
+func (e *Entry) String() string {
+  if e.QueueEntry != nil {
+    return e.QueueEntry.ExecuteEntry.OperationName
+  }
+  if len(e.Value) > 40 {
+    return e.Value[:40] + "..."
+  }
+  return e.Value
+}
+
+func RemoveEntry(e *Entry) Command {
+  if e.Priority {
+    return Command {
+      Description: fmt.Sprintf("ZREM %s %s", e.Key, e),
+      apply: func(ctx context.Context, c *UnifiedRedis) error {
+        return removed(c.ZRem(ctx, e.Key, e.Member), e.String())
+      },
+    }
+  }
+  return Command {
+    Description: fmt.Sprintf("LREM %s 1 %s", e.Key, e),
+    apply: func(ctx context.Context, c *UnifiedRedis) error {
+      return removed(c.LRem(ctx, e.Key, 1, e.Member), e.String())
+    },
+  }
+}
+
+// push adds value to the shortest key of q, at score for _priority zsets
+func (q *Queue) push(ctx context.Context, c *UnifiedRedis, value string, score float64, what string) (Command, error) {
+  key := ""
+  var shortest int64
+  for _, k := range q.keys {
+    n, err := rlen(ctx, c, k).Result()
+    if err != nil {
+      return Command{}, err
+    }
+    if key == "" || n < shortest {
+      key, shortest = k, n
+    }
+  }
+  if key == "" {
+    return Command{}, fmt.Errorf("%s has no keys", q.Name)
+  }
+  if isPriority(key) {
+    // members are made unique by a time prefix
+    member := fmt.Sprintf("%d:%s", time.Now().UnixMilli(), value)
+    return Command {
+      Description: fmt.Sprintf("ZADD %s %g %s", key, score, what),
+      apply: func(ctx context.Context, c *UnifiedRedis) error {
+        return c.ZAdd(ctx, key, redis.Z { Score: score, Member: member }).Err()
+      },
+    }, nil
+  }
+  return Command {
+    Description: fmt.Sprintf("LPUSH %s %s", key, what),
+    apply: func(ctx context.Context, c *UnifiedRedis) error {
+      return c.LPush(ctx, key, value).Err()
+    },
+  }, nil
+}
+
+// MoveEntry removes e and pushes it onto the queue to. The removal comes
+// first, so an entry dispatched meanwhile is not duplicated.
+func MoveEntry(ctx context.Context, c *UnifiedRedis, e *Entry, to *Queue) ([]Command, error) {
+  if e.Queue == to.Name {
+    return nil, fmt.Errorf("%s is already in %s", e, to.Name)
+  }
+  push, err := to.push(ctx, c, e.Value, e.Score, e.String())
+  if err != nil {
+    return nil, err
+  }
+  return []Command { RemoveEntry(e), push }, nil
+}
+
+// ReprioritizeEntry changes the score of an entry of a _priority zset
+func ReprioritizeEntry(e *Entry, score float64) (Command, error) {
+  if !e.Priority {
+    return Command{}, fmt.Errorf("%s is not a priority queue", e.Key)
+  }
+  return Command {
+    Description: fmt.Sprintf("ZADD %s XX CH %g %s (from %g)", e.Key, score, e, e.Score),
+    apply: func(ctx context.Context, c *UnifiedRedis) error {
+      return removed(c.ZAddArgs(ctx, e.Key, redis.ZAddArgs {
+        XX: true,
+        Ch: true,
+        Members: []redis.Z { redis.Z { Score: score, Member: e.Member } },
+      }), e.String())
+    },
+  }, nil
+}
+
+// RequeueDispatched takes the operation name out of the dispatched hash and
+// pushes it back onto the queue to, as an ExecuteEntry for the prequeue, to
+// be matched to a provision again, or as a QueueEntry with another requeue
+// attempt
+func RequeueDispatched(ctx context.Context, c *UnifiedRedis, name string, to *Queue, prequeue bool) ([]Command, error) {
+  json, err := c.HGet(ctx, DispatchedOperationsHash, name).Result()
+  if err == redis.Nil {
+    return nil, fmt.Errorf("%s is not dispatched", name)
+  } else if err != nil {
+    return nil, err
+  }
+  d := &bfpb.DispatchedOperation{}
+  if err := jsonpb.Unmarshal(strings.NewReader(json), d); err != nil {
+    return nil, err
+  }
+  if d.QueueEntry == nil || d.QueueEntry.ExecuteEntry == nil {
+    return nil, errors.New("dispatched operation has no queue entry")
+  }
+  var value string
+  m := &jsonpb.Marshaler{}
+  if prequeue {
+    value, err = m.MarshalToString(d.QueueEntry.ExecuteEntry)
+  } else {
+    d.QueueEntry.RequeueAttempts++
+    value, err = m.MarshalToString(d.QueueEntry)
+  }
+  if err != nil {
+    return nil, err
+  }
+  push, err := to.push(ctx, c, value, 0, name)
+  if err != nil {
+    return nil, err
+  }
+  return []Command {
+    Command {
+      Description: fmt.Sprintf("HDEL %s %s", DispatchedOperationsHash, name),
+      apply: func(ctx context.Context, c *UnifiedRedis) error {
+        return removed(c.HDel(ctx, DispatchedOperationsHash, name), name)
+      },
+    },
+    push,
+  }, nil
+}
+
+// EntryFilterFields are the request metadata fields entries are filtered by
+var EntryFilterFields = []string{"toolInvocationId", "correlatedInvocationsId", "targetId", "actionMnemonic"}
+
+// EntryFilter selects entries by a request metadata field
+type EntryFilter struct {
+  Field string
+  Value string
+}
+
+// ParseEntryFilter parses field=value, as in targetId=//a:b
+func ParseEntryFilter(s string) (EntryFilter, error) {
+  i := strings.Index(s, "=")
+  if i == -1 {
+    return EntryFilter{}, fmt.Errorf("expected field=value, with a field of %s", strings.Join(EntryFilterFields, ", "))
+  }
+  f := EntryFilter { Field: s[:i], Value: s[i + 1:] }
+  for _, field := range EntryFilterFields {
+    if f.Field == field {
+      return f, nil
+    }
+  }
+  return EntryFilter{}, fmt.Errorf("unknown field %s, expected one of %s", f.Field, strings.Join(EntryFilterFields, ", "))
+}
+
+func (f EntryFilter) Matches(e *Entry) bool {
+  if e.QueueEntry == nil || e.QueueEntry.ExecuteEntry.RequestMetadata == nil {
+    return false
+  }
+  m := e.QueueEntry.ExecuteEntry.RequestMetadata
+  switch f.Field {
+  case "toolInvocationId": return m.ToolInvocationId == f.Value
+  case "correlatedInvocationsId": return m.CorrelatedInvocationsId == f.Value
+  case "targetId": return m.TargetId == f.Value
+  case "actionMnemonic": return m.ActionMnemonic == f.Value
+  }
+  return false
+}
@@ -0,0 +1,134 @@
+package client_test
+
+import (
+  "context"
+  "slices"
+  "testing"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func TestMoveEntry(t *testing.T) {
+  s, a := faketest.Start(t)
+  names := faketest.SubmitN(t, s, 2)
+  ctx := context.Background()
+  prequeue := newQueue(a, fake.PrequeueName)
+  queue := newQueue(a, fake.QueueName)
+  entries, err := prequeue.Cursor(1).Read(ctx, a.Client)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if _, err := client.MoveEntry(ctx, a.Client, entries[0], prequeue); err == nil {
+    t.Error("moved an entry into its own queue")
+  }
+  commands, err := client.MoveEntry(ctx, a.Client, entries[0], queue)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, err := client.Apply(ctx, a.Client, commands); err != nil {
+    t.Fatal(err)
+  }
+  if got := s.Redis.LLen(fake.PrequeueName); got != 1 {
+    t.Errorf("prequeue holds %d entries, want 1", got)
+  }
+  moved, err := queue.Cursor(10).Read(ctx, a.Client)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := entryNames(moved); !slices.Equal(got, names[:1]) {
+    t.Errorf("queue holds %v, want %v", got, names[:1])
+  }
+
+  // the entry is gone, so moving it again changes nothing
+  if _, err := client.Apply(ctx, a.Client, commands); err == nil {
+    t.Error("moved an entry that was already gone")
+  }
+  if got := s.Redis.LLen(fake.QueueName); got != 1 {
+    t.Errorf("queue holds %d entries, want 1", got)
+  }
+}
+
+func TestRequeueDispatched(t *testing.T) {
+  s, a := faketest.Start(t)
+  if _, err := s.AddWorker(1); err != nil {
+    t.Fatal(err)
+  }
+  names := faketest.SubmitN(t, s, 1)
+  // into the queue, then onto the worker
+  s.Step()
+  s.Step()
+  if got := s.Redis.HLen(client.DispatchedOperationsHash); got != 1 {
+    t.Fatalf("%d operations dispatched, want 1", got)
+  }
+  ctx := context.Background()
+  queue := newQueue(a, fake.QueueName)
+
+  if _, err := client.RequeueDispatched(ctx, a.Client, "missing", queue, false); err == nil {
+    t.Error("requeued an operation that is not dispatched")
+  }
+  commands, err := client.RequeueDispatched(ctx, a.Client, names[0], queue, false)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, err := client.Apply(ctx, a.Client, commands); err != nil {
+    t.Fatal(err)
+  }
+  if got := s.Redis.HLen(client.DispatchedOperationsHash); got != 0 {
+    t.Errorf("%d operations dispatched, want 0", got)
+  }
+  entries, err := queue.Cursor(10).Read(ctx, a.Client)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := entryNames(entries); !slices.Equal(got, names) {
+    t.Fatalf("queue holds %v, want %v", got, names)
+  }
+  if n := entries[0].QueueEntry.RequeueAttempts; n != 1 {
+    t.Errorf("requeued with %d attempts, want 1", n)
+  }
+}
+
+func TestRequeueDispatchedToPrequeue(t *testing.T) {
+  s, a := faketest.Start(t)
+  if _, err := s.AddWorker(1); err != nil {
+    t.Fatal(err)
+  }
+  names := faketest.SubmitN(t, s, 1)
+  s.Step()
+  s.Step()
+  ctx := context.Background()
+  prequeue := newQueue(a, fake.PrequeueName)
+
+  commands, err := client.RequeueDispatched(ctx, a.Client, names[0], prequeue, true)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if _, err := client.Apply(ctx, a.Client, commands); err != nil {
+    t.Fatal(err)
+  }
+  entries, err := prequeue.Cursor(10).Read(ctx, a.Client)
+  if err != nil {
+    t.Fatal(err)
+  }
+  // prequeued as an ExecuteEntry, to be matched to a provision again
+  if got := entryNames(entries); !slices.Equal(got, names) {
+    t.Fatalf("prequeue holds %v, want %v", got, names)
+  }
+  if _, err := client.ParsePrequeueName(entries[0].Value); err != nil {
+    t.Errorf("prequeued %s: %v", entries[0].Value, err)
+  }
+}
+
+func entryNames(entries []*client.Entry) []string {
+  var names []string
+  for _, e := range entries {
+    names = append(names, e.String())
+  }
+  return names
+}
+
+func newQueue(a *client.App, name string) *client.Queue {
+  return client.NewQueue(context.Background(), a.Client, name)
+}
@@ -0,0 +1,51 @@
+package client_test
+
+import (
+  "context"
+  "slices"
+  "testing"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func operationNames(entries []*client.Entry) []string {
+  var names []string
+  for _, e := range entries {
+    if e.Err != nil {
+      names = append(names, e.Value)
+    } else {
+      names = append(names, e.Operation.Name)
+    }
+  }
+  return names
+}
+
+func TestSlice(t *testing.T) {
+  s, a := faketest.Start(t)
+  names := faketest.SubmitN(t, s, 3)
+  ctx := context.Background()
+  q := newQueue(a, fake.PrequeueName)
+
+  entries, err := q.Slice(ctx, a.Client, 1, 2, client.ParsePrequeueName)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if got := operationNames(entries); !slices.Equal(got, names[1:3]) {
+    t.Errorf("sliced %v, want %v", got, names[1:3])
+  }
+
+  // an entry amid the rest that does not decode
+  s.Redis.LPush(fake.PrequeueName, "corrupt")
+  faketest.SubmitN(t, s, 1)
+  entries, err = q.Slice(ctx, a.Client, 0, 9, client.ParsePrequeueName)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(entries) != 5 || entries[3].Err == nil {
+    t.Fatalf("sliced %v, want the corrupt entry fourth with an error", operationNames(entries))
+  }
+  if got := operationNames(entries); !slices.Equal(got[:3], names) {
+    t.Errorf("sliced %v before the corrupt entry, want %v", got[:3], names)
+  }
+}
@@ -0,0 +1,305 @@
+package client
+
+import (
+  "bufio"
+  "context"
+  "encoding/json"
+  "errors"
+  "os"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/golang/protobuf/jsonpb"
+  "github.com/golang/protobuf/proto"
+  "google.golang.org/grpc"
+  "google.golang.org/grpc/codes"
+  "google.golang.org/grpc/status"
+)
+
+// recorded methods only read state, PipelineChange only without changes
+var recordedMethods = map[string]bool {
+  "/build.buildfarm.v1test.OperationQueue/Status": true,
+  "/build.buildfarm.v1test.WorkerProfile/GetWorkerProfile": true,
+  "/build.buildfarm.v1test.WorkerControl/PipelineChange": true,
+  "/google.longrunning.Operations/ListOperations": true,
+  "/google.longrunning.Operations/GetOperation": true,
+}
+
+type session struct {
+  RedisHost string `json:"redis"`
+  ReapiHost string `json:"reapi"`
+}
+
+// record is one line of a recording, the first carries only the session
+type record struct {
+  Time time.Time `json:"time"`
+  Session *session `json:"session,omitempty"`
+  Target string `json:"target,omitempty"`
+  Method string `json:"method,omitempty"`
+  Request json.RawMessage `json:"request,omitempty"`
+  Response json.RawMessage `json:"response,omitempty"`
+}
+
+func marshalMessage(m interface{}) (json.RawMessage, error) {
+  pm, ok := m.(proto.Message)
+  if !ok {
+    return nil, errors.New("not a proto message")
+  }
+  s, err := (&jsonpb.Marshaler{}).MarshalToString(pm)
+  return json.RawMessage(s), err
+}
+
+// requestKey identifies a request independent of its json formatting
+func requestKey(target string, method string, request json.RawMessage) string {
+  var v interface{}
+  if err := json.Unmarshal(request, &v); err == nil {
+    request, _ = json.Marshal(v)
+  }
+  return target + " " + method + " " + string(request)
+}
+
+// Recorder appends the responses to read-only calls on every connection of
+// an app to a file
+type Recorder struct {
+  file *os.File
+  mutex sync.Mutex
+}
+
+func NewRecorder(path string, a *App) (*Recorder, error) {
+  f, err := os.OpenFile(path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0644)
+  if err != nil {
+    return nil, err
+  }
+  r := &Recorder { file: f }
+  err = r.write(record {
+    Time: time.Now(),
+    Session: &session { RedisHost: a.RedisHost, ReapiHost: a.ReapiHost },
+  })
+  if err != nil {
+    f.Close()
+    return nil, err
+  }
+  return r, nil
+}
+
+func (r *Recorder) write(rec record) error {
+  b, err := json.Marshal(rec)
+  if err != nil {
+    return err
+  }
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  _, err = r.file.Write(append(b, '\n'))
+  return err
+}
+
+func isQuery(method string, req interface{}) bool {
+  if !recordedMethods[method] {
+    return false
+  }
+  if change, ok := req.(*bfpb.WorkerPipelineChangeRequest); ok {
+    return len(change.Changes) == 0
+  }
+  return true
+}
+
+func (r *Recorder) Intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+  err := invoker(ctx, method, req, reply, cc, opts...)
+  if err != nil || !isQuery(method, req) {
+    return err
+  }
+  request, merr := marshalMessage(req)
+  if merr != nil {
+    return err
+  }
+  response, merr := marshalMessage(reply)
+  if merr != nil {
+    return err
+  }
+  // a failure to record does not fail the call
+  r.write(record {
+    Time: time.Now(),
+    Target: cc.Target(),
+    Method: method,
+    Request: request,
+    Response: response,
+  })
+  return nil
+}
+
+func (r *Recorder) Close() error {
+  return r.file.Close()
+}
+
+// Replay answers the calls of an app from a recording, as of a clock that
+// plays, pauses and seeks within the recording
+type Replay struct {
+  RedisHost string
+  ReapiHost string
+  Start time.Time
+  End time.Time
+  records map[string][]record
+  position time.Time
+  wall time.Time
+  speed float64
+  paused bool
+  mutex sync.Mutex
+}
+
+func OpenReplay(path string) (*Replay, error) {
+  f, err := os.Open(path)
+  if err != nil {
+    return nil, err
+  }
+  defer f.Close()
+
+  r := &Replay {
+    records: make(map[string][]record),
+    speed: 1,
+  }
+  scanner := bufio.NewScanner(f)
+  scanner.Buffer(nil, 64 << 20)
+  for scanner.Scan() {
+    var rec record
+    if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+      return nil, err
+    }
+    if rec.Session != nil {
+      if r.ReapiHost == "" {
+        r.RedisHost, r.ReapiHost = rec.Session.RedisHost, rec.Session.ReapiHost
+      }
+      continue
+    }
+    if r.Start.IsZero() || rec.Time.Before(r.Start) {
+      r.Start = rec.Time
+    }
+    if rec.Time.After(r.End) {
+      r.End = rec.Time
+    }
+    key := requestKey(rec.Target, rec.Method, rec.Request)
+    r.records[key] = append(r.records[key], rec)
+  }
+  if err := scanner.Err(); err != nil {
+    return nil, err
+  }
+  if r.Start.IsZero() {
+    return nil, errors.New(path + ": no recorded responses")
+  }
+  for _, records := range r.records {
+    sort.Slice(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+  }
+  r.position = r.Start
+  r.wall = time.Now()
+  return r, nil
+}
+
+// advance moves the position by the wall time since the last advance
+func (r *Replay) advance() {
+  now := time.Now()
+  if !r.paused {
+    r.position = r.position.Add(time.Duration(float64(now.Sub(r.wall)) * r.speed))
+    if r.position.After(r.End) {
+      r.position = r.End
+      r.paused = true
+    }
+  }
+  r.wall = now
+}
+
+// Attach answers every call of a from the recording, showing its state as of
+// the position
+func (r *Replay) Attach(a *App) {
+  a.Offline = true
+  a.Clock = r.Now
+  a.DialOptions = append(a.DialOptions,
+      grpc.WithChainUnaryInterceptor(r.Intercept),
+      grpc.WithChainStreamInterceptor(r.InterceptStream))
+}
+
+func (r *Replay) Now() time.Time {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  r.advance()
+  return r.position
+}
+
+func (r *Replay) Paused() bool {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  return r.paused
+}
+
+func (r *Replay) Speed() float64 {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  return r.speed
+}
+
+func (r *Replay) TogglePause() {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  r.advance()
+  r.paused = !r.paused
+  // playing from the end starts over
+  if !r.paused && !r.position.Before(r.End) {
+    r.position = r.Start
+  }
+}
+
+// Seek moves the position by d, within the recording
+func (r *Replay) Seek(d time.Duration) {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  r.advance()
+  r.position = r.position.Add(d)
+  if r.position.Before(r.Start) {
+    r.position = r.Start
+  }
+  if r.position.After(r.End) {
+    r.position = r.End
+  }
+}
+
+func (r *Replay) SetSpeed(speed float64) {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  r.advance()
+  r.speed = speed
+}
+
+// response finds the last response recorded at or before the position, or
+// the first one after it
+func (r *Replay) response(key string) (json.RawMessage, bool) {
+  records := r.records[key]
+  if len(records) == 0 {
+    return nil, false
+  }
+  now := r.Now()
+  i := sort.Search(len(records), func(i int) bool { return records[i].Time.After(now) })
+  if i > 0 {
+    i--
+  }
+  return records[i].Response, true
+}
+
+func (r *Replay) Intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+  if !isQuery(method, req) {
+    // changes are dropped, leaving an empty reply
+    return nil
+  }
+  request, err := marshalMessage(req)
+  if err != nil {
+    return err
+  }
+  response, ok := r.response(requestKey(cc.Target(), method, request))
+  if !ok {
+    return status.Error(codes.Unavailable, "not recorded: " + method)
+  }
+  return jsonpb.Unmarshal(strings.NewReader(string(response)), reply.(proto.Message))
+}
+
+func (r *Replay) InterceptStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+  return nil, status.Error(codes.Unavailable, "streams are not recorded: " + method)
+}
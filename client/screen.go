@@ -0,0 +1,34 @@
+package client
+
+import (
+  "image"
+  "strings"
+
+  ui "github.com/gizak/termui/v3"
+)
+
+// Snapshot draws items into an off-screen buffer covering area and returns
+// its text, one line per row without trailing spaces
+func Snapshot(area image.Rectangle, items ...ui.Drawable) string {
+  buf := ui.NewBuffer(area)
+  for _, item := range items {
+    item.Lock()
+    item.Draw(buf)
+    item.Unlock()
+  }
+  var sb strings.Builder
+  for y := area.Min.Y; y < area.Max.Y; y++ {
+    var line []rune
+    for x := area.Min.X; x < area.Max.X; x++ {
+      r := buf.GetCell(image.Pt(x, y)).Rune
+      if r == 0 {
+        // the trailing half of a wide rune
+        continue
+      }
+      line = append(line, r)
+    }
+    sb.WriteString(strings.TrimRight(string(line), " "))
+    sb.WriteByte('\n')
+  }
+  return sb.String()
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+  "context"
+  "strings"
+  "sync"
+  "time"
+  redis "github.com/redis/go-redis/v9"
+)
+
+// RedisNode is one node of a shard, probed for its round trip and INFO
+type RedisNode struct {
+  Addr string
+  Role string
+  Health string
+  Latency time.Duration
+  // Info holds the fields of INFO by name
+  Info map[string]string
+  Err error
+}
+
+// RedisShard is a range of slots served by a primary and its replicas, with
+// the buildfarm keys it holds
+type RedisShard struct {
+  Slots []redis.SlotRange
+  Nodes []*RedisNode
+  Keys []string
+}
+
+func parseInfo(info string) map[string]string {
+  fields := make(map[string]string)
+  for _, line := range strings.Split(info, "\n") {
+    line = strings.TrimSpace(line)
+    if line == "" || strings.HasPrefix(line, "#") {
+      continue
+    }
+    if i := strings.Index(line, ":"); i != -1 {
+      fields[line[:i]] = line[i + 1:]
+    }
+  }
+  return fields
+}
+
+func (n *RedisNode) probe(ctx context.Context, c *UnifiedRedis) {
+  node := c.Node(n.Addr)
+  start := time.Now()
+  if err := node.Ping(ctx).Err(); err != nil {
+    n.Err = err
+    return
+  }
+  n.Latency = time.Since(start)
+  info, err := node.Info(ctx).Result()
+  if err != nil {
+    n.Err = err
+    return
+  }
+  n.Info = parseInfo(info)
+  if n.Role == "" {
+    n.Role = n.Info["role"]
+  }
+}
+
+// RedisTopology describes the shards of the cluster, or the single node,
+// probing every node and placing each of keys on the shard of its slot
+func RedisTopology(ctx context.Context, c *UnifiedRedis, keys []string) ([]*RedisShard, error) {
+  var shards []*RedisShard
+  result := c.ClusterShards(ctx)
+  if result.Err() != nil {
+    if c.Addr() == "" {
+      return nil, result.Err()
+    }
+    shards = append(shards, &RedisShard {
+      Slots: []redis.SlotRange { redis.SlotRange { Start: 0, End: slotNumber - 1 } },
+      Nodes: []*RedisNode { &RedisNode { Addr: c.Addr() } },
+    })
+  } else {
+    for _, shard := range result.Val() {
+      s := &RedisShard { Slots: shard.Slots }
+      for _, node := range shard.Nodes {
+        s.Nodes = append(s.Nodes, &RedisNode {
+          Addr: nodeAddr(node),
+          Role: node.Role,
+          Health: node.Health,
+        })
+      }
+      shards = append(shards, s)
+    }
+  }
+
+  var wg sync.WaitGroup
+  for _, s := range shards {
+    for _, n := range s.Nodes {
+      wg.Add(1)
+      go func(n *RedisNode) {
+        defer wg.Done()
+        n.probe(ctx, c)
+      }(n)
+    }
+  }
+  wg.Wait()
+
+  for _, key := range keys {
+    slot := Slot(key)
+    for _, s := range shards {
+      if slotRangesContainsSlot(s.Slots, slot) {
+        s.Keys = append(s.Keys, key)
+        break
+      }
+    }
+  }
+  return shards, nil
+}
@@ -176,6 +176,19 @@ func (self *Tree) ScrollAmount(amount int) {
   }
 }
 
+// RowAt returns the index of the row drawn at the screen point p, or -1 if
+// there is none.
+func (self *Tree) RowAt(p image.Point) int {
+  if !p.In(self.Inner) {
+    return -1
+  }
+  row := self.topRow + p.Y - self.Inner.Min.Y
+  if row < len(self.rows) {
+    return row
+  }
+  return -1
+}
+
 func (self *Tree) SelectedNode() *TreeNode {
   if len(self.rows) == 0 {
     return nil
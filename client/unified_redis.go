@@ -2,6 +2,9 @@ package client
 
 import (
   "context"
+  "errors"
+  "net"
+  "sync"
   "time"
   redis "github.com/redis/go-redis/v9"
 )
@@ -9,6 +12,9 @@ import (
 type UnifiedRedis struct {
   client *redis.Client
   cluster *redis.ClusterClient
+  // nodes are clients of each node by address, for per-node commands
+  nodes map[string]*redis.Client
+  mutex sync.Mutex
 }
 
 func (r *UnifiedRedis) connect(host string) {
@@ -35,6 +41,17 @@ func (r *UnifiedRedis) connect(host string) {
   }
 }
 
+// offline fails every command without connecting
+func (r *UnifiedRedis) offline() {
+  r.cluster = nil
+  r.client = redis.NewClient(&redis.Options{
+    Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+      return nil, errors.New("redis is offline")
+    },
+    MaxRetries: -1,
+  })
+}
+
 func (r *UnifiedRedis) ZCard(ctx context.Context, key string) *redis.IntCmd {
   if r.client != nil {
     return r.client.ZCard(ctx, key)
@@ -83,3 +100,136 @@ func (r *UnifiedRedis) ClusterShards(ctx context.Context) *redis.ClusterShardsCm
   }
   return r.cluster.ClusterShards(ctx)
 }
+
+func (r *UnifiedRedis) Ping(ctx context.Context) *redis.StatusCmd {
+  if r.client != nil {
+    return r.client.Ping(ctx)
+  }
+  return r.cluster.Ping(ctx)
+}
+
+func (r *UnifiedRedis) ZRangeWithScores(ctx context.Context, key string, start, stop int64) *redis.ZSliceCmd {
+  if r.client != nil {
+    return r.client.ZRangeWithScores(ctx, key, start, stop)
+  }
+  return r.cluster.ZRangeWithScores(ctx, key, start, stop)
+}
+
+// Addr is the address of a single node, empty for a cluster
+func (r *UnifiedRedis) Addr() string {
+  if r.client != nil {
+    return r.client.Options().Addr
+  }
+  return ""
+}
+
+func (r *UnifiedRedis) HGet(ctx context.Context, key, field string) *redis.StringCmd {
+  if r.client != nil {
+    return r.client.HGet(ctx, key, field)
+  }
+  return r.cluster.HGet(ctx, key, field)
+}
+
+func (r *UnifiedRedis) HDel(ctx context.Context, key string, fields ...string) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.HDel(ctx, key, fields...)
+  }
+  return r.cluster.HDel(ctx, key, fields...)
+}
+
+func (r *UnifiedRedis) LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.LPush(ctx, key, values...)
+  }
+  return r.cluster.LPush(ctx, key, values...)
+}
+
+func (r *UnifiedRedis) LRem(ctx context.Context, key string, count int64, value interface{}) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.LRem(ctx, key, count, value)
+  }
+  return r.cluster.LRem(ctx, key, count, value)
+}
+
+func (r *UnifiedRedis) ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.ZAdd(ctx, key, members...)
+  }
+  return r.cluster.ZAdd(ctx, key, members...)
+}
+
+func (r *UnifiedRedis) ZAddArgs(ctx context.Context, key string, args redis.ZAddArgs) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.ZAddArgs(ctx, key, args)
+  }
+  return r.cluster.ZAddArgs(ctx, key, args)
+}
+
+func (r *UnifiedRedis) ZRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.ZRem(ctx, key, members...)
+  }
+  return r.cluster.ZRem(ctx, key, members...)
+}
+
+func (r *UnifiedRedis) LPos(ctx context.Context, key string, value string, args redis.LPosArgs) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.LPos(ctx, key, value, args)
+  }
+  return r.cluster.LPos(ctx, key, value, args)
+}
+
+func (r *UnifiedRedis) ZRank(ctx context.Context, key, member string) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.ZRank(ctx, key, member)
+  }
+  return r.cluster.ZRank(ctx, key, member)
+}
+
+func (r *UnifiedRedis) ZCount(ctx context.Context, key, min, max string) *redis.IntCmd {
+  if r.client != nil {
+    return r.client.ZCount(ctx, key, min, max)
+  }
+  return r.cluster.ZCount(ctx, key, min, max)
+}
+
+// Subscribe listens on channels, of whichever node serves them in a
+// cluster, where published messages reach every node
+func (r *UnifiedRedis) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+  if r.client != nil {
+    return r.client.Subscribe(ctx, channels...)
+  }
+  return r.cluster.Subscribe(ctx, channels...)
+}
+
+func (r *UnifiedRedis) PSubscribe(ctx context.Context, patterns ...string) *redis.PubSub {
+  if r.client != nil {
+    return r.client.PSubscribe(ctx, patterns...)
+  }
+  return r.cluster.PSubscribe(ctx, patterns...)
+}
+
+// Node is a client of the node at addr alone, the single node client
+// itself when not a cluster
+func (r *UnifiedRedis) Node(addr string) *redis.Client {
+  if r.client != nil && addr == r.Addr() {
+    return r.client
+  }
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  if r.nodes == nil {
+    r.nodes = make(map[string]*redis.Client)
+  }
+  if n, ok := r.nodes[addr]; ok {
+    return n
+  }
+  n := redis.NewClient(&redis.Options{
+    Addr: addr,
+    Password: "",
+    DialTimeout: time.Second,
+    ReadTimeout: time.Second,
+    WriteTimeout: time.Second,
+  })
+  r.nodes[addr] = n
+  return n
+}
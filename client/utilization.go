@@ -0,0 +1,148 @@
+package client
+
+import (
+  "math"
+  "sync"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+)
+
+// UtilizedStages are the stages whose slots are followed, in pipeline order
+var UtilizedStages = []string { "InputFetchStage", "ExecuteActionStage", "ReportResultStage" }
+
+// an app remembers an hour of utilization, ten seconds to a step
+const utilizationStep = 10 * time.Second
+const utilizationSize = 360
+
+// stageHistory holds the slots used and configured in a stage, one average
+// per step, oldest first
+type stageHistory struct {
+  used []float64
+  configured []float64
+  pending [2]accumulator
+}
+
+type workerHistory struct {
+  bucket int64
+  stages map[string]*stageHistory
+}
+
+// Utilization remembers the slots each worker has used in the stages that
+// hold operations, over the last size steps. Steps in which a worker was
+// not profiled are NaN.
+type Utilization struct {
+  step time.Duration
+  size int
+  workers map[string]*workerHistory
+  mutex sync.Mutex
+}
+
+func NewUtilization(step time.Duration, size int) *Utilization {
+  return &Utilization {
+    step: step,
+    size: size,
+    workers: make(map[string]*workerHistory),
+  }
+}
+
+func (s *stageHistory) push(v float64, c float64, size int) {
+  s.used = append(s.used, v)
+  s.configured = append(s.configured, c)
+  if n := len(s.used); n > size {
+    s.used = s.used[n - size:]
+    s.configured = s.configured[n - size:]
+  }
+}
+
+// flush closes the worker's step, leaving a gap for any it missed up to
+// bucket
+func (w *workerHistory) flush(bucket int64, step int64, size int) {
+  missed := min(int((bucket - w.bucket) / step) - 1, size)
+  for _, s := range w.stages {
+    if s.pending[0].n > 0 {
+      s.push(s.pending[0].sum / float64(s.pending[0].n), s.pending[1].sum / float64(s.pending[1].n), size)
+    } else {
+      s.push(math.NaN(), math.NaN(), size)
+    }
+    s.pending = [2]accumulator{}
+    for i := 0; i < missed; i++ {
+      s.push(math.NaN(), math.NaN(), size)
+    }
+  }
+}
+
+// Record adds the slots of profile, observed at t, to the average of its step
+func (u *Utilization) Record(worker string, t time.Time, profile *bfpb.WorkerProfileMessage) {
+  u.mutex.Lock()
+  defer u.mutex.Unlock()
+  bucket := t.Truncate(u.step).Unix()
+  w := u.workers[worker]
+  if w == nil {
+    w = &workerHistory { bucket: bucket, stages: make(map[string]*stageHistory) }
+    for _, stage := range UtilizedStages {
+      w.stages[stage] = &stageHistory{}
+    }
+    u.workers[worker] = w
+  }
+  if bucket > w.bucket {
+    w.flush(bucket, int64(u.step / time.Second), u.size)
+    w.bucket = bucket
+  }
+  for _, stage := range profile.Stages {
+    if s := w.stages[stage.Name]; s != nil {
+      s.pending[0].sum += float64(stage.SlotsUsed)
+      s.pending[0].n++
+      s.pending[1].sum += float64(stage.SlotsConfigured)
+      s.pending[1].n++
+    }
+  }
+}
+
+// Used is the history of slots the worker used in stage, and of those
+// configured, oldest first, without the step in progress
+func (u *Utilization) Used(worker string, stage string) ([]float64, []float64) {
+  u.mutex.Lock()
+  defer u.mutex.Unlock()
+  w := u.workers[worker]
+  if w == nil || w.stages[stage] == nil {
+    return nil, nil
+  }
+  s := w.stages[stage]
+  return append([]float64(nil), s.used...), append([]float64(nil), s.configured...)
+}
+
+// Ratios is the history of the fraction of its configured slots the worker
+// used in stage, NaN where it was not profiled or had no slots
+func (u *Utilization) Ratios(worker string, stage string) []float64 {
+  used, configured := u.Used(worker, stage)
+  ratios := make([]float64, len(used))
+  for i := range used {
+    if configured[i] > 0 {
+      ratios[i] = used[i] / configured[i]
+    } else {
+      ratios[i] = math.NaN()
+    }
+  }
+  return ratios
+}
+
+// Mean averages the values that are not NaN, reporting false if there are
+// none
+func Mean(values []float64) (float64, bool) {
+  sum, n := 0.0, 0
+  for _, v := range values {
+    if !math.IsNaN(v) {
+      sum += v
+      n++
+    }
+  }
+  if n == 0 {
+    return 0, false
+  }
+  return sum / float64(n), true
+}
+
+// Step is the time each value of the history averages over
+func (u *Utilization) Step() time.Duration {
+  return u.step
+}
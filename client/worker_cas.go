@@ -0,0 +1,139 @@
+package client
+
+import (
+  "context"
+  "sync"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "google.golang.org/grpc"
+)
+
+// CasUsage is a worker's CAS as of a profile. Evictions are those made
+// since the profile before, the worker resetting its count on each.
+type CasUsage struct {
+  At time.Time
+  Size int64
+  MaxSize int64
+  MaxEntrySize int64
+  Entries int64
+  Unreferenced int64
+  Directories int64
+  Evicted int64
+  EvictedSize int64
+}
+
+func NewCasUsage(at time.Time, profile *bfpb.WorkerProfileMessage) *CasUsage {
+  return &CasUsage {
+    At: at,
+    Size: profile.CasSize,
+    MaxSize: profile.CasMaxSize,
+    MaxEntrySize: profile.CasMaxEntrySize,
+    Entries: profile.CasEntryCount,
+    Unreferenced: profile.CasUnreferencedEntryCount,
+    Directories: profile.CasDirectoryEntryCount,
+    Evicted: int64(profile.CasEvictedEntryCount),
+    EvictedSize: profile.CasEvictedEntrySize,
+  }
+}
+
+// Fill is the fraction of the CAS capacity in use
+func (c *CasUsage) Fill() float64 {
+  if c.MaxSize <= 0 {
+    return 0
+  }
+  return float64(c.Size) / float64(c.MaxSize)
+}
+
+// UnreferencedFill is the fraction of entries no operation refers to, the
+// first to be evicted
+func (c *CasUsage) UnreferencedFill() float64 {
+  if c.Entries <= 0 {
+    return 0
+  }
+  return float64(c.Unreferenced) / float64(c.Entries)
+}
+
+// CasTrend follows the CAS of a worker between its last two profiles
+type CasTrend struct {
+  Worker string
+  Last *CasUsage
+  Previous *CasUsage
+  // Polls counts the profiles recorded
+  Polls int
+  Err error
+}
+
+func NewCasTrend(worker string) *CasTrend {
+  return &CasTrend { Worker: worker }
+}
+
+func (t *CasTrend) Record(at time.Time, profile *bfpb.WorkerProfileMessage) {
+  t.Previous, t.Last = t.Last, NewCasUsage(at, profile)
+  t.Polls++
+  t.Err = nil
+}
+
+// interval is the seconds between the last two profiles, zero until there
+// are two
+func (t *CasTrend) interval() float64 {
+  if t.Last == nil || t.Previous == nil {
+    return 0
+  }
+  return t.Last.At.Sub(t.Previous.At).Seconds()
+}
+
+// Growth is the bytes per second the CAS grew by between the last two
+// profiles, and EntryGrowth the entries per second
+func (t *CasTrend) Growth() float64 {
+  if s := t.interval(); s > 0 {
+    return float64(t.Last.Size - t.Previous.Size) / s
+  }
+  return 0
+}
+
+func (t *CasTrend) EntryGrowth() float64 {
+  if s := t.interval(); s > 0 {
+    return float64(t.Last.Entries - t.Previous.Entries) / s
+  }
+  return 0
+}
+
+// Evictions is the entries per second evicted before the last profile, and
+// EvictedBytes their bytes per second
+func (t *CasTrend) Evictions() float64 {
+  if s := t.interval(); s > 0 {
+    return float64(t.Last.Evicted) / s
+  }
+  return 0
+}
+
+func (t *CasTrend) EvictedBytes() float64 {
+  if s := t.interval(); s > 0 {
+    return float64(t.Last.EvictedSize) / s
+  }
+  return 0
+}
+
+// ProfileCas records the CAS of every worker, all at once
+func ProfileCas(a *App, trends []*CasTrend, timeout time.Duration) {
+  conns := make([]*grpc.ClientConn, len(trends))
+  for i, t := range trends {
+    conns[i] = a.GetWorkerConn(t.Worker, a.CA)
+  }
+  var wg sync.WaitGroup
+  for i, t := range trends {
+    wg.Add(1)
+    go func(t *CasTrend, conn *grpc.ClientConn) {
+      defer wg.Done()
+      ctx, cancel := context.WithTimeout(context.Background(), timeout)
+      defer cancel()
+      profile, err := WorkerProfile(ctx, conn)
+      if err != nil {
+        t.Err = err
+        return
+      }
+      t.Record(a.Now(), profile)
+    }(t, conns[i])
+  }
+  wg.Wait()
+}
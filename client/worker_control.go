@@ -0,0 +1,97 @@
+package client
+
+import (
+  "context"
+  "fmt"
+  "sync"
+  "time"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "google.golang.org/grpc"
+)
+
+// a worker is given this long to apply a change
+const pipelineTimeout = 5 * time.Second
+
+// Stages are the stages of a worker's pipeline, in order
+var Stages = []string { "MatchStage", "InputFetchStage", "ExecuteActionStage", "ReportResultStage" }
+
+// PipelineAction pauses or resumes a stage of a worker's pipeline or, with
+// a Width, resizes the stage and leaves it paused or not as it was
+type PipelineAction struct {
+  Stage string
+  Paused bool
+  Width int32
+}
+
+func (p PipelineAction) String() string {
+  switch {
+  case p.Width > 0:
+    return fmt.Sprintf("resize %s to %d", p.Stage, p.Width)
+  case p.Paused:
+    return "pause " + p.Stage
+  }
+  return "resume " + p.Stage
+}
+
+// ChangePipeline applies the action to the worker at conn, failing unless
+// the worker reports the change in effect
+func ChangePipeline(ctx context.Context, conn *grpc.ClientConn, action PipelineAction) error {
+  c := bfpb.NewWorkerControlClient(conn)
+  paused := action.Paused
+  if action.Width > 0 {
+    // an empty change reports the pipeline as it is
+    r, err := c.PipelineChange(ctx, &bfpb.WorkerPipelineChangeRequest {})
+    if err != nil {
+      return err
+    }
+    for _, change := range r.Changes {
+      if change.Stage == action.Stage {
+        paused = change.Paused
+      }
+    }
+  }
+  r, err := c.PipelineChange(ctx, &bfpb.WorkerPipelineChangeRequest {
+    Changes: []*bfpb.PipelineChange {
+      &bfpb.PipelineChange {
+        Stage: action.Stage,
+        Paused: paused,
+        Width: action.Width,
+      },
+    },
+  })
+  if err != nil {
+    return err
+  }
+  for _, change := range r.Changes {
+    if change.Stage != action.Stage {
+      continue
+    }
+    if change.Paused != paused || action.Width > 0 && change.Width > 0 && change.Width != action.Width {
+      return fmt.Errorf("%s not effective", action)
+    }
+  }
+  return nil
+}
+
+// ChangePipelines applies the action to every worker at once, returning
+// the error of each, nil where it succeeded
+func ChangePipelines(a *App, workers []string, action PipelineAction) []error {
+  // connections are made in turn, requests at once
+  conns := make([]*grpc.ClientConn, len(workers))
+  for i, worker := range workers {
+    conns[i] = a.GetWorkerConn(worker, a.CA)
+  }
+  errs := make([]error, len(workers))
+  var wg sync.WaitGroup
+  for i := range workers {
+    wg.Add(1)
+    go func(i int) {
+      defer wg.Done()
+      ctx, cancel := context.WithTimeout(context.Background(), pipelineTimeout)
+      defer cancel()
+      errs[i] = ChangePipeline(ctx, conns[i], action)
+    }(i)
+  }
+  wg.Wait()
+  return errs
+}
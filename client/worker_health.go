@@ -0,0 +1,153 @@
+package client
+
+import (
+  "context"
+  "errors"
+  "net"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+  "google.golang.org/grpc"
+  "google.golang.org/grpc/codes"
+  "google.golang.org/grpc/status"
+)
+
+// categories of the errors profiling a worker
+const (
+  HealthDNS = "dns"
+  HealthTLS = "tls"
+  HealthDeadline = "deadline"
+  HealthUnavailable = "unavailable"
+)
+
+// WorkerHealth follows whether a worker answers for its profile: how many
+// times in a row it has not, since when, and why at the last
+type WorkerHealth struct {
+  Worker string
+  Failures int
+  FirstFailed time.Time
+  LastFailed time.Time
+  LastSuccess time.Time
+  Category string
+  Err error
+}
+
+// Down is how long the worker has failed to answer, zero while it answers
+func (w *WorkerHealth) Down(now time.Time) time.Duration {
+  if w.Failures == 0 {
+    return 0
+  }
+  return now.Sub(w.FirstFailed)
+}
+
+// Health follows every worker profiled
+type Health struct {
+  workers map[string]*WorkerHealth
+  mutex sync.Mutex
+}
+
+func NewHealth() *Health {
+  return &Health { workers: make(map[string]*WorkerHealth) }
+}
+
+// Categorize names the cause of a failure to reach a worker, the lowercase
+// status code when it is none of the usual
+func Categorize(err error) string {
+  if err == nil {
+    return ""
+  }
+  msg := strings.ToLower(err.Error())
+  var dnsErr *net.DNSError
+  // dial failures arrive as unavailable, their cause only in the message
+  switch {
+  case errors.As(err, &dnsErr) || strings.Contains(msg, "no such host") || strings.Contains(msg, "dial tcp: lookup"):
+    return HealthDNS
+  case strings.Contains(msg, "tls:") || strings.Contains(msg, "x509:") || strings.Contains(msg, "handshake failed"):
+    return HealthTLS
+  case errors.Is(err, context.DeadlineExceeded):
+    return HealthDeadline
+  }
+  switch code := status.Code(err); code {
+  case codes.DeadlineExceeded:
+    return HealthDeadline
+  case codes.Unavailable:
+    return HealthUnavailable
+  default:
+    return strings.ToLower(code.String())
+  }
+}
+
+// Record notes the outcome of profiling the worker at t, err nil if it
+// answered
+func (h *Health) Record(worker string, t time.Time, err error) {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+  w := h.workers[worker]
+  if w == nil {
+    w = &WorkerHealth { Worker: worker }
+    h.workers[worker] = w
+  }
+  if err == nil {
+    w.Failures = 0
+    w.LastSuccess = t
+    w.Category, w.Err = "", nil
+    return
+  }
+  if w.Failures == 0 {
+    w.FirstFailed = t
+  }
+  w.Failures++
+  w.LastFailed = t
+  w.Category, w.Err = Categorize(err), err
+}
+
+// Worker is a copy of the health of the worker, nil if it was never profiled
+func (h *Health) Worker(worker string) *WorkerHealth {
+  h.mutex.Lock()
+  defer h.mutex.Unlock()
+  if w := h.workers[worker]; w != nil {
+    c := *w
+    return &c
+  }
+  return nil
+}
+
+// Unreachable are copies of the healths of the active workers that failed
+// to answer when last profiled, those down longest first
+func (h *Health) Unreachable(active []string) []*WorkerHealth {
+  var unreachable []*WorkerHealth
+  for _, worker := range active {
+    if w := h.Worker(worker); w != nil && w.Failures > 0 {
+      unreachable = append(unreachable, w)
+    }
+  }
+  sort.Slice(unreachable, func(i, j int) bool {
+    if !unreachable[i].FirstFailed.Equal(unreachable[j].FirstFailed) {
+      return unreachable[i].FirstFailed.Before(unreachable[j].FirstFailed)
+    }
+    return unreachable[i].Worker < unreachable[j].Worker
+  })
+  return unreachable
+}
+
+// ProbeWorkers profiles every worker at once, each given the timeout to
+// answer, recording their health
+func ProbeWorkers(a *App, workers []string, timeout time.Duration) {
+  conns := make([]*grpc.ClientConn, len(workers))
+  for i, worker := range workers {
+    conns[i] = a.GetWorkerConn(worker, a.CA)
+  }
+  var wg sync.WaitGroup
+  for i, worker := range workers {
+    wg.Add(1)
+    go func(worker string, conn *grpc.ClientConn) {
+      defer wg.Done()
+      ctx, cancel := context.WithTimeout(context.Background(), timeout)
+      defer cancel()
+      _, err := WorkerProfile(ctx, conn)
+      a.Health.Record(worker, a.Now(), err)
+    }(worker, conns[i])
+  }
+  wg.Wait()
+}
@@ -0,0 +1,89 @@
+package fake
+
+import (
+  "fmt"
+  "math"
+  "math/rand"
+  "time"
+  reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+)
+
+var demoMnemonics = []string{"GoCompile", "GoLink", "CppCompile", "CppLink", "Javac", "TestRunner"}
+
+// Demo is a fake cluster under a load that rises and falls over a few
+// minutes, for trying out the client without a buildfarm
+type Demo struct {
+  Redis *Redis
+  Server *Server
+  actions []bfpb.Digest
+  done chan struct{}
+}
+
+func StartDemo(workers int) (*Demo, error) {
+  r := NewRedis()
+  if err := r.Start("127.0.0.1:0"); err != nil {
+    return nil, err
+  }
+  s := NewServer(r)
+  if err := s.Start("127.0.0.1:0"); err != nil {
+    r.Close()
+    return nil, err
+  }
+  d := &Demo { Redis: r, Server: s, done: make(chan struct{}) }
+  for i := 0; i < workers; i++ {
+    if _, err := s.AddWorker(4); err != nil {
+      d.Stop()
+      return nil, err
+    }
+  }
+  for _, mnemonic := range demoMnemonics {
+    action, err := s.PutAction([]string{"/bin/" + mnemonic}, map[string]string {
+      "BUILD": fmt.Sprintf("demo(name = \"%s\")\n", mnemonic),
+    })
+    if err != nil {
+      d.Stop()
+      return nil, err
+    }
+    d.actions = append(d.actions, action)
+  }
+  go d.run(workers)
+  return d, nil
+}
+
+// run submits a varying number of actions and steps the server every second
+func (d *Demo) run(workers int) {
+  ticker := time.NewTicker(time.Second)
+  defer ticker.Stop()
+  start := time.Now()
+  invocation := 0
+  for {
+    select {
+    case <-d.done:
+      return
+    case <-ticker.C:
+    }
+    // the capacity of the workers, give or take
+    phase := time.Since(start).Seconds() / 180 * 2 * math.Pi
+    load := float64(workers) * (1.5 + 1.5 * math.Sin(phase))
+    if rand.Intn(20) == 0 {
+      invocation++
+    }
+    for n := rand.Intn(int(load) + 1); n > 0; n-- {
+      i := rand.Intn(len(d.actions))
+      d.Server.Submit(d.actions[i], &reapi.RequestMetadata {
+        ToolInvocationId: fmt.Sprintf("demo-invocation-%d", invocation),
+        CorrelatedInvocationsId: "demo",
+        ActionMnemonic: demoMnemonics[i],
+        TargetId: fmt.Sprintf("//demo:%s_%d", demoMnemonics[i], rand.Intn(50)),
+      })
+    }
+    d.Server.Step()
+  }
+}
+
+func (d *Demo) Stop() {
+  close(d.done)
+  d.Server.Stop()
+  d.Redis.Close()
+}
@@ -0,0 +1,51 @@
+package faketest
+
+import (
+  "fmt"
+  "testing"
+  reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake"
+)
+
+// Start serves a fake buildfarm and returns it with an app connected to
+// it, both stopped when the test ends
+func Start(t testing.TB) (*fake.Server, *client.App) {
+  r := fake.NewRedis()
+  if err := r.Start("127.0.0.1:0"); err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(func() { r.Close() })
+  s := fake.NewServer(r)
+  if err := s.Start("127.0.0.1:0"); err != nil {
+    t.Fatal(err)
+  }
+  t.Cleanup(s.Stop)
+  a := client.NewApp(r.Addr(), s.Addr(), "")
+  a.Connect()
+  t.Cleanup(func() { a.Conn.Close() })
+  return s, a
+}
+
+// Submit prequeues an execution of target in invocation, returning its name
+func Submit(t testing.TB, s *fake.Server, invocation string, target string) string {
+  action, err := s.PutAction([]string{"/bin/true"}, nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+  return s.Submit(action, &reapi.RequestMetadata {
+    ToolInvocationId: invocation,
+    ActionMnemonic: "Test",
+    TargetId: target,
+  })
+}
+
+// SubmitN prequeues n executions in one invocation, returning their names in
+// submission order
+func SubmitN(t testing.TB, s *fake.Server, n int) []string {
+  var names []string
+  for i := 0; i < n; i++ {
+    names = append(names, Submit(t, s, "test", fmt.Sprintf("//test:%d", i)))
+  }
+  return names
+}
@@ -0,0 +1,969 @@
+package fake
+
+import (
+  "bufio"
+  "errors"
+  "fmt"
+  "io"
+  "net"
+  "regexp"
+  "sort"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+)
+
+type zmember struct {
+  member string
+  score float64
+}
+
+// Redis is a stand-in for a single, non-cluster redis node, speaking enough
+// of RESP2 for the commands the client issues. Its lists, hashes and sorted
+// sets are scripted directly with its exported methods, as is publishing.
+type Redis struct {
+  mutex sync.Mutex
+  lists map[string][]string
+  hashes map[string]map[string]string
+  zsets map[string]map[string]float64
+  listener net.Listener
+  conns map[net.Conn]*conn
+  // commands counted for INFO, with the rate over the last full second
+  commands int
+  second time.Time
+  secondCommands int
+  opsPerSec int
+}
+
+// conn is a client connection, written to by its commands and, once
+// subscribed, by publishers
+type conn struct {
+  mutex sync.Mutex
+  out *bufio.Writer
+  channels map[string]bool
+  patterns map[string]bool
+}
+
+func (c *conn) send(replies ...interface{}) error {
+  c.mutex.Lock()
+  defer c.mutex.Unlock()
+  for _, reply := range replies {
+    writeReply(c.out, reply)
+  }
+  return c.out.Flush()
+}
+
+func (c *conn) subscriptions() int {
+  return len(c.channels) + len(c.patterns)
+}
+
+func NewRedis() *Redis {
+  return &Redis {
+    lists: make(map[string][]string),
+    hashes: make(map[string]map[string]string),
+    zsets: make(map[string]map[string]float64),
+    conns: make(map[net.Conn]*conn),
+  }
+}
+
+// Start serves on addr, an empty port picks a free one
+func (r *Redis) Start(addr string) error {
+  l, err := net.Listen("tcp", addr)
+  if err != nil {
+    return err
+  }
+  r.listener = l
+  go r.serve()
+  return nil
+}
+
+func (r *Redis) Addr() string {
+  return r.listener.Addr().String()
+}
+
+func (r *Redis) Close() error {
+  err := r.listener.Close()
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  for c := range r.conns {
+    c.Close()
+  }
+  return err
+}
+
+func (r *Redis) serve() {
+  for {
+    c, err := r.listener.Accept()
+    if err != nil {
+      return
+    }
+    r.mutex.Lock()
+    r.conns[c] = &conn {
+      out: bufio.NewWriter(c),
+      channels: make(map[string]bool),
+      patterns: make(map[string]bool),
+    }
+    r.mutex.Unlock()
+    go r.handle(c)
+  }
+}
+
+func (r *Redis) handle(c net.Conn) {
+  defer func() {
+    r.mutex.Lock()
+    delete(r.conns, c)
+    r.mutex.Unlock()
+    c.Close()
+  }()
+  in := bufio.NewReader(c)
+  r.mutex.Lock()
+  cn := r.conns[c]
+  r.mutex.Unlock()
+  for {
+    args, err := readCommand(in)
+    if err != nil {
+      return
+    }
+    if len(args) == 0 {
+      continue
+    }
+    command := strings.ToUpper(args[0])
+    r.mutex.Lock()
+    var replies []interface{}
+    if cn.subscriptions() > 0 || strings.HasSuffix(command, "SUBSCRIBE") {
+      replies = r.pubsub(cn, command, args[1:])
+    } else {
+      replies = []interface{} { r.execute(command, args[1:]) }
+    }
+    r.mutex.Unlock()
+    cn.mutex.Lock()
+    for _, reply := range replies {
+      writeReply(cn.out, reply)
+    }
+    // pipelined commands are answered together
+    if in.Buffered() == 0 {
+      err = cn.out.Flush()
+    }
+    cn.mutex.Unlock()
+    if err != nil {
+      return
+    }
+  }
+}
+
+// pubsub answers the commands of a connection in pub/sub mode, one reply
+// for each channel or pattern
+func (r *Redis) pubsub(c *conn, command string, args []string) []interface{} {
+  var replies []interface{}
+  subscribe := func(kind string, set map[string]bool, names []string, add bool) {
+    if !add && len(names) == 0 {
+      for name := range set {
+        names = append(names, name)
+      }
+      sort.Strings(names)
+      if len(names) == 0 {
+        replies = append(replies, []interface{} { kind, nil, c.subscriptions() })
+      }
+    }
+    for _, name := range names {
+      if add {
+        set[name] = true
+      } else {
+        delete(set, name)
+      }
+      replies = append(replies, []interface{} { kind, name, c.subscriptions() })
+    }
+  }
+  switch command {
+  case "SUBSCRIBE", "PSUBSCRIBE":
+    if len(args) == 0 {
+      return []interface{} { errArgs(command) }
+    }
+    if command == "SUBSCRIBE" {
+      subscribe("subscribe", c.channels, args, true)
+    } else {
+      subscribe("psubscribe", c.patterns, args, true)
+    }
+  case "UNSUBSCRIBE":
+    subscribe("unsubscribe", c.channels, args, false)
+  case "PUNSUBSCRIBE":
+    subscribe("punsubscribe", c.patterns, args, false)
+  case "PING":
+    message := ""
+    if len(args) > 0 {
+      message = args[0]
+    }
+    replies = append(replies, []interface{} { "pong", message })
+  default:
+    replies = append(replies, fmt.Errorf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context", strings.ToLower(command)))
+  }
+  return replies
+}
+
+// Publish sends message to the connections subscribed to channel, directly
+// or by pattern, returning how many received it
+func (r *Redis) Publish(channel string, message string) int {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  return r.publish(channel, message)
+}
+
+// publish writes in order, with the store locked
+func (r *Redis) publish(channel string, message string) int {
+  n := 0
+  for _, c := range r.conns {
+    if c.channels[channel] {
+      c.send([]interface{} { "message", channel, message })
+      n++
+    }
+    for pattern := range c.patterns {
+      if glob(pattern, channel) {
+        c.send([]interface{} { "pmessage", pattern, channel, message })
+        n++
+      }
+    }
+  }
+  return n
+}
+
+func readLine(in *bufio.Reader) (string, error) {
+  line, err := in.ReadString('\n')
+  if err != nil {
+    return "", err
+  }
+  return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readCommand reads an array of bulk strings, or an inline command
+func readCommand(in *bufio.Reader) ([]string, error) {
+  line, err := readLine(in)
+  if err != nil {
+    return nil, err
+  }
+  if !strings.HasPrefix(line, "*") {
+    return strings.Fields(line), nil
+  }
+  n, err := strconv.Atoi(line[1:])
+  if err != nil {
+    return nil, err
+  }
+  args := make([]string, n)
+  for i := range args {
+    line, err := readLine(in)
+    if err != nil {
+      return nil, err
+    }
+    if !strings.HasPrefix(line, "$") {
+      return nil, errors.New("expected a bulk string")
+    }
+    size, err := strconv.Atoi(line[1:])
+    if err != nil {
+      return nil, err
+    }
+    b := make([]byte, size + 2)
+    if _, err := io.ReadFull(in, b); err != nil {
+      return nil, err
+    }
+    args[i] = string(b[:size])
+  }
+  return args, nil
+}
+
+// replies are written by their go type
+type simpleString string
+
+func writeReply(out *bufio.Writer, reply interface{}) {
+  switch v := reply.(type) {
+  case nil:
+    out.WriteString("$-1\r\n")
+  case simpleString:
+    fmt.Fprintf(out, "+%s\r\n", v)
+  case error:
+    fmt.Fprintf(out, "-%s\r\n", v)
+  case int:
+    fmt.Fprintf(out, ":%d\r\n", v)
+  case string:
+    fmt.Fprintf(out, "$%d\r\n%s\r\n", len(v), v)
+  case []string:
+    fmt.Fprintf(out, "*%d\r\n", len(v))
+    for _, s := range v {
+      writeReply(out, s)
+    }
+  case []interface{}:
+    fmt.Fprintf(out, "*%d\r\n", len(v))
+    for _, e := range v {
+      writeReply(out, e)
+    }
+  }
+}
+
+var (
+  errWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+  errSyntax = errors.New("ERR syntax error")
+  errNotInteger = errors.New("ERR value is not an integer or out of range")
+)
+
+func errArgs(command string) error {
+  return fmt.Errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(command))
+}
+
+// bounds resolves redis start and stop indices, negative from the end
+func bounds(n int, start int, stop int) (int, int) {
+  if start < 0 {
+    start += n
+  }
+  if stop < 0 {
+    stop += n
+  }
+  if start < 0 {
+    start = 0
+  }
+  if stop >= n {
+    stop = n - 1
+  }
+  return start, stop + 1
+}
+
+func (r *Redis) keyType(key string) string {
+  if _, ok := r.lists[key]; ok {
+    return "list"
+  }
+  if _, ok := r.hashes[key]; ok {
+    return "hash"
+  }
+  if _, ok := r.zsets[key]; ok {
+    return "zset"
+  }
+  return "none"
+}
+
+// holds reports whether key is absent or of type t
+func (r *Redis) holds(key string, t string) bool {
+  kt := r.keyType(key)
+  return kt == "none" || kt == t
+}
+
+func (r *Redis) sortedSet(key string) []zmember {
+  var members []zmember
+  for m, s := range r.zsets[key] {
+    members = append(members, zmember { member: m, score: s })
+  }
+  sort.Slice(members, func(i, j int) bool {
+    if members[i].score != members[j].score {
+      return members[i].score < members[j].score
+    }
+    return members[i].member < members[j].member
+  })
+  return members
+}
+
+func (r *Redis) execute(command string, args []string) interface{} {
+  arity := map[string]int {
+    "LLEN": 1, "LRANGE": 3, "HLEN": 1, "HKEYS": 1, "HGET": 2, "HGETALL": 1,
+    "ZCARD": 1, "TYPE": 1, "TTL": 1, "LREM": 3, "ZRANK": 2, "ZCOUNT": 3,
+  }
+  if n, ok := arity[command]; ok && len(args) != n {
+    return errArgs(command)
+  }
+  r.count()
+  switch command {
+  case "PING":
+    return simpleString("PONG")
+  case "INFO":
+    return r.info()
+  case "CLIENT", "SELECT":
+    return simpleString("OK")
+  case "CLUSTER":
+    return errors.New("ERR This instance has cluster support disabled")
+  case "TYPE":
+    return simpleString(r.keyType(args[0]))
+  case "TTL":
+    // keys never expire here
+    if r.keyType(args[0]) == "none" {
+      return -2
+    }
+    return -1
+  case "PUBLISH":
+    if len(args) != 2 {
+      return errArgs(command)
+    }
+    return r.publish(args[0], args[1])
+  case "KEYS", "SCAN":
+    return r.scan(command, args)
+  case "LLEN":
+    if !r.holds(args[0], "list") {
+      return errWrongType
+    }
+    return len(r.lists[args[0]])
+  case "LRANGE":
+    if !r.holds(args[0], "list") {
+      return errWrongType
+    }
+    start, err1 := strconv.Atoi(args[1])
+    stop, err2 := strconv.Atoi(args[2])
+    if err1 != nil || err2 != nil {
+      return errNotInteger
+    }
+    l := r.lists[args[0]]
+    start, end := bounds(len(l), start, stop)
+    if start >= end {
+      return []string{}
+    }
+    return append([]string{}, l[start:end]...)
+  case "LPUSH":
+    if len(args) < 2 {
+      return errArgs(command)
+    }
+    if !r.holds(args[0], "list") {
+      return errWrongType
+    }
+    for _, v := range args[1:] {
+      r.lists[args[0]] = append([]string { v }, r.lists[args[0]]...)
+    }
+    return len(r.lists[args[0]])
+  case "LPOS":
+    return r.lpos(args)
+  case "LREM":
+    if !r.holds(args[0], "list") {
+      return errWrongType
+    }
+    count, err := strconv.Atoi(args[1])
+    if err != nil {
+      return errNotInteger
+    }
+    return r.lrem(args[0], count, args[2])
+  case "HLEN":
+    if !r.holds(args[0], "hash") {
+      return errWrongType
+    }
+    return len(r.hashes[args[0]])
+  case "HKEYS":
+    if !r.holds(args[0], "hash") {
+      return errWrongType
+    }
+    keys := []string{}
+    for k := range r.hashes[args[0]] {
+      keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+  case "HGET":
+    if !r.holds(args[0], "hash") {
+      return errWrongType
+    }
+    if v, ok := r.hashes[args[0]][args[1]]; ok {
+      return v
+    }
+    return nil
+  case "HGETALL":
+    if !r.holds(args[0], "hash") {
+      return errWrongType
+    }
+    return r.hashFields(args[0], "*")
+  case "HSCAN":
+    return r.hscan(args)
+  case "HDEL":
+    if len(args) < 2 {
+      return errArgs(command)
+    }
+    if !r.holds(args[0], "hash") {
+      return errWrongType
+    }
+    n := 0
+    for _, field := range args[1:] {
+      if r.hdel(args[0], field) {
+        n++
+      }
+    }
+    return n
+  case "ZCARD":
+    if !r.holds(args[0], "zset") {
+      return errWrongType
+    }
+    return len(r.zsets[args[0]])
+  case "ZADD":
+    return r.zadd(args)
+  case "ZREM":
+    if len(args) < 2 {
+      return errArgs(command)
+    }
+    if !r.holds(args[0], "zset") {
+      return errWrongType
+    }
+    n := 0
+    for _, member := range args[1:] {
+      if _, ok := r.zsets[args[0]][member]; ok {
+        delete(r.zsets[args[0]], member)
+        n++
+      }
+    }
+    if len(r.zsets[args[0]]) == 0 {
+      delete(r.zsets, args[0])
+    }
+    return n
+  case "ZRANK":
+    if !r.holds(args[0], "zset") {
+      return errWrongType
+    }
+    for i, m := range r.sortedSet(args[0]) {
+      if m.member == args[1] {
+        return i
+      }
+    }
+    return nil
+  case "ZCOUNT":
+    if !r.holds(args[0], "zset") {
+      return errWrongType
+    }
+    min, err1 := parseBound(args[1])
+    max, err2 := parseBound(args[2])
+    if err1 != nil || err2 != nil {
+      return errors.New("ERR min or max is not a float")
+    }
+    n := 0
+    for _, score := range r.zsets[args[0]] {
+      if min.below(score) && max.above(score) {
+        n++
+      }
+    }
+    return n
+  case "ZRANGE":
+    withScores := len(args) == 4 && strings.ToUpper(args[3]) == "WITHSCORES"
+    if len(args) != 3 && !withScores {
+      return errArgs(command)
+    }
+    if !r.holds(args[0], "zset") {
+      return errWrongType
+    }
+    start, err1 := strconv.Atoi(args[1])
+    stop, err2 := strconv.Atoi(args[2])
+    if err1 != nil || err2 != nil {
+      return errNotInteger
+    }
+    members := r.sortedSet(args[0])
+    start, end := bounds(len(members), start, stop)
+    result := []string{}
+    for i := start; i < end; i++ {
+      result = append(result, members[i].member)
+      if withScores {
+        result = append(result, strconv.FormatFloat(members[i].score, 'g', -1, 64))
+      }
+    }
+    return result
+  }
+  return fmt.Errorf("ERR unknown command '%s'", strings.ToLower(command))
+}
+
+// bound is a score limit of ZCOUNT, exclusive when written with a (
+type bound struct {
+  score float64
+  exclusive bool
+}
+
+func parseBound(s string) (bound, error) {
+  b := bound { exclusive: strings.HasPrefix(s, "(") }
+  var err error
+  b.score, err = strconv.ParseFloat(strings.TrimPrefix(s, "("), 64)
+  return b, err
+}
+
+func (b bound) below(score float64) bool {
+  return b.score < score || !b.exclusive && b.score == score
+}
+
+func (b bound) above(score float64) bool {
+  return b.score > score || !b.exclusive && b.score == score
+}
+
+// lpos finds the index of value in the list at key, counting matches from
+// the tail for a negative rank
+func (r *Redis) lpos(args []string) interface{} {
+  if len(args) < 2 {
+    return errArgs("lpos")
+  }
+  if !r.holds(args[0], "list") {
+    return errWrongType
+  }
+  rank := 1
+  for i := 2; i < len(args); i += 2 {
+    if i + 1 == len(args) {
+      return errSyntax
+    }
+    n, err := strconv.Atoi(args[i + 1])
+    if err != nil {
+      return errNotInteger
+    }
+    switch strings.ToUpper(args[i]) {
+    case "RANK":
+      if n == 0 {
+        return errors.New("ERR RANK can't be zero")
+      }
+      rank = n
+    case "MAXLEN":
+    default:
+      return errSyntax
+    }
+  }
+  l := r.lists[args[0]]
+  if rank > 0 {
+    for i, v := range l {
+      if v == args[1] {
+        if rank--; rank == 0 {
+          return i
+        }
+      }
+    }
+  } else {
+    for i := len(l) - 1; i >= 0; i-- {
+      if l[i] == args[1] {
+        if rank++; rank == 0 {
+          return i
+        }
+      }
+    }
+  }
+  return nil
+}
+
+func (r *Redis) count() {
+  r.commands++
+  now := time.Now()
+  if now.Sub(r.second) >= time.Second {
+    r.opsPerSec = r.commands - r.secondCommands
+    r.second, r.secondCommands = now, r.commands
+  }
+}
+
+// info reports the fields of INFO the client shows, the memory used
+// approximated by the size of the values held
+func (r *Redis) info() string {
+  memory := 0
+  for k, l := range r.lists {
+    memory += len(k)
+    for _, v := range l {
+      memory += len(v)
+    }
+  }
+  for k, h := range r.hashes {
+    memory += len(k)
+    for f, v := range h {
+      memory += len(f) + len(v)
+    }
+  }
+  for k, z := range r.zsets {
+    memory += len(k)
+    for m := range z {
+      memory += len(m) + 8
+    }
+  }
+  return strings.Join([]string {
+    "# Server",
+    "redis_version:7.0.0-fake",
+    "redis_mode:standalone",
+    "# Clients",
+    fmt.Sprintf("connected_clients:%d", len(r.conns)),
+    "# Memory",
+    fmt.Sprintf("used_memory:%d", memory),
+    "# Stats",
+    fmt.Sprintf("total_commands_processed:%d", r.commands),
+    fmt.Sprintf("instantaneous_ops_per_sec:%d", r.opsPerSec),
+    "# Replication",
+    "role:master",
+    "connected_slaves:0",
+    "# Keyspace",
+    fmt.Sprintf("db0:keys=%d,expires=0,avg_ttl=0", len(r.allKeys())),
+  }, "\r\n") + "\r\n"
+}
+
+// lrem removes count occurrences of value from the head, or from the tail
+// when negative, or all of them for 0
+func (r *Redis) lrem(key string, count int, value string) int {
+  l := r.lists[key]
+  var kept []string
+  n := 0
+  if count >= 0 {
+    for _, v := range l {
+      if v == value && (count == 0 || n < count) {
+        n++
+      } else {
+        kept = append(kept, v)
+      }
+    }
+  } else {
+    for i := len(l) - 1; i >= 0; i-- {
+      if l[i] == value && n < -count {
+        n++
+      } else {
+        kept = append([]string { l[i] }, kept...)
+      }
+    }
+  }
+  if len(kept) == 0 {
+    delete(r.lists, key)
+  } else {
+    r.lists[key] = kept
+  }
+  return n
+}
+
+// zadd handles the NX, XX and CH flags, answering with the number of members
+// added, or changed with CH
+func (r *Redis) zadd(args []string) interface{} {
+  if len(args) < 3 {
+    return errArgs("zadd")
+  }
+  key := args[0]
+  if !r.holds(key, "zset") {
+    return errWrongType
+  }
+  var nx, xx, ch bool
+  flags := map[string]*bool { "NX": &nx, "XX": &xx, "CH": &ch }
+  for args = args[1:]; len(args) > 0; args = args[1:] {
+    flag, ok := flags[strings.ToUpper(args[0])]
+    if !ok {
+      break
+    }
+    *flag = true
+  }
+  if len(args) == 0 || len(args) % 2 != 0 || nx && xx {
+    return errSyntax
+  }
+  z := r.zsets[key]
+  if z == nil {
+    z = make(map[string]float64)
+  }
+  n := 0
+  for i := 0; i < len(args); i += 2 {
+    score, err := strconv.ParseFloat(args[i], 64)
+    if err != nil {
+      return errors.New("ERR value is not a valid float")
+    }
+    member := args[i + 1]
+    old, exists := z[member]
+    if exists && nx || !exists && xx {
+      continue
+    }
+    z[member] = score
+    if !exists || ch && old != score {
+      n++
+    }
+  }
+  if len(z) > 0 {
+    r.zsets[key] = z
+  }
+  return n
+}
+
+// hashFields lists the fields matching pattern and their values
+func (r *Redis) hashFields(key string, pattern string) []string {
+  var fields []string
+  for k := range r.hashes[key] {
+    if glob(pattern, k) {
+      fields = append(fields, k)
+    }
+  }
+  sort.Strings(fields)
+  result := []string{}
+  for _, k := range fields {
+    result = append(result, k, r.hashes[key][k])
+  }
+  return result
+}
+
+// glob matches redis patterns, where * and ? match any character, slashes
+// included, unlike path.Match
+func glob(pattern string, s string) bool {
+  var re strings.Builder
+  re.WriteString("^")
+  for i := 0; i < len(pattern); i++ {
+    switch c := pattern[i]; c {
+    case '*':
+      re.WriteString("(?s:.*)")
+    case '?':
+      re.WriteString("(?s:.)")
+    case '[':
+      j := strings.IndexByte(pattern[i:], ']')
+      if j == -1 {
+        re.WriteString(`\[`)
+        continue
+      }
+      re.WriteString(pattern[i:i + j + 1])
+      i += j
+    case '\\':
+      if i + 1 < len(pattern) {
+        i++
+      }
+      re.WriteString(regexp.QuoteMeta(pattern[i:i + 1]))
+    default:
+      re.WriteString(regexp.QuoteMeta(string(c)))
+    }
+  }
+  re.WriteString("$")
+  ok, err := regexp.MatchString(re.String(), s)
+  return err == nil && ok
+}
+
+// options parses trailing MATCH and COUNT arguments
+func options(args []string) (string, error) {
+  match := "*"
+  for i := 0; i < len(args); i += 2 {
+    if i + 1 == len(args) {
+      return "", errSyntax
+    }
+    switch strings.ToUpper(args[i]) {
+    case "MATCH":
+      match = args[i + 1]
+    case "COUNT", "TYPE":
+    default:
+      return "", errSyntax
+    }
+  }
+  return match, nil
+}
+
+// hscan returns every field in one iteration
+func (r *Redis) hscan(args []string) interface{} {
+  if len(args) < 2 {
+    return errArgs("hscan")
+  }
+  if !r.holds(args[0], "hash") {
+    return errWrongType
+  }
+  match, err := options(args[2:])
+  if err != nil {
+    return err
+  }
+  return []interface{} { "0", r.hashFields(args[0], match) }
+}
+
+// scan answers KEYS, and SCAN in one iteration
+func (r *Redis) scan(command string, args []string) interface{} {
+  var match string
+  var err error
+  if command == "KEYS" {
+    if len(args) != 1 {
+      return errArgs(command)
+    }
+    match = args[0]
+  } else {
+    if len(args) < 1 {
+      return errArgs(command)
+    }
+    match, err = options(args[1:])
+    if err != nil {
+      return err
+    }
+  }
+  keys := []string{}
+  for _, k := range r.allKeys() {
+    if glob(match, k) {
+      keys = append(keys, k)
+    }
+  }
+  if command == "KEYS" {
+    return keys
+  }
+  return []interface{} { "0", keys }
+}
+
+func (r *Redis) Keys() []string {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  return r.allKeys()
+}
+
+func (r *Redis) allKeys() []string {
+  var keys []string
+  for k := range r.lists {
+    keys = append(keys, k)
+  }
+  for k := range r.hashes {
+    keys = append(keys, k)
+  }
+  for k := range r.zsets {
+    keys = append(keys, k)
+  }
+  sort.Strings(keys)
+  return keys
+}
+
+// LPush prepends values to the list at key, the way buildfarm enqueues
+func (r *Redis) LPush(key string, values ...string) {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  l := r.lists[key]
+  for _, v := range values {
+    l = append([]string { v }, l...)
+  }
+  r.lists[key] = l
+}
+
+// RPop removes and returns the last value of the list at key
+func (r *Redis) RPop(key string) (string, bool) {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  l := r.lists[key]
+  if len(l) == 0 {
+    return "", false
+  }
+  v := l[len(l) - 1]
+  if len(l) == 1 {
+    delete(r.lists, key)
+  } else {
+    r.lists[key] = l[:len(l) - 1]
+  }
+  return v, true
+}
+
+func (r *Redis) LLen(key string) int {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  return len(r.lists[key])
+}
+
+func (r *Redis) HSet(key string, field string, value string) {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  h := r.hashes[key]
+  if h == nil {
+    h = make(map[string]string)
+    r.hashes[key] = h
+  }
+  h[field] = value
+}
+
+func (r *Redis) HDel(key string, field string) bool {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  return r.hdel(key, field)
+}
+
+func (r *Redis) hdel(key string, field string) bool {
+  h := r.hashes[key]
+  if _, ok := h[field]; !ok {
+    return false
+  }
+  delete(h, field)
+  if len(h) == 0 {
+    delete(r.hashes, key)
+  }
+  return true
+}
+
+func (r *Redis) HLen(key string) int {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  return len(r.hashes[key])
+}
+
+func (r *Redis) ZAdd(key string, score float64, member string) {
+  r.mutex.Lock()
+  defer r.mutex.Unlock()
+  z := r.zsets[key]
+  if z == nil {
+    z = make(map[string]float64)
+    r.zsets[key] = z
+  }
+  z[member] = score
+}
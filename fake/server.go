@@ -0,0 +1,589 @@
+package fake
+
+import (
+  "context"
+  "fmt"
+  "net"
+  "strconv"
+  "strings"
+  "sync"
+  "time"
+  reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/golang/protobuf/jsonpb"
+  "github.com/golang/protobuf/proto"
+  "github.com/golang/protobuf/ptypes"
+  "github.com/golang/protobuf/ptypes/empty"
+  "github.com/werkt/bf-client/client"
+  "google.golang.org/genproto/googleapis/bytestream"
+  "google.golang.org/genproto/googleapis/longrunning"
+  rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+  "google.golang.org/grpc"
+  "google.golang.org/grpc/codes"
+  "google.golang.org/grpc/status"
+)
+
+const (
+  PrequeueName = "{Arrival}:PreQueuedOperations"
+  QueueName = "{Execution}:QueuedOperations"
+)
+
+// DispatchTimeout is how long a worker may go without reporting on an
+// operation before it is due to be requeued
+const DispatchTimeout = 30 * time.Second
+
+// operation is the server side of a longrunning operation, as it moves
+// from the prequeue to the queue and through a worker
+type operation struct {
+  op *longrunning.Operation
+  metadata *bfpb.QueuedOperationMetadata
+  entry *bfpb.ExecuteEntry
+  // redis values while prequeued, queued and dispatched
+  prequeued string
+  queued string
+  cancelled bool
+}
+
+// Server is an in-process buildfarm with the backplane status, operations,
+// CAS, action cache and bytestream of a reapi endpoint, over state held in
+// memory and queues held in a Redis stand-in
+type Server struct {
+  Redis *Redis
+  Instance string
+  mutex sync.Mutex
+  operations map[string]*operation
+  // names in submission order
+  names []string
+  blobs map[string][]byte
+  actionResults map[string]*reapi.ActionResult
+  workers []*Worker
+  listener net.Listener
+  server *grpc.Server
+}
+
+func NewServer(r *Redis) *Server {
+  return &Server {
+    Redis: r,
+    Instance: "shard",
+    operations: make(map[string]*operation),
+    blobs: make(map[string][]byte),
+    actionResults: make(map[string]*reapi.ActionResult),
+  }
+}
+
+// Start serves every service on addr, an empty port picks a free one
+func (s *Server) Start(addr string) error {
+  l, err := net.Listen("tcp", addr)
+  if err != nil {
+    return err
+  }
+  s.listener = l
+  s.server = grpc.NewServer()
+  bfpb.RegisterOperationQueueServer(s.server, &operationQueue { s: s })
+  longrunning.RegisterOperationsServer(s.server, &operations { s: s })
+  reapi.RegisterContentAddressableStorageServer(s.server, &cas { s: s })
+  reapi.RegisterActionCacheServer(s.server, &actionCache { s: s })
+  bytestream.RegisterByteStreamServer(s.server, &byteStream { s: s })
+  go s.server.Serve(l)
+  return nil
+}
+
+func (s *Server) Addr() string {
+  return s.listener.Addr().String()
+}
+
+func (s *Server) Stop() {
+  for _, w := range s.Workers() {
+    w.stop()
+  }
+  s.server.Stop()
+}
+
+func (s *Server) Workers() []*Worker {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  return append([]*Worker{}, s.workers...)
+}
+
+// PutBlob stores b in the CAS under its sha256 digest
+func (s *Server) PutBlob(b []byte) bfpb.Digest {
+  d := client.DigestFromBlob(b, client.SHA256)
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  s.blobs[client.DigestString(d)] = b
+  return d
+}
+
+func (s *Server) PutMessage(m proto.Message) (bfpb.Digest, error) {
+  b, err := proto.Marshal(m)
+  if err != nil {
+    return bfpb.Digest{}, err
+  }
+  return s.PutBlob(b), nil
+}
+
+func (s *Server) blob(d *reapi.Digest) ([]byte, bool) {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  b, ok := s.blobs[client.DigestString(bfpb.Digest { Hash: d.Hash, Size: d.SizeBytes })]
+  return b, ok
+}
+
+// PutAction stores an action running arguments over an input root holding
+// each of files
+func (s *Server) PutAction(arguments []string, files map[string]string) (bfpb.Digest, error) {
+  root := &reapi.Directory{}
+  for name, content := range files {
+    d := s.PutBlob([]byte(content))
+    root.Files = append(root.Files, &reapi.FileNode {
+      Name: name,
+      Digest: &reapi.Digest { Hash: d.Hash, SizeBytes: d.Size },
+    })
+  }
+  rootDigest, err := s.PutMessage(root)
+  if err != nil {
+    return bfpb.Digest{}, err
+  }
+  commandDigest, err := s.PutMessage(&reapi.Command { Arguments: arguments })
+  if err != nil {
+    return bfpb.Digest{}, err
+  }
+  return s.PutMessage(&reapi.Action {
+    CommandDigest: &reapi.Digest { Hash: commandDigest.Hash, SizeBytes: commandDigest.Size },
+    InputRootDigest: &reapi.Digest { Hash: rootDigest.Hash, SizeBytes: rootDigest.Size },
+  })
+}
+
+func (s *Server) PutActionResult(action bfpb.Digest, r *reapi.ActionResult) {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  s.actionResults[client.DigestString(action)] = r
+}
+
+func marshalEntry(m proto.Message) string {
+  json, err := (&jsonpb.Marshaler{}).MarshalToString(m)
+  if err != nil {
+    panic(err)
+  }
+  return json
+}
+
+// Submit prequeues an execution of action, returning the operation name
+func (s *Server) Submit(action bfpb.Digest, metadata *reapi.RequestMetadata) string {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  name := fmt.Sprintf("%s/executions/%08d", s.Instance, len(s.names) + 1)
+  actionDigest := &reapi.Digest { Hash: action.Hash, SizeBytes: action.Size }
+  o := &operation {
+    op: &longrunning.Operation { Name: name },
+    metadata: &bfpb.QueuedOperationMetadata {
+      ExecuteOperationMetadata: &reapi.ExecuteOperationMetadata {
+        Stage: reapi.ExecutionStage_QUEUED,
+        ActionDigest: actionDigest,
+      },
+      RequestMetadata: metadata,
+    },
+    entry: &bfpb.ExecuteEntry {
+      OperationName: name,
+      ActionDigest: actionDigest,
+      RequestMetadata: metadata,
+      QueuedTimestamp: ptypes.TimestampNow(),
+    },
+  }
+  s.setMetadata(o)
+  o.prequeued = marshalEntry(o.entry)
+  s.operations[name] = o
+  s.names = append(s.names, name)
+  s.Redis.LPush(PrequeueName, o.prequeued)
+  s.publish(o)
+  return name
+}
+
+// publish announces the change of o on its operation channel, as the
+// backplane does, with the server locked
+func (s *Server) publish(o *operation) {
+  s.Redis.Publish(client.OperationChannelPrefix + ":" + o.op.Name, marshalEntry(&bfpb.OperationChange {
+    EffectiveAt: ptypes.TimestampNow(),
+    Source: "fake",
+    Type: &bfpb.OperationChange_Reset_ {
+      Reset_: &bfpb.OperationChange_Reset { Operation: o.op },
+    },
+  }))
+}
+
+func (s *Server) setMetadata(o *operation) {
+  m, err := ptypes.MarshalAny(o.metadata)
+  if err != nil {
+    panic(err)
+  }
+  o.op.Metadata = m
+}
+
+// Cancel completes a pending operation with a cancelled status, it is
+// dropped from its queue when next reached
+func (s *Server) Cancel(name string) bool {
+  s.mutex.Lock()
+  o := s.operations[name]
+  if o == nil || o.op.Done {
+    s.mutex.Unlock()
+    return false
+  }
+  o.cancelled = true
+  o.op.Done = true
+  o.op.Result = &longrunning.Operation_Error {
+    Error: &rpcstatus.Status { Code: int32(codes.Canceled), Message: "cancelled" },
+  }
+  s.publish(o)
+  s.mutex.Unlock()
+  s.Redis.HDel(client.DispatchedOperationsHash, name)
+  for _, w := range s.Workers() {
+    w.remove(name)
+  }
+  return true
+}
+
+func (s *Server) operation(name string) *operation {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  return s.operations[name]
+}
+
+// Step moves every operation by one place: out of the last stage of each
+// worker, along its pipeline, from the queue into free workers and from the
+// prequeue into the queue
+func (s *Server) Step() {
+  for _, w := range s.Workers() {
+    w.step(s)
+  }
+  for _, w := range s.Workers() {
+    for w.available() {
+      entry, ok := s.Redis.RPop(QueueName)
+      if !ok {
+        break
+      }
+      s.dispatch(w, entry)
+    }
+  }
+  for {
+    entry, ok := s.Redis.RPop(PrequeueName)
+    if !ok {
+      break
+    }
+    s.enqueue(entry)
+  }
+}
+
+func (s *Server) find(entry string, prequeued bool) *operation {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  for _, o := range s.operations {
+    if (prequeued && o.prequeued == entry) || (!prequeued && o.queued == entry) {
+      return o
+    }
+  }
+  return nil
+}
+
+func (s *Server) enqueue(entry string) {
+  o := s.find(entry, true)
+  if o == nil || o.cancelled {
+    return
+  }
+  s.mutex.Lock()
+  o.prequeued = ""
+  o.queued = marshalEntry(&bfpb.QueueEntry {
+    ExecuteEntry: o.entry,
+    QueuedOperationDigest: &bfpb.Digest { Hash: o.entry.ActionDigest.Hash, Size: o.entry.ActionDigest.SizeBytes },
+  })
+  queued := o.queued
+  s.publish(o)
+  s.mutex.Unlock()
+  s.Redis.LPush(QueueName, queued)
+}
+
+func (s *Server) dispatch(w *Worker, entry string) {
+  o := s.find(entry, false)
+  if o == nil || o.cancelled {
+    return
+  }
+  s.mutex.Lock()
+  o.queued = ""
+  s.mutex.Unlock()
+  s.extend(o)
+  w.start(s, o)
+}
+
+// extend pushes back the requeue deadline of a dispatched operation, as
+// workers do while they hold one
+func (s *Server) extend(o *operation) {
+  s.Redis.HSet(client.DispatchedOperationsHash, o.op.Name, marshalEntry(&bfpb.DispatchedOperation {
+    QueueEntry: &bfpb.QueueEntry { ExecuteEntry: o.entry },
+    RequeueAt: time.Now().Add(DispatchTimeout).UnixMilli(),
+  }))
+}
+
+// advance records an operation entering stage on worker
+func (s *Server) advance(o *operation, worker string, stage string) {
+  s.mutex.Lock()
+  defer s.mutex.Unlock()
+  em := o.metadata.ExecuteOperationMetadata
+  if em.PartialExecutionMetadata == nil {
+    em.PartialExecutionMetadata = &reapi.ExecutedActionMetadata {
+      Worker: worker,
+      QueuedTimestamp: o.entry.QueuedTimestamp,
+    }
+  }
+  m := em.PartialExecutionMetadata
+  now := ptypes.TimestampNow()
+  switch stage {
+  case "MatchStage":
+    m.WorkerStartTimestamp = now
+  case "InputFetchStage":
+    m.InputFetchStartTimestamp = now
+  case "ExecuteActionStage":
+    m.InputFetchCompletedTimestamp = now
+    m.ExecutionStartTimestamp = now
+    em.Stage = reapi.ExecutionStage_EXECUTING
+  case "ReportResultStage":
+    m.ExecutionCompletedTimestamp = now
+    m.OutputUploadStartTimestamp = now
+    em.Stage = reapi.ExecutionStage_COMPLETED
+  }
+  s.setMetadata(o)
+  s.publish(o)
+}
+
+func (s *Server) complete(o *operation) {
+  s.mutex.Lock()
+  em := o.metadata.ExecuteOperationMetadata
+  m := em.PartialExecutionMetadata
+  now := ptypes.TimestampNow()
+  m.OutputUploadCompletedTimestamp = now
+  m.WorkerCompletedTimestamp = now
+  result := &reapi.ActionResult { ExitCode: 0, ExecutionMetadata: m }
+  s.actionResults[client.DigestString(bfpb.Digest { Hash: em.ActionDigest.Hash, Size: em.ActionDigest.SizeBytes })] = result
+  response, err := ptypes.MarshalAny(&reapi.ExecuteResponse { Result: result })
+  if err != nil {
+    panic(err)
+  }
+  o.op.Done = true
+  o.op.Result = &longrunning.Operation_Response { Response: response }
+  s.publish(o)
+  s.mutex.Unlock()
+  s.Redis.HDel(client.DispatchedOperationsHash, o.op.Name)
+}
+
+func (s *Server) status() *bfpb.BackplaneStatus {
+  queue := int64(s.Redis.LLen(QueueName))
+  var workers []string
+  for _, w := range s.Workers() {
+    workers = append(workers, w.Name)
+  }
+  return &bfpb.BackplaneStatus {
+    Prequeue: &bfpb.QueueStatus {
+      Name: PrequeueName,
+      Size: int64(s.Redis.LLen(PrequeueName)),
+    },
+    OperationQueue: &bfpb.OperationQueueStatus {
+      Size: queue,
+      Provisions: []*bfpb.QueueStatus {
+        &bfpb.QueueStatus { Name: QueueName, Size: queue },
+      },
+    },
+    DispatchedSize: int64(s.Redis.HLen(client.DispatchedOperationsHash)),
+    ActiveExecuteWorkers: workers,
+  }
+}
+
+type operationQueue struct {
+  bfpb.UnimplementedOperationQueueServer
+  s *Server
+}
+
+func (q *operationQueue) Status(ctx context.Context, r *bfpb.BackplaneStatusRequest) (*bfpb.BackplaneStatus, error) {
+  return q.s.status(), nil
+}
+
+type operations struct {
+  longrunning.UnimplementedOperationsServer
+  s *Server
+}
+
+func (o *operations) GetOperation(ctx context.Context, r *longrunning.GetOperationRequest) (*longrunning.Operation, error) {
+  op := o.s.operation(r.Name)
+  if op == nil {
+    return nil, status.Error(codes.NotFound, r.Name)
+  }
+  o.s.mutex.Lock()
+  defer o.s.mutex.Unlock()
+  return proto.Clone(op.op).(*longrunning.Operation), nil
+}
+
+// matches applies a filter of space separated key=value terms to the
+// request metadata of an operation
+func matches(o *operation, filter string) bool {
+  m := o.metadata.RequestMetadata
+  if m == nil {
+    m = &reapi.RequestMetadata{}
+  }
+  for _, term := range strings.Fields(filter) {
+    key, value, _ := strings.Cut(term, "=")
+    var actual string
+    switch key {
+    case "toolInvocationId":
+      actual = m.ToolInvocationId
+    case "correlatedInvocationsId":
+      actual = m.CorrelatedInvocationsId
+    case "actionMnemonic":
+      actual = m.ActionMnemonic
+    case "targetId":
+      actual = m.TargetId
+    case "stage":
+      actual = o.metadata.ExecuteOperationMetadata.Stage.String()
+    default:
+      continue
+    }
+    if actual != value {
+      return false
+    }
+  }
+  return true
+}
+
+func (o *operations) ListOperations(ctx context.Context, r *longrunning.ListOperationsRequest) (*longrunning.ListOperationsResponse, error) {
+  start := 0
+  if r.PageToken != "" {
+    var err error
+    if start, err = strconv.Atoi(r.PageToken); err != nil {
+      return nil, status.Error(codes.InvalidArgument, "invalid page token")
+    }
+  }
+  size := int(r.PageSize)
+  if size <= 0 {
+    size = 100
+  }
+  o.s.mutex.Lock()
+  defer o.s.mutex.Unlock()
+  response := &longrunning.ListOperationsResponse{}
+  i := start
+  for ; i < len(o.s.names) && len(response.Operations) < size; i++ {
+    op := o.s.operations[o.s.names[i]]
+    if matches(op, r.Filter) {
+      response.Operations = append(response.Operations, proto.Clone(op.op).(*longrunning.Operation))
+    }
+  }
+  if i < len(o.s.names) {
+    response.NextPageToken = strconv.Itoa(i)
+  }
+  return response, nil
+}
+
+func (o *operations) CancelOperation(ctx context.Context, r *longrunning.CancelOperationRequest) (*empty.Empty, error) {
+  if o.s.operation(r.Name) == nil {
+    return nil, status.Error(codes.NotFound, r.Name)
+  }
+  o.s.Cancel(r.Name)
+  return &empty.Empty{}, nil
+}
+
+type cas struct {
+  reapi.UnimplementedContentAddressableStorageServer
+  s *Server
+}
+
+func (c *cas) FindMissingBlobs(ctx context.Context, r *reapi.FindMissingBlobsRequest) (*reapi.FindMissingBlobsResponse, error) {
+  response := &reapi.FindMissingBlobsResponse{}
+  for _, d := range r.BlobDigests {
+    if _, ok := c.s.blob(d); !ok {
+      response.MissingBlobDigests = append(response.MissingBlobDigests, d)
+    }
+  }
+  return response, nil
+}
+
+// GetTree returns every directory under the root in one page
+func (c *cas) GetTree(r *reapi.GetTreeRequest, stream reapi.ContentAddressableStorage_GetTreeServer) error {
+  response := &reapi.GetTreeResponse{}
+  pending := []*reapi.Digest { r.RootDigest }
+  for len(pending) > 0 {
+    d := pending[0]
+    pending = pending[1:]
+    b, ok := c.s.blob(d)
+    if !ok {
+      continue
+    }
+    dir := &reapi.Directory{}
+    if err := proto.Unmarshal(b, dir); err != nil {
+      return status.Error(codes.DataLoss, err.Error())
+    }
+    response.Directories = append(response.Directories, dir)
+    for _, child := range dir.Directories {
+      pending = append(pending, child.Digest)
+    }
+  }
+  return stream.Send(response)
+}
+
+type actionCache struct {
+  reapi.UnimplementedActionCacheServer
+  s *Server
+}
+
+func actionKey(d *reapi.Digest) string {
+  return client.DigestString(bfpb.Digest { Hash: d.Hash, Size: d.SizeBytes })
+}
+
+func (a *actionCache) GetActionResult(ctx context.Context, r *reapi.GetActionResultRequest) (*reapi.ActionResult, error) {
+  a.s.mutex.Lock()
+  defer a.s.mutex.Unlock()
+  result, ok := a.s.actionResults[actionKey(r.ActionDigest)]
+  if !ok {
+    return nil, status.Error(codes.NotFound, actionKey(r.ActionDigest))
+  }
+  return result, nil
+}
+
+func (a *actionCache) UpdateActionResult(ctx context.Context, r *reapi.UpdateActionResultRequest) (*reapi.ActionResult, error) {
+  a.s.mutex.Lock()
+  defer a.s.mutex.Unlock()
+  a.s.actionResults[actionKey(r.ActionDigest)] = r.ActionResult
+  return r.ActionResult, nil
+}
+
+type byteStream struct {
+  bytestream.UnimplementedByteStreamServer
+  s *Server
+}
+
+// Read serves [instance/]blobs/[function/]hash/size
+func (b *byteStream) Read(r *bytestream.ReadRequest, stream bytestream.ByteStream_ReadServer) error {
+  i := strings.Index(r.ResourceName, "blobs/")
+  if i == -1 {
+    return status.Error(codes.InvalidArgument, "invalid resource name: " + r.ResourceName)
+  }
+  d := client.ParseDigest(r.ResourceName[i + len("blobs/"):])
+  blob, ok := b.s.blob(&reapi.Digest { Hash: d.Hash, SizeBytes: d.Size })
+  if !ok {
+    return status.Error(codes.NotFound, r.ResourceName)
+  }
+  if r.ReadOffset > int64(len(blob)) {
+    return status.Error(codes.OutOfRange, "read offset beyond the blob")
+  }
+  blob = blob[r.ReadOffset:]
+  if r.ReadLimit > 0 && r.ReadLimit < int64(len(blob)) {
+    blob = blob[:r.ReadLimit]
+  }
+  for {
+    n := len(blob)
+    if n > 64 * 1024 {
+      n = 64 * 1024
+    }
+    if err := stream.Send(&bytestream.ReadResponse { Data: blob[:n] }); err != nil {
+      return err
+    }
+    blob = blob[n:]
+    if len(blob) == 0 {
+      return nil
+    }
+  }
+}
@@ -0,0 +1,182 @@
+package fake
+
+import (
+  "context"
+  "net"
+  "sync"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  "github.com/golang/protobuf/proto"
+  "google.golang.org/genproto/googleapis/longrunning"
+  "google.golang.org/grpc"
+)
+
+// Worker is a fake execute worker, serving its profile and pipeline control
+// on its own address, which is also its name
+type Worker struct {
+  Name string
+  mutex sync.Mutex
+  // stages in pipeline order
+  stages []*bfpb.StageInformation
+  paused map[string]bool
+  listener net.Listener
+  server *grpc.Server
+}
+
+// AddWorker starts a worker with slots in each stage after matching
+func (s *Server) AddWorker(slots int32) (*Worker, error) {
+  l, err := net.Listen("tcp", "127.0.0.1:0")
+  if err != nil {
+    return nil, err
+  }
+  w := &Worker {
+    Name: l.Addr().String(),
+    stages: []*bfpb.StageInformation {
+      &bfpb.StageInformation { Name: "MatchStage", SlotsConfigured: 1 },
+      &bfpb.StageInformation { Name: "InputFetchStage", SlotsConfigured: slots },
+      &bfpb.StageInformation { Name: "ExecuteActionStage", SlotsConfigured: slots },
+      &bfpb.StageInformation { Name: "ReportResultStage", SlotsConfigured: slots },
+    },
+    paused: make(map[string]bool),
+    listener: l,
+    server: grpc.NewServer(),
+  }
+  bfpb.RegisterWorkerProfileServer(w.server, &workerProfile { w: w })
+  bfpb.RegisterWorkerControlServer(w.server, &workerControl { w: w })
+  // workers answer for the operations they hold
+  longrunning.RegisterOperationsServer(w.server, &operations { s: s })
+  go w.server.Serve(l)
+  s.mutex.Lock()
+  s.workers = append(s.workers, w)
+  s.mutex.Unlock()
+  return w, nil
+}
+
+func (w *Worker) stop() {
+  w.server.Stop()
+}
+
+// Pause stops stage taking new operations
+func (w *Worker) Pause(stage string, paused bool) {
+  w.mutex.Lock()
+  defer w.mutex.Unlock()
+  w.paused[stage] = paused
+}
+
+func (w *Worker) Profile() *bfpb.WorkerProfileMessage {
+  w.mutex.Lock()
+  defer w.mutex.Unlock()
+  p := &bfpb.WorkerProfileMessage { Name: w.Name }
+  for _, stage := range w.stages {
+    stage := proto.Clone(stage).(*bfpb.StageInformation)
+    stage.SlotsUsed = int32(len(stage.OperationNames))
+    p.Stages = append(p.Stages, stage)
+  }
+  return p
+}
+
+// accepts reports whether stage i can take another operation
+func (w *Worker) accepts(i int) bool {
+  stage := w.stages[i]
+  return !w.paused[stage.Name] && int32(len(stage.OperationNames)) < stage.SlotsConfigured
+}
+
+func (w *Worker) available() bool {
+  w.mutex.Lock()
+  defer w.mutex.Unlock()
+  return w.accepts(0)
+}
+
+func (w *Worker) start(s *Server, o *operation) {
+  w.mutex.Lock()
+  w.stages[0].OperationNames = append(w.stages[0].OperationNames, o.op.Name)
+  w.mutex.Unlock()
+  s.advance(o, w.Name, w.stages[0].Name)
+}
+
+func (w *Worker) remove(name string) {
+  w.mutex.Lock()
+  defer w.mutex.Unlock()
+  for _, stage := range w.stages {
+    for i, n := range stage.OperationNames {
+      if n == name {
+        stage.OperationNames = append(stage.OperationNames[:i], stage.OperationNames[i + 1:]...)
+        return
+      }
+    }
+  }
+}
+
+// step completes the operations in the last stage, and moves those of each
+// earlier stage into the next as far as it accepts them
+func (w *Worker) step(s *Server) {
+  w.mutex.Lock()
+  last := w.stages[len(w.stages) - 1]
+  completed := last.OperationNames
+  last.OperationNames = nil
+  type move struct {
+    name string
+    stage string
+  }
+  var moves []move
+  for i := len(w.stages) - 2; i >= 0; i-- {
+    stage := w.stages[i]
+    for len(stage.OperationNames) > 0 && w.accepts(i + 1) {
+      name := stage.OperationNames[0]
+      stage.OperationNames = stage.OperationNames[1:]
+      next := w.stages[i + 1]
+      next.OperationNames = append(next.OperationNames, name)
+      moves = append(moves, move { name: name, stage: next.Name })
+    }
+  }
+  w.mutex.Unlock()
+
+  for _, name := range completed {
+    if o := s.operation(name); o != nil && !o.cancelled {
+      s.complete(o)
+    }
+  }
+  for _, m := range moves {
+    if o := s.operation(m.name); o != nil && !o.cancelled {
+      s.advance(o, w.Name, m.stage)
+      s.extend(o)
+    }
+  }
+}
+
+type workerProfile struct {
+  bfpb.UnimplementedWorkerProfileServer
+  w *Worker
+}
+
+func (p *workerProfile) GetWorkerProfile(ctx context.Context, r *bfpb.WorkerProfileRequest) (*bfpb.WorkerProfileMessage, error) {
+  return p.w.Profile(), nil
+}
+
+type workerControl struct {
+  bfpb.UnimplementedWorkerControlServer
+  w *Worker
+}
+
+// PipelineChange applies each change and responds with the state of every
+// stage, an empty request only queries it
+func (c *workerControl) PipelineChange(ctx context.Context, r *bfpb.WorkerPipelineChangeRequest) (*bfpb.WorkerPipelineChangeResponse, error) {
+  c.w.mutex.Lock()
+  defer c.w.mutex.Unlock()
+  for _, change := range r.Changes {
+    c.w.paused[change.Stage] = change.Paused
+    for _, stage := range c.w.stages {
+      if stage.Name == change.Stage && change.Width > 0 {
+        stage.SlotsConfigured = change.Width
+      }
+    }
+  }
+  response := &bfpb.WorkerPipelineChangeResponse{}
+  for _, stage := range c.w.stages {
+    response.Changes = append(response.Changes, &bfpb.PipelineChange {
+      Stage: stage.Name,
+      Paused: c.w.paused[stage.Name],
+      Width: stage.SlotsConfigured,
+    })
+  }
+  return response, nil
+}
@@ -2,18 +2,22 @@ package main
 
 import (
   "fmt"
+  "image"
   "log"
-  "strings"
+  "strconv"
   "time"
   "os"
 
   ui "github.com/gizak/termui/v3"
   "github.com/gizak/termui/v3/widgets"
 
+  "github.com/werkt/bf-client/cli"
   "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake"
   "github.com/werkt/bf-client/view"
 
   tm "github.com/nsf/termbox-go"
+  "google.golang.org/grpc"
 )
 
 type component interface {
@@ -22,70 +26,255 @@ type component interface {
   handle(ui.Event)
   update() component
   render()
+  resize(width int, height int)
   done() bool
 }
 
 type baseComponent struct {
   a *client.App
   v view.View
+  area image.Rectangle
+  recorder *client.Recorder
+  replay *client.Replay
+  // monitor evaluates the alert rules and records the history whichever
+  // view is open
+  monitor *view.Monitor
+  // historyErr is why the history could not be opened
+  historyErr error
+  // bell is rung after the next frame, between termbox flushes
+  bell bool
 }
 
 func (c *baseComponent) open() {
   c.a.Connect()
+  c.a.Watch()
 }
 
 func (c *baseComponent) close() {
   c.a.Conn.Close()
+  if c.a.History != nil {
+    c.a.History.Close()
+  }
+  if c.recorder != nil {
+    c.recorder.Close()
+  }
 }
 
 func (c *baseComponent) handle(e ui.Event) {
+  if c.replay != nil {
+    // replay controls are taken before the view
+    switch e.ID {
+    case "<F5>":
+      c.replay.TogglePause()
+      return
+    case "<F6>":
+      c.replay.Seek(-time.Minute)
+      return
+    case "<F7>":
+      c.replay.Seek(time.Minute)
+      return
+    case "<F8>":
+      c.replay.SetSpeed(c.replay.Speed() / 2)
+      return
+    case "<F9>":
+      c.replay.SetSpeed(c.replay.Speed() * 2)
+      return
+    }
+  }
   c.v = c.v.Handle(e)
 }
 
 func (c *baseComponent) update() component {
   c.v.Update()
+  if c.monitor != nil {
+    c.monitor.Update()
+  }
   return c
 }
 
-func (c baseComponent) render() {
-  w := c.v.Render()
+func (c *baseComponent) render() {
+  w := c.v.Render(c.area)
 
+  // status line below the view
   f := widgets.NewParagraph()
+  f.Border = false
   f.Text = fmt.Sprintf("Fetches: %d", c.a.Fetches)
-  f.SetRect(0, 40, 20, 43)
+  width := 20
+  if c.a.Alerts != nil {
+    if firing := len(c.a.Alerts.Firing()); firing > 0 {
+      f.Text += fmt.Sprintf("  [%d alerts firing](fg:red,mod:bold)", firing)
+      width += 20
+    }
+  }
+  historyErr := c.historyErr
+  if historyErr == nil && c.a.History != nil {
+    historyErr = c.a.History.Err()
+  }
+  if historyErr != nil {
+    message := fmt.Sprintf("history: %v", historyErr)
+    f.Text += fmt.Sprintf("  [%s](fg:red)", message)
+    width += len(message) + 2
+  }
+  if c.replay != nil {
+    state := "playing"
+    if c.replay.Paused() {
+      state = "paused"
+    }
+    now := c.replay.Now()
+    f.Text += fmt.Sprintf("  Replay %s %s/%s %gx %s  F5 play/pause F6/F7 seek F8/F9 speed",
+        now.Format("2006-01-02 15:04:05"),
+        now.Sub(c.replay.Start).Truncate(time.Second),
+        c.replay.End.Sub(c.replay.Start).Truncate(time.Second),
+        c.replay.Speed(), state)
+    width = c.area.Dx() + 1
+  }
+  f.SetRect(c.area.Min.X - 1, c.area.Max.Y - 1, c.area.Min.X + width, c.area.Max.Y + 2)
+
+  // drawn first, its buffer would clear the row above it
+  ui.Render(append([]ui.Drawable{ f }, w...)...)
+  if c.bell {
+    c.bell = false
+    fmt.Fprint(os.Stdout, "\a")
+  }
+}
 
-  ui.Render(append(w, f)...)
+func (c *baseComponent) resize(width int, height int) {
+  c.area = image.Rect(0, 0, width, height - 1)
 }
 
 func (c baseComponent) done() bool {
   return c.a.Done
 }
 
+func usage() {
+  fmt.Fprintf(os.Stderr, "usage: %s <redis> <reapi> [ca]\n", os.Args[0])
+  fmt.Fprintf(os.Stderr, "       %s record <file> <redis> <reapi> [ca]\n", os.Args[0])
+  fmt.Fprintf(os.Stderr, "       %s replay <file>\n", os.Args[0])
+  fmt.Fprintf(os.Stderr, "       %s demo [workers]\n", os.Args[0])
+  os.Exit(2)
+}
+
 func main() {
-  if err := ui.Init(); err != nil {
-    log.Fatalf("failed to initialize termui: %v", err)
+  if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+    os.Exit(cli.Run(os.Args[1:]))
+  }
+
+  args := os.Args[1:]
+  var recordPath string
+  var replay *client.Replay
+  var demo *fake.Demo
+  if len(args) > 0 && args[0] == "record" {
+    if len(args) < 4 {
+      usage()
+    }
+    recordPath, args = args[1], args[2:]
+  } else if len(args) > 0 && args[0] == "replay" {
+    if len(args) != 2 {
+      usage()
+    }
+    var err error
+    replay, err = client.OpenReplay(args[1])
+    if err != nil {
+      log.Fatalf("failed to open replay: %v", err)
+    }
+    args = []string{replay.RedisHost, replay.ReapiHost}
+  } else if len(args) > 0 && args[0] == "demo" {
+    workers := 8
+    if len(args) > 1 {
+      var err error
+      if workers, err = strconv.Atoi(args[1]); err != nil || workers < 1 {
+        usage()
+      }
+    }
+    var err error
+    demo, err = fake.StartDemo(workers)
+    if err != nil {
+      log.Fatalf("failed to start demo: %v", err)
+    }
+    defer demo.Stop()
+    args = []string{demo.Redis.Addr(), demo.Server.Addr()}
+  } else if len(args) < 2 {
+    usage()
+  }
+
+  configPath, err := client.DefaultConfigPath()
+  if err != nil {
+    log.Fatalf("failed to locate config: %v", err)
+  }
+  config, err := client.LoadConfig(configPath)
+  if err != nil {
+    log.Fatalf("failed to load config: %v", err)
   }
-  tm.SetInputMode(tm.InputEsc)
-  defer ui.Close()
 
-  redisHost, reapiHost := os.Args[1], os.Args[2]
+  redisHost, reapiHost := args[0], args[1]
 
   var ca string
-  if len(os.Args) > 3 {
-    ca = os.Args[3]
+  if len(args) > 2 {
+    ca = args[2]
   }
 
-  if !strings.Contains(redisHost, ":") {
-    redisHost += ":6379"
+  a := client.NewApp(redisHost, reapiHost, ca)
+  var historyErr error
+  var recorder *client.Recorder
+  if recordPath != "" {
+    recorder, err = client.NewRecorder(recordPath, a)
+    if err != nil {
+      log.Fatalf("failed to open recording: %v", err)
+    }
+    a.DialOptions = append(a.DialOptions, grpc.WithChainUnaryInterceptor(recorder.Intercept))
+  }
+  if replay != nil {
+    // every call is answered from the recording, a replay keeps no history
+    replay.Attach(a)
+  } else if demo != nil {
+    // a demo keeps no history
+  } else {
+    // without history the dashboard only plots this session
+    var path string
+    path, historyErr = client.DefaultHistoryPath(reapiHost)
+    if historyErr == nil {
+      a.History, historyErr = client.OpenHistory(path, 10 * time.Second, 7 * 24 * time.Hour)
+    }
+  }
+  // recorded and fake operations notify no one
+  if len(config.Rules) > 0 && replay == nil && demo == nil {
+    a.Alerts = client.NewAlerts(config)
   }
 
-  a := client.NewApp(redisHost, reapiHost, ca)
-  var c component = &baseComponent {
+  if err := ui.Init(); err != nil {
+    log.Fatalf("failed to initialize termui: %v", err)
+  }
+  tm.SetInputMode(tm.InputEsc | tm.InputMouse)
+  defer ui.Close()
+
+  var monitor *view.Monitor
+  if a.Alerts != nil || a.History != nil {
+    monitor = view.NewMonitor(a)
+  }
+  newView := func() view.View {
+    q := view.NewQueue(a, 3)
+    if monitor != nil {
+      monitor.Feed(q)
+    }
+    return q
+  }
+  base := &baseComponent {
     a: a,
-    v: view.NewQueue(a, 3),
+    v: view.NewWorkspace(a, newView(), newView),
+    recorder: recorder,
+    replay: replay,
+    monitor: monitor,
+    historyErr: historyErr,
+  }
+  if a.Alerts != nil {
+    a.Alerts.Bell = func() {
+      base.bell = true
+    }
   }
+  var c component = base
 
   c.open()
+  c.resize(ui.TerminalDimensions())
 
   uiEvents := ui.PollEvents()
   lastFrameLimit := a.FrameLimit
@@ -93,7 +282,13 @@ func main() {
   for !c.done() {
     select {
     case e := <-uiEvents:
-      c.handle(e)
+      if e.ID == "<Resize>" {
+        r := e.Payload.(ui.Resize)
+        ui.Clear()
+        c.resize(r.Width, r.Height)
+      } else {
+        c.handle(e)
+      }
       if lastFrameLimit != a.FrameLimit {
         ticker = time.NewTicker(time.Second / time.Duration(a.FrameLimit)).C
         lastFrameLimit = a.FrameLimit
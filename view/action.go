@@ -2,12 +2,12 @@ package view
 
 import (
   "fmt"
+  "image"
   "strings"
 
   reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
   bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
   ui "github.com/gizak/termui/v3"
-  "github.com/gizak/termui/v3/widgets"
   "github.com/werkt/bf-client/client"
   "golang.org/x/net/html"
 )
@@ -19,7 +19,7 @@ type actionView struct {
   err error
   v View
   doc *client.Document
-  p *widgets.Paragraph
+  p *client.Paragraph
   actionNode *html.Node
   source bool
 
@@ -57,7 +57,7 @@ func NewAction(a *client.App, d bfpb.Digest, v View) View {
     a: a,
     d: d,
     v: v,
-    p: widgets.NewParagraph(),
+    p: client.NewParagraph(),
     actionNode: actionNode,
     doc: doc,
     anchors: anchors,
@@ -102,6 +102,16 @@ func (v *actionView) Handle(e ui.Event) View {
       return v.link(href)
     }
     return v
+  case "<MouseLeft>":
+    if v.source {
+      return v
+    }
+    if i := anchorAt(v.doc, v.p, v.anchors, mousePoint(e)); i != -1 {
+      defocus(v.anchors[v.focusAnchor])
+      v.focusAnchor = i
+      focus(v.anchors[v.focusAnchor])
+      return v.Handle(enterEvent)
+    }
   }
   return v
 }
@@ -151,14 +161,14 @@ func (v *actionView) Update() {
   }
 }
 
-func (v actionView) Render() []ui.Drawable {
+func (v actionView) Render(area image.Rectangle) []ui.Drawable {
   v.p.Title = v.doc.Title()
   if v.source {
     v.p.Text = v.doc.RenderSource()
   } else {
     v.p.Text = v.doc.Render()
   }
-  v.p.SetRect(0, 0, 120, 60)
+  setRect(v.p, area)
   /*
   if v.err != nil {
     p.Text = string(v.err.Error())
@@ -0,0 +1,235 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/dustin/go-humanize"
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// the fleet's CAS is profiled at most this often, each worker given the
+// timeout to answer
+const casInterval = 2 * time.Second
+const casTimeout = time.Second
+
+// width of a CAS gauge
+const casBar = 20
+
+// a CAS this full is about to evict heavily, one nearly so is warned of
+const casFull = 0.95
+const casFilling = 0.8
+
+func fillColor(fraction float64) string {
+  switch {
+  case fraction >= casFull:
+    return "red"
+  case fraction >= casFilling:
+    return "yellow"
+  }
+  return "green"
+}
+
+// casGauge draws fraction of width cells in the color of its fill
+func casGauge(fraction float64) string {
+  done := Min(Max(int(fraction * casBar + 0.5), 0), casBar)
+  gauge := "[" + strings.Repeat("#", done) + "](fg:" + fillColor(fraction) + ")"
+  if done < casBar {
+    gauge += "[" + strings.Repeat("-", casBar - done) + "](fg:white,mod:dim)"
+  }
+  return gauge
+}
+
+// perSecond signs a per second value, humanizing bytes
+func perSecond(v float64, bytes bool) string {
+  sign := "+"
+  if v < 0 {
+    sign, v = "-", -v
+  }
+  if bytes {
+    return sign + humanize.Bytes(uint64(v)) + "/s"
+  }
+  return fmt.Sprintf("%s%.1f/s", sign, v)
+}
+
+// casRows describes the CAS of a worker, its trend once profiled twice
+func casRows(t *client.CasTrend) []string {
+  c := t.Last
+  if c == nil {
+    if t.Err != nil {
+      return []string { fmt.Sprintf("[%v](fg:red)", t.Err) }
+    }
+    return []string { "profiling..." }
+  }
+  trend := t.Previous != nil
+  size := fmt.Sprintf("Size         %s %3d%% %s of %s", casGauge(c.Fill()), int(c.Fill() * 100), humanize.Bytes(uint64(c.Size)), humanize.Bytes(uint64(c.MaxSize)))
+  entries := fmt.Sprintf("Entries      %d, %d directories", c.Entries, c.Directories)
+  unreferenced := fmt.Sprintf("Unreferenced %s %3d%% %d entries", casGauge(c.UnreferencedFill()), int(c.UnreferencedFill() * 100), c.Unreferenced)
+  evicted := fmt.Sprintf("Evicted      %d entries, %s", c.Evicted, humanize.Bytes(uint64(c.EvictedSize)))
+  if trend {
+    size += " " + perSecond(t.Growth(), true)
+    entries += " " + perSecond(t.EntryGrowth(), false)
+    evicted += fmt.Sprintf(" %s, %s", perSecond(t.Evictions(), false), perSecond(t.EvictedBytes(), true))
+    if c.Evicted > 0 {
+      evicted = "[" + evicted + "](fg:yellow)"
+    }
+  }
+  rows := []string { size, entries, unreferenced, evicted }
+  if c.MaxEntrySize > 0 {
+    rows = append(rows, "Max entry    " + humanize.Bytes(uint64(c.MaxEntrySize)))
+  }
+  if t.Err != nil {
+    rows = append(rows, fmt.Sprintf("[%v](fg:red)", t.Err))
+  }
+  return rows
+}
+
+type casView struct {
+  a *client.App
+  v View
+  trends map[string]*client.CasTrend
+  // rows are the trends in the order listed
+  rows []*client.CasTrend
+  err error
+  last time.Time
+  list *client.List
+}
+
+// NewCasTable lists the CAS of every worker, the fullest first
+func NewCasTable(a *client.App, v View) View {
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  return &casView {
+    a: a,
+    v: v,
+    trends: make(map[string]*client.CasTrend),
+    list: list,
+  }
+}
+
+func (v *casView) Handle(e ui.Event) View {
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.list.ScrollDown()
+  case "k", "<Up>":
+    v.list.ScrollUp()
+  case "J", "<PageDown>":
+    v.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.list.ScrollPageUp()
+  case "r":
+    // profile again now
+    v.last = time.Time{}
+  case "<Enter>":
+    if v.list.SelectedRow >= 0 && v.list.SelectedRow < len(v.rows) {
+      ui.Clear()
+      return NewWorker(v.a, v.rows[v.list.SelectedRow].Worker, v)
+    }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.list.RowAt(p); row != -1 {
+      v.list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
+  }
+  return v
+}
+
+func (v *casView) Update() {
+  if time.Since(v.last) < casInterval {
+    return
+  }
+  v.last = time.Now()
+  v.a.Fetches++
+  status, err := client.BackplaneStatus(context.Background(), v.a.Conn, v.a.Instance)
+  if v.err = err; err != nil {
+    return
+  }
+  // storage workers hold a CAS as execute workers do
+  trends := make(map[string]*client.CasTrend)
+  for _, worker := range append(status.ActiveExecuteWorkers, status.ActiveStorageWorkers...) {
+    if t := v.trends[worker]; t != nil {
+      trends[worker] = t
+    } else {
+      trends[worker] = client.NewCasTrend(worker)
+    }
+  }
+  v.trends = trends
+  var all []*client.CasTrend
+  for _, t := range trends {
+    all = append(all, t)
+  }
+  v.a.Fetches += uint(len(all))
+  client.ProfileCas(v.a, all, casTimeout)
+}
+
+func casFill(t *client.CasTrend) float64 {
+  if t.Last == nil {
+    return -1
+  }
+  return t.Last.Fill()
+}
+
+func (v *casView) Render(area image.Rectangle) []ui.Drawable {
+  v.rows = v.rows[:0]
+  width := 0
+  for _, t := range v.trends {
+    v.rows = append(v.rows, t)
+    width = Max(width, len(t.Worker))
+  }
+  sort.Slice(v.rows, func(i, j int) bool {
+    if fi, fj := casFill(v.rows[i]), casFill(v.rows[j]); fi != fj {
+      return fi > fj
+    }
+    return v.rows[i].Worker < v.rows[j].Worker
+  })
+  var rows []string
+  full := 0
+  for _, t := range v.rows {
+    row := t.Worker + strings.Repeat(" ", width - len(t.Worker))
+    if c := t.Last; c != nil {
+      if c.Fill() >= casFull {
+        full++
+      }
+      row += fmt.Sprintf(" %s %3d%% %9s/%-9s %9d entries %3d%% unref",
+          casGauge(c.Fill()), int(c.Fill() * 100),
+          humanize.Bytes(uint64(c.Size)), humanize.Bytes(uint64(c.MaxSize)),
+          c.Entries, int(c.UnreferencedFill() * 100))
+      if t.Previous != nil {
+        row += fmt.Sprintf(" %10s evicting %s", perSecond(t.Growth(), true), perSecond(t.Evictions(), false))
+      }
+    }
+    if t.Err != nil {
+      row += fmt.Sprintf(" [%v](fg:red)", t.Err)
+    }
+    rows = append(rows, row)
+  }
+  v.list.Rows = makeStringers(rows)
+  if v.list.SelectedRow >= len(rows) {
+    v.list.SelectedRow = Max(len(rows) - 1, 0)
+  }
+  title := fmt.Sprintf("CAS of %d workers, fullest first", len(v.rows))
+  if full > 0 {
+    title += fmt.Sprintf(", [%d at least %d%% full](fg:red)", full, int(casFull * 100))
+  }
+  if v.err != nil {
+    title += fmt.Sprintf(" [%v](fg:red)", v.err)
+  }
+  v.list.Title = title
+  setRect(v.list, area)
+  return []ui.Drawable { v.list }
+}
@@ -1,6 +1,8 @@
 package view
 
 import (
+  "image"
+
   reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
   bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
   ui "github.com/gizak/termui/v3"
@@ -43,11 +45,11 @@ func (v *commandView) Update() {
   }
 }
 
-func (v commandView) Render() []ui.Drawable {
+func (v commandView) Render(area image.Rectangle) []ui.Drawable {
   p := widgets.NewParagraph()
   p.Title = client.DigestString(v.d)
   p.WrapText = true
-  p.SetRect(0, 0, 120, 60)
+  setRect(p, area)
   if v.err != nil {
     p.Text = string(v.err.Error())
   } else {
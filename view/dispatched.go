@@ -0,0 +1,283 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+  "sort"
+  "strings"
+  "sync"
+  "time"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// operations fetched for their worker on each update, at most
+const dispatchedFetches = 100
+
+type dispatchedView struct {
+  a *client.App
+  v View
+  dispatched []*client.Dispatched
+  err error
+  // message reports a refused action, until the next key
+  message string
+  reversed bool
+  list *client.List
+  detail *client.Paragraph
+}
+
+// NewDispatched browses the dispatched hash straight from redis, for the
+// operations workers hold and when they are due to be requeued
+func NewDispatched(a *client.App, v View) View {
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  detail := client.NewParagraph()
+  detail.Title = "Dispatched Operation"
+  return &dispatchedView {
+    a: a,
+    v: v,
+    list: list,
+    detail: detail,
+  }
+}
+
+// requeue offers the prequeue and the provisions as destinations for the
+// dispatched operation name
+func requeue(a *client.App, name string, v View) (View, error) {
+  status, err := client.BackplaneStatus(context.Background(), a.Conn, a.Instance)
+  if err != nil {
+    return nil, err
+  }
+  names := append([]string { status.Prequeue.Name }, client.QueueNames(status, false)...)
+  return newChoice("Requeue " + name + " onto", names, func(i int) (View, error) {
+    ctx := context.Background()
+    to := client.NewQueue(ctx, a.Client, names[i])
+    commands, err := client.RequeueDispatched(ctx, a.Client, name, to, i == 0)
+    if err != nil {
+      return nil, err
+    }
+    return newConfirm(a, "Requeue " + name, commands, v), nil
+  }, v), nil
+}
+
+// holder is the worker holding a dispatched operation and when it started,
+// as far as the operation has been fetched
+func holder(a *client.App, name string) (string, time.Time) {
+  a.Mutex.Lock()
+  o, ok := a.Ops[name]
+  a.Mutex.Unlock()
+  if !ok || o == nil || o.Metadata == nil {
+    return "", time.Time{}
+  }
+  eam, err := client.ExecutedActionMetadata(o)
+  if err != nil {
+    return "", time.Time{}
+  }
+  var start time.Time
+  if eam.WorkerStartTimestamp != nil {
+    start = eam.WorkerStartTimestamp.AsTime()
+  }
+  return eam.Worker, start
+}
+
+func deadline(d *client.Dispatched, now time.Time) string {
+  switch {
+  case d.Err != nil:
+    return fmt.Sprintf("[%v](fg:red)", d.Err)
+  case d.RequeueAt.IsZero():
+    return "no deadline"
+  case d.Overdue(now):
+    return fmt.Sprintf("[overdue %s](fg:red,mod:bold)", now.Sub(d.RequeueAt).Truncate(time.Second))
+  }
+  return fmt.Sprintf("requeue in %s", d.RequeueAt.Sub(now).Truncate(time.Second))
+}
+
+type dispatchedRow struct {
+  v *dispatchedView
+  d *client.Dispatched
+  now time.Time
+}
+
+func (r dispatchedRow) String() string {
+  worker, start := holder(r.v.a, r.d.Name)
+  if worker == "" {
+    worker = "?"
+  }
+  return fmt.Sprintf("%-24s %8s  %s  %s", worker, age(start, r.now), deadline(r.d, r.now), r.d.Name)
+}
+
+func (v *dispatchedView) renderDetail(d *client.Dispatched) string {
+  var lines []string
+  field := func(name string, value interface{}) {
+    lines = append(lines, fmt.Sprintf("[%s:](mod:bold) %v", name, value))
+  }
+  now := v.a.Now()
+  field("Operation", d.Name + " [(enter)](fg:blue)")
+  worker, start := holder(v.a, d.Name)
+  if worker != "" {
+    field("Worker", worker + " [(w)](fg:blue)")
+  }
+  if !start.IsZero() {
+    field("Dispatched", fmt.Sprintf("%s (%s ago)", formatTime(start), age(start, now)))
+  }
+  if !d.RequeueAt.IsZero() {
+    field("Requeue At", fmt.Sprintf("%s (%s)", formatTime(d.RequeueAt), deadline(d, now)))
+  }
+  if d.Err != nil {
+    field("Error", d.Err)
+  }
+  if qe := d.QueueEntry; qe != nil && qe.ExecuteEntry != nil {
+    if t := queuedTime(qe); !t.IsZero() {
+      field("Queued", fmt.Sprintf("%s (%s ago)", formatTime(t), age(t, now)))
+    }
+    field("Requeue Attempts", qe.RequeueAttempts)
+    if m := qe.ExecuteEntry.RequestMetadata; m != nil {
+      field("Tool Invocation", m.ToolInvocationId)
+      field("Target", m.TargetId)
+      field("Mnemonic", m.ActionMnemonic)
+    }
+  }
+  return strings.Join(lines, "\n")
+}
+
+func (v *dispatchedView) selected() *client.Dispatched {
+  if v.list.SelectedRow < 0 || v.list.SelectedRow >= len(v.dispatched) {
+    return nil
+  }
+  return v.dispatched[v.list.SelectedRow]
+}
+
+func (v *dispatchedView) Handle(e ui.Event) View {
+  v.message = ""
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.list.ScrollDown()
+  case "k", "<Up>":
+    v.list.ScrollUp()
+  case "J", "<PageDown>":
+    v.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.list.ScrollPageUp()
+  case "<Home>":
+    v.list.ScrollTop()
+  case "<End>":
+    v.list.ScrollBottom()
+  case ">", "<":
+    v.reversed = !v.reversed
+  case "<Enter>":
+    if d := v.selected(); d != nil {
+      ui.Clear()
+      return NewOperation(v.a, d.Name, v)
+    }
+  case "w":
+    if d := v.selected(); d != nil {
+      worker, _ := holder(v.a, d.Name)
+      if worker == "" {
+        v.message = "the worker of " + d.Name + " is not known yet"
+        return v
+      }
+      ui.Clear()
+      return NewWorker(v.a, worker, v)
+    }
+  case "R":
+    if d := v.selected(); d != nil {
+      next, err := requeue(v.a, d.Name, v)
+      if err != nil {
+        v.message = err.Error()
+        return v
+      }
+      ui.Clear()
+      return next
+    }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.list.RowAt(p); row != -1 {
+      v.list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
+  }
+  return v
+}
+
+func (v *dispatchedView) Update() {
+  v.a.Fetches++
+  dispatched, err := client.ScanDispatched(context.Background(), v.a.Client, 1000)
+  // the earliest deadlines, overdue first
+  sort.SliceStable(dispatched, func(i, j int) bool {
+    if v.reversed {
+      i, j = j, i
+    }
+    return dispatched[i].RequeueAt.Before(dispatched[j].RequeueAt)
+  })
+  v.dispatched, v.err = dispatched, err
+  fetchHolders(v.a, dispatched)
+}
+
+// fetchHolders fetches the operations whose workers are not yet known, the
+// worker coming from the operation
+func fetchHolders(a *client.App, dispatched []*client.Dispatched) {
+  // chosen before any fetch starts writing Ops
+  var names []string
+  for _, d := range dispatched {
+    if len(names) == dispatchedFetches {
+      break
+    }
+    if worker, _ := holder(a, d.Name); worker == "" {
+      names = append(names, d.Name)
+    }
+  }
+  var wg sync.WaitGroup
+  for _, name := range names {
+    a.Fetches++
+    wg.Add(1)
+    go getExecution(a, name, a.Conn, &wg)
+  }
+  wg.Wait()
+}
+
+func (v *dispatchedView) Render(area image.Rectangle) []ui.Drawable {
+  now := v.a.Now()
+  overdue := 0
+  rows := make([]fmt.Stringer, len(v.dispatched))
+  for i, d := range v.dispatched {
+    if d.Overdue(now) {
+      overdue++
+    }
+    rows[i] = dispatchedRow { v: v, d: d, now: now }
+  }
+  v.list.Rows = rows
+  if v.list.SelectedRow >= len(rows) {
+    v.list.SelectedRow = Max(len(rows) - 1, 0)
+  }
+  v.list.Title = fmt.Sprintf("Dispatched Operations %d", len(rows))
+  if overdue > 0 {
+    v.list.Title += fmt.Sprintf(" [(%d overdue)](fg:red)", overdue)
+  }
+  if v.err != nil {
+    v.list.Title += " (" + v.err.Error() + ")"
+  }
+  if v.message != "" {
+    v.list.Title += " [" + v.message + "](fg:red)"
+  }
+  if d := v.selected(); d != nil {
+    v.detail.Text = v.renderDetail(d)
+  } else {
+    v.detail.Text = ""
+  }
+
+  rects := vsplit(area, 0, 11)
+  setRect(v.list, rects[0])
+  setRect(v.detail, rects[1])
+  return []ui.Drawable { v.list, v.detail }
+}
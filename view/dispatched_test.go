@@ -0,0 +1,36 @@
+package view
+
+import (
+  "context"
+  "testing"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func TestFetchHolders(t *testing.T) {
+  s, a := faketest.Start(t)
+  w, err := s.AddWorker(2)
+  if err != nil {
+    t.Fatal(err)
+  }
+  faketest.Submit(t, s, "a", "//a:1")
+  faketest.Submit(t, s, "a", "//a:2")
+  // into the queue, then onto the worker one at a time
+  for i := 0; i < 3; i++ {
+    s.Step()
+  }
+  dispatched, err := client.ScanDispatched(context.Background(), a.Client, 100)
+  if err != nil {
+    t.Fatal(err)
+  }
+  if len(dispatched) != 2 {
+    t.Fatalf("%d operations dispatched, want 2", len(dispatched))
+  }
+
+  fetchHolders(a, dispatched)
+  for _, d := range dispatched {
+    if worker, _ := holder(a, d.Name); worker != w.Name {
+      t.Errorf("%s is held by %q, want %s", d.Name, worker, w.Name)
+    }
+  }
+}
@@ -4,6 +4,7 @@ import (
   "context"
   "fmt"
   "errors"
+  "image"
   "strings"
   "time"
   bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
@@ -85,7 +86,7 @@ func NewDocument(a *client.App, name string, v View) *document {
   }
 }
 
-func (d document) Render() []ui.Drawable {
+func (d document) Render(area image.Rectangle) []ui.Drawable {
   ui.Clear()
   d.p.Title = d.d.Title()
   if d.source {
@@ -93,7 +94,7 @@ func (d document) Render() []ui.Drawable {
   } else {
     d.p.Text = d.d.Render()
   }
-  d.p.SetRect(0, 0, 120, 60)
+  setRect(d.p, area)
   return []ui.Drawable { d.p }
 }
 
@@ -226,7 +227,7 @@ func (n node) frag(f string) node {
   return n
 }
 
-func updateExecutedActionMetadata(em *reapi.ExecutedActionMetadata) node {
+func updateExecutedActionMetadata(em *reapi.ExecutedActionMetadata, now time.Time) node {
   root := div().id("executed-metadata")
   root.appendNode(h2().text("Execution Details"))
   ul := ul().id("execution-details")
@@ -301,7 +302,7 @@ func updateExecutedActionMetadata(em *reapi.ExecutedActionMetadata) node {
     if ifct.Compare(qt) > 0 {
       ul.append(fmt.Sprintf("Input Fetch complete: %s elapsed, took %s", ifct.Sub(qt).String(), ifct.Sub(ifst).String()))
     } else {
-      ul.append(fmt.Sprintf("Input Fetch running for %s", time.Now().Sub(wst).String()))
+      ul.append(fmt.Sprintf("Input Fetch running for %s", now.Sub(wst).String()))
     }
   }
   var estall time.Duration
@@ -311,10 +312,10 @@ func updateExecutedActionMetadata(em *reapi.ExecutedActionMetadata) node {
     if ect.Compare(qt) > 0 {
       ul.append(fmt.Sprintf("Execute Complete: %s elapsed, took %s", ect.Sub(qt).String(), ect.Sub(est).String()))
     } else {
-      ul.append(fmt.Sprintf("Execute Running for %s", time.Now().Sub(est).String()))
+      ul.append(fmt.Sprintf("Execute Running for %s", now.Sub(est).String()))
     }
   } else if (ifct.Compare(qt) > 0) {
-    ul.append(fmt.Sprintf("Execute Stalled for %s", time.Now().Sub(ifct).String()))
+    ul.append(fmt.Sprintf("Execute Stalled for %s", now.Sub(ifct).String()))
   }
   var oustall time.Duration
   if oust.Compare(ect) > 0 {
@@ -323,10 +324,10 @@ func updateExecutedActionMetadata(em *reapi.ExecutedActionMetadata) node {
     if ouct.Compare(qt) > 0 {
       ul.append(fmt.Sprintf("Output Upload Complete: %s elapsed, took %s", ouct.Sub(qt).String(), ouct.Sub(oust).String()))
     } else {
-      ul.append(fmt.Sprintf("Output Upload Running for %s", time.Now().Sub(oust).String()))
+      ul.append(fmt.Sprintf("Output Upload Running for %s", now.Sub(oust).String()))
     }
   } else if (oust.Compare(qt) > 0) {
-    ul.append(fmt.Sprintf("Output Upload Stalled for %s", time.Now().Sub(ect).String()))
+    ul.append(fmt.Sprintf("Output Upload Stalled for %s", now.Sub(ect).String()))
   }
   if wct.Compare(qt) > 0 {
     ul.append(fmt.Sprintf(
@@ -345,7 +346,7 @@ func renderDigest(d reapi.Digest, df reapi.DigestFunction_Value) string {
   })
 }
 
-func updateExecuteOperationMetadata(n *html.Node, em *reapi.ExecuteOperationMetadata) {
+func updateExecuteOperationMetadata(n *html.Node, em *reapi.ExecuteOperationMetadata, now time.Time) {
   content := `
   <div id="stage">Stage: <span id="stage">%s</span></div>
   <div>Action: <a id="action" href="action:%[2]s">%[2]s</a></div>
@@ -355,12 +356,12 @@ func updateExecuteOperationMetadata(n *html.Node, em *reapi.ExecuteOperationMeta
   replaceNodeContent(content, n)
   if em.PartialExecutionMetadata != nil {
     // single element return
-    n.LastChild.NextSibling = updateExecutedActionMetadata(em.PartialExecutionMetadata).node
+    n.LastChild.NextSibling = updateExecutedActionMetadata(em.PartialExecutionMetadata, now).node
     n.LastChild = n.LastChild.NextSibling
   }
 }
 
-func updateActionResult(n *html.Node, ar *reapi.ActionResult, df reapi.DigestFunction_Value) {
+func updateActionResult(n *html.Node, ar *reapi.ActionResult, df reapi.DigestFunction_Value, now time.Time) {
   el := node { node: n }
   e := "success"
   if ar.ExitCode != 0 {
@@ -387,7 +388,7 @@ func updateActionResult(n *html.Node, ar *reapi.ActionResult, df reapi.DigestFun
     digest := renderDigest(*od.TreeDigest, df)
     el.li().frag(fmt.Sprintf(`directory: <a href="directory:%[2]s">%[1]s (%[2]s)</a>`, od.Path, digest))
   }
-  el.li().appendNode(updateExecutedActionMetadata(ar.ExecutionMetadata))
+  el.li().appendNode(updateExecutedActionMetadata(ar.ExecutionMetadata, now))
 }
 
 /*
@@ -399,12 +400,12 @@ func statusClass(s *status.Status) string {
 }
 */
 
-func updateExecuteResponse(n *html.Node, er *reapi.ExecuteResponse, df reapi.DigestFunction_Value) {
+func updateExecuteResponse(n *html.Node, er *reapi.ExecuteResponse, df reapi.DigestFunction_Value, now time.Time) {
   el := node { node: n }
   if er.Result != nil {
     ar := ul()
     el.appendNode(ar)
-    updateActionResult(ar.node, er.Result, df)
+    updateActionResult(ar.node, er.Result, df, now)
   }
   s := proto.MarshalTextString(er.Status)
   if len(s) > 0 {
@@ -443,7 +444,7 @@ func (d *document) Update() {
     if err != nil {
       panic(err)
     }
-    updateExecuteOperationMetadata(d.em, em)
+    updateExecuteOperationMetadata(d.em, em, d.a.Now())
     qm := &bfpb.QueuedOperationMetadata{}
     m := d.op.Metadata
     if ptypes.Is(d.op.Metadata, qm) {
@@ -465,7 +466,7 @@ func (d *document) Update() {
         if err := ptypes.UnmarshalAny(r.Response, er); err != nil {
           panic(err)
         }
-        updateExecuteResponse(d.r, er, df)
+        updateExecuteResponse(d.r, er, df, d.a.Now())
       }
     }
 
@@ -572,6 +573,27 @@ func (d *document) Handle(e ui.Event) View {
   case "<Escape>", "q", "<C-c>":
     ui.Clear()
     return d.v
+  case "<MouseLeft>":
+    if d.source || d.p.Raw {
+      return d
+    }
+    if i := anchorAt(d.d, d.p, d.anchors, mousePoint(e)); i != -1 {
+      defocus(d.anchors[d.focusAnchor])
+      d.focusAnchor = i
+      focus(d.anchors[d.focusAnchor])
+      return d.Handle(enterEvent)
+    }
   }
   return d
 }
+
+// anchorAt returns the index in anchors of the link drawn at p, or -1
+func anchorAt(d *client.Document, p *client.Paragraph, anchors []*html.Node, pt image.Point) int {
+  a := d.AnchorAt(p.CellAt(pt))
+  for i, anchor := range anchors {
+    if anchor == a {
+      return i
+    }
+  }
+  return -1
+}
@@ -0,0 +1,210 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+  "strings"
+  "time"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// drains are checked at most this often
+const drainInterval = time.Second
+
+// width of a drain's progress bar
+const drainBar = 20
+
+type drainView struct {
+  a *client.App
+  v View
+  drains []*client.Drain
+  // timeout before operations left over are cancelled, never when zero
+  timeout time.Duration
+  cancelled bool
+  // started is when the first check paused matching
+  started time.Time
+  last time.Time
+  message string
+  list *client.List
+}
+
+// drain asks how long the workers may take before the operations they still
+// hold are cancelled, then drains them
+func drain(a *client.App, workers []string, v View) View {
+  title := fmt.Sprintf("Drain %d workers, cancelling operations left after (empty to never)", len(workers))
+  return newPrompt(title, "", func(text string) (View, error) {
+    var timeout time.Duration
+    if text = strings.TrimSpace(text); text != "" {
+      var err error
+      if timeout, err = time.ParseDuration(text); err != nil {
+        return nil, err
+      }
+    }
+    return NewDrain(a, workers, timeout, v), nil
+  }, v)
+}
+
+// NewDrain pauses matching on the workers and follows the operations they
+// hold until each is idle and safe to stop
+func NewDrain(a *client.App, workers []string, timeout time.Duration, v View) View {
+  var drains []*client.Drain
+  for _, worker := range workers {
+    drains = append(drains, client.NewDrain(worker))
+  }
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  return &drainView {
+    a: a,
+    v: v,
+    drains: drains,
+    timeout: timeout,
+    list: list,
+  }
+}
+
+func (v *drainView) selected() *client.Drain {
+  if v.list.SelectedRow < 0 || v.list.SelectedRow >= len(v.drains) {
+    return nil
+  }
+  return v.drains[v.list.SelectedRow]
+}
+
+// cancel cancels the operations left on every worker still draining
+func (v *drainView) cancel() {
+  v.cancelled = true
+  for _, d := range v.drains {
+    if d.Idle() {
+      continue
+    }
+    if err := d.CancelStragglers(context.Background(), v.a.Conn); err != nil {
+      v.message = err.Error()
+    }
+  }
+}
+
+func (v *drainView) Handle(e ui.Event) View {
+  v.message = ""
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.list.ScrollDown()
+  case "k", "<Up>":
+    v.list.ScrollUp()
+  case "J", "<PageDown>":
+    v.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.list.ScrollPageUp()
+  case "c":
+    v.cancel()
+  case "R":
+    // put the workers back in service
+    for _, d := range v.drains {
+      if err := d.Resume(context.Background(), v.a); err != nil {
+        v.message = err.Error()
+      }
+    }
+    ui.Clear()
+    return v.v
+  case "<Enter>":
+    if d := v.selected(); d != nil {
+      ui.Clear()
+      return NewWorker(v.a, d.Worker, v)
+    }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.list.RowAt(p); row != -1 {
+      v.list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
+  }
+  return v
+}
+
+func (v *drainView) Update() {
+  if time.Since(v.last) < drainInterval {
+    return
+  }
+  v.last = time.Now()
+  if v.started.IsZero() {
+    v.started = v.last
+  }
+  v.a.Fetches += uint(len(v.drains))
+  client.CheckDrains(v.a, v.drains)
+  if v.timeout > 0 && !v.cancelled && time.Since(v.started) > v.timeout {
+    v.cancel()
+  }
+}
+
+func progressBar(fraction float64) string {
+  done := int(fraction * drainBar)
+  return "[" + strings.Repeat("#", done) + "](fg:green)[" + strings.Repeat("-", drainBar - done) + "](fg:white,mod:dim)"
+}
+
+func renderDrain(d *client.Drain, width int) string {
+  name := d.Worker + strings.Repeat(" ", Max(width - len(d.Worker), 0))
+  row := fmt.Sprintf("%s %s", name, progressBar(d.Progress()))
+  switch {
+  case d.Err != nil:
+    row += fmt.Sprintf(" [%v](fg:red)", d.Err)
+  case d.Idle():
+    row += " [idle, safe to stop](fg:green,mod:bold)"
+  default:
+    row += fmt.Sprintf(" %d of %d left", d.Remaining, d.Initial)
+    var stages []string
+    for _, stage := range []string { "InputFetchStage", "ExecuteActionStage", "ReportResultStage" } {
+      if n := len(d.Operations[stage]); n > 0 {
+        stages = append(stages, fmt.Sprintf("%s %d", strings.TrimSuffix(stage, "Stage"), n))
+      }
+    }
+    if len(stages) > 0 {
+      row += " (" + strings.Join(stages, ", ") + ")"
+    }
+  }
+  if d.Cancelled > 0 {
+    row += fmt.Sprintf(" [%d cancelled](fg:yellow)", d.Cancelled)
+  }
+  return row
+}
+
+func (v *drainView) Render(area image.Rectangle) []ui.Drawable {
+  width, idle := 0, 0
+  for _, d := range v.drains {
+    width = Max(width, len(d.Worker))
+    if d.Idle() {
+      idle++
+    }
+  }
+  var rows []string
+  for _, d := range v.drains {
+    rows = append(rows, renderDrain(d, width))
+  }
+  v.list.Rows = makeStringers(rows)
+  if v.list.SelectedRow >= len(rows) {
+    v.list.SelectedRow = Max(len(rows) - 1, 0)
+  }
+  title := fmt.Sprintf("Draining %d workers, %d idle", len(v.drains), idle)
+  if idle == len(v.drains) && idle > 0 {
+    title = fmt.Sprintf("[Drained %d workers, safe to stop](fg:green)", idle)
+  } else if v.timeout > 0 && !v.cancelled && !v.started.IsZero() {
+    left := v.timeout - time.Since(v.started)
+    title += fmt.Sprintf(", cancelling the rest in %s", left.Truncate(time.Second))
+  }
+  title += ", (c) cancel the rest, (R) resume matching"
+  if v.message != "" {
+    title += " [" + v.message + "](fg:red)"
+  }
+  v.list.Title = title
+  setRect(v.list, area)
+  return []ui.Drawable { v.list }
+}
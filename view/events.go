@@ -0,0 +1,256 @@
+package view
+
+import (
+  "fmt"
+  "image"
+  "sort"
+  "strings"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+var eventFilterFields = []string{"toolInvocationId", "actionMnemonic", "worker", "state"}
+
+var stateColors = map[string]string {
+  "queued": "yellow",
+  "dispatched": "cyan",
+  "executing": "blue",
+  "completed": "green",
+  "expired": "magenta",
+}
+
+// events kept by a feed
+const feedLimit = 10000
+
+type eventsView struct {
+  a *client.App
+  v View
+  // the events received since opening, oldest first, as are those shown
+  events []*client.OperationEvent
+  shown []*client.OperationEvent
+  next uint64
+  filters map[string]string
+  paused bool
+  message string
+  list *client.List
+}
+
+// NewEvents streams the operation changes published by the backplane as
+// they happen
+func NewEvents(a *client.App, v View) View {
+  a.Watch()
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  return &eventsView {
+    a: a,
+    v: v,
+    filters: make(map[string]string),
+    list: list,
+  }
+}
+
+func eventField(ev *client.OperationEvent, field string) string {
+  switch field {
+  case "worker": return ev.Worker
+  case "state": return ev.State
+  }
+  if ev.Metadata == nil {
+    return ""
+  }
+  switch field {
+  case "toolInvocationId": return ev.Metadata.ToolInvocationId
+  case "actionMnemonic": return ev.Metadata.ActionMnemonic
+  }
+  return ""
+}
+
+func (v *eventsView) matches(ev *client.OperationEvent) bool {
+  for field, value := range v.filters {
+    if eventField(ev, field) != value {
+      return false
+    }
+  }
+  return true
+}
+
+type eventRow struct {
+  ev *client.OperationEvent
+}
+
+func (r eventRow) String() string {
+  ev := r.ev
+  if ev.Err != nil {
+    return fmt.Sprintf("%s [undecodable change of %s: %v](fg:red)", ev.Time.Format("15:04:05.000"), ev.Name, ev.Err)
+  }
+  state := ev.State
+  if color, ok := stateColors[state]; ok {
+    state = fmt.Sprintf("[%-10s](fg:%s)", state, color)
+  } else {
+    state = fmt.Sprintf("%-10s", state)
+  }
+  row := fmt.Sprintf("%s %s %s", ev.Time.Format("15:04:05.000"), state, ev.Name)
+  if m := ev.Metadata; m != nil {
+    row += fmt.Sprintf("  %s %s", m.ActionMnemonic, m.TargetId)
+  }
+  if ev.Worker != "" {
+    row += "  on " + ev.Worker
+  }
+  return row
+}
+
+// selected is the event of the selected row, the newest at the top
+func (v *eventsView) selected() *client.OperationEvent {
+  if v.list.SelectedRow < 0 || v.list.SelectedRow >= len(v.shown) {
+    return nil
+  }
+  return v.shown[len(v.shown) - 1 - v.list.SelectedRow]
+}
+
+// filter sets one field=value filter, an empty value clearing it
+func (v *eventsView) filter(text string) (View, error) {
+  text = strings.TrimSpace(text)
+  i := strings.Index(text, "=")
+  if i == -1 {
+    return nil, fmt.Errorf("expected field=value, with a field of %s", strings.Join(eventFilterFields, ", "))
+  }
+  field, value := text[:i], text[i + 1:]
+  for _, f := range eventFilterFields {
+    if f == field {
+      if value == "" {
+        delete(v.filters, field)
+      } else {
+        v.filters[field] = value
+      }
+      v.refilter()
+      return v, nil
+    }
+  }
+  return nil, fmt.Errorf("unknown field %s, expected one of %s", field, strings.Join(eventFilterFields, ", "))
+}
+
+func (v *eventsView) refilter() {
+  v.shown = nil
+  for _, ev := range v.events {
+    if v.matches(ev) {
+      v.shown = append(v.shown, ev)
+    }
+  }
+  v.list.SelectedRow = 0
+}
+
+func (v *eventsView) Handle(e ui.Event) View {
+  v.message = ""
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.list.ScrollDown()
+  case "k", "<Up>":
+    v.list.ScrollUp()
+  case "J", "<PageDown>":
+    v.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.list.ScrollPageUp()
+  case "<Home>":
+    v.list.ScrollTop()
+  case "<End>":
+    v.list.ScrollBottom()
+  case "p", "<Space>":
+    v.paused = !v.paused
+  case "f":
+    return newPrompt("Filter events by field=value, empty to clear", "toolInvocationId=", v.filter, v)
+  case "c":
+    v.filters = make(map[string]string)
+    v.refilter()
+  case "<Enter>":
+    if ev := v.selected(); ev != nil {
+      ui.Clear()
+      return NewOperation(v.a, ev.Name, v)
+    }
+  case "w":
+    if ev := v.selected(); ev != nil {
+      if ev.Worker == "" {
+        v.message = ev.Name + " has no worker yet"
+        return v
+      }
+      ui.Clear()
+      return NewWorker(v.a, ev.Worker, v)
+    }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.list.RowAt(p); row != -1 {
+      v.list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
+  }
+  return v
+}
+
+func (v *eventsView) Update() {
+  if v.paused || v.a.Events == nil {
+    return
+  }
+  var events []*client.OperationEvent
+  events, v.next = v.a.Events.Since(v.next)
+  added := 0
+  for _, ev := range events {
+    v.events = append(v.events, ev)
+    if v.matches(ev) {
+      v.shown = append(v.shown, ev)
+      added++
+    }
+  }
+  if len(v.events) > feedLimit {
+    v.events = v.events[len(v.events) - feedLimit:]
+  }
+  if len(v.shown) > feedLimit {
+    v.shown = v.shown[len(v.shown) - feedLimit:]
+  }
+  // the selected event stays selected as newer ones arrive above it
+  if v.list.SelectedRow > 0 {
+    v.list.SelectedRow += added
+  }
+}
+
+func (v *eventsView) Render(area image.Rectangle) []ui.Drawable {
+  rows := make([]fmt.Stringer, len(v.shown))
+  for i, ev := range v.shown {
+    rows[len(rows) - 1 - i] = eventRow { ev: ev }
+  }
+  v.list.Rows = rows
+  if v.list.SelectedRow >= len(rows) {
+    v.list.SelectedRow = Max(len(rows) - 1, 0)
+  }
+
+  title := fmt.Sprintf("Operation Events %d", len(v.shown))
+  if len(v.filters) > 0 {
+    var filters []string
+    for field, value := range v.filters {
+      filters = append(filters, field + "=" + value)
+    }
+    sort.Strings(filters)
+    title += " (" + strings.Join(filters, ", ") + ")"
+  }
+  switch {
+  case v.paused:
+    title += " (Paused)"
+  case v.a.Events.Err() != nil:
+    title += fmt.Sprintf(" [not subscribed: %v](fg:red)", v.a.Events.Err())
+  case !v.a.Events.Live():
+    title += " [not subscribed](fg:yellow)"
+  }
+  if v.message != "" {
+    title += " [" + v.message + "](fg:red)"
+  }
+  v.list.Title = title
+  setRect(v.list, area)
+  return []ui.Drawable { v.list }
+}
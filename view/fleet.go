@@ -0,0 +1,258 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+  "path"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// changePipelines offers a pause, resume or resize of each stage for the
+// workers, the chosen change then being confirmed for all of them
+func changePipelines(a *client.App, workers []string, v View) View {
+  var labels []string
+  var actions []client.PipelineAction
+  for _, stage := range client.Stages {
+    labels = append(labels, "pause " + stage, "resume " + stage, "resize " + stage)
+    actions = append(actions,
+      client.PipelineAction { Stage: stage, Paused: true },
+      client.PipelineAction { Stage: stage },
+      client.PipelineAction { Stage: stage, Width: -1 })
+  }
+  title := fmt.Sprintf("Change the pipeline of %d workers", len(workers))
+  return newChoice(title, labels, func(i int) (View, error) {
+    action := actions[i]
+    if action.Width == 0 {
+      return newPipelineConfirm(a, workers, action, v), nil
+    }
+    return newPrompt("Width of " + action.Stage + " on each worker", "", func(text string) (View, error) {
+      width, err := strconv.Atoi(strings.TrimSpace(text))
+      if err != nil || width < 1 {
+        return nil, fmt.Errorf("expected a width of at least 1")
+      }
+      action.Width = int32(width)
+      return newPipelineConfirm(a, workers, action, v), nil
+    }, v), nil
+  }, v)
+}
+
+// marked are the workers marked in the meter, or the one selected
+func (v *Queue) marked() []string {
+  var workers []string
+  for worker := range v.marks {
+    workers = append(workers, worker)
+  }
+  sort.Strings(workers)
+  if worker := v.selectedWorker(); len(workers) == 0 && worker != "" {
+    workers = append(workers, worker)
+  }
+  return workers
+}
+
+// platforms gathers the platform properties of the operations each worker
+// holds, from the dispatched hash, as name=value
+func (v *Queue) platforms() (map[string]map[string]bool, error) {
+  v.a.Fetches++
+  dispatched, err := client.ScanDispatched(context.Background(), v.a.Client, 1000)
+  if err != nil {
+    return nil, err
+  }
+  properties := make(map[string][]string)
+  for _, d := range dispatched {
+    if qe := d.QueueEntry; qe != nil && qe.Platform != nil {
+      for _, p := range qe.Platform.Properties {
+        properties[d.Name] = append(properties[d.Name], p.Name + "=" + p.Value)
+      }
+    }
+  }
+  platforms := make(map[string]map[string]bool)
+  v.s.mutex.Lock()
+  defer v.s.mutex.Unlock()
+  for worker, r := range v.s.profiles {
+    platforms[worker] = make(map[string]bool)
+    for _, stage := range r.profile.Stages {
+      for _, name := range stage.OperationNames {
+        for _, p := range properties[name] {
+          platforms[worker][p] = true
+        }
+      }
+    }
+  }
+  return platforms, nil
+}
+
+// groupPlatforms labels each worker with the platforms of the operations
+// it holds, for the meter to group them by
+func (v *Queue) groupPlatforms() {
+  if time.Since(v.s.platformsAt) < platformsInterval {
+    return
+  }
+  v.s.platformsAt = time.Now()
+  platforms, err := v.platforms()
+  if err != nil {
+    return
+  }
+  labels := make(map[string]string)
+  for worker, properties := range platforms {
+    var names []string
+    for p := range properties {
+      names = append(names, p)
+    }
+    sort.Strings(names)
+    labels[worker] = strings.Join(names, ",")
+  }
+  v.s.mutex.Lock()
+  v.s.platforms = labels
+  v.s.mutex.Unlock()
+}
+
+// matchWorkers finds the workers named by name=glob, or those holding
+// operations with a platform=property=glob, idle workers having no platform
+func (v *Queue) matchWorkers(text string) ([]string, error) {
+  text = strings.TrimSpace(text)
+  field, pattern, ok := strings.Cut(text, "=")
+  if !ok || field != "name" && field != "platform" {
+    return nil, fmt.Errorf("expected name=glob or platform=property=glob")
+  }
+  var platforms map[string]map[string]bool
+  if field == "platform" {
+    if !strings.Contains(pattern, "=") {
+      return nil, fmt.Errorf("expected platform=property=glob")
+    }
+    var err error
+    if platforms, err = v.platforms(); err != nil {
+      return nil, err
+    }
+  }
+  if _, err := path.Match(pattern, ""); err != nil {
+    return nil, err
+  }
+  var workers []string
+  v.s.mutex.Lock()
+  defer v.s.mutex.Unlock()
+  for worker, r := range v.s.profiles {
+    matched := false
+    if field == "name" {
+      m1, _ := path.Match(pattern, worker)
+      m2, _ := path.Match(pattern, r.profile.Name)
+      matched = m1 || m2
+    } else {
+      for p := range platforms[worker] {
+        if m, _ := path.Match(pattern, p); m {
+          matched = true
+        }
+      }
+    }
+    if matched {
+      workers = append(workers, worker)
+    }
+  }
+  if len(workers) == 0 {
+    return nil, fmt.Errorf("no workers match %s", text)
+  }
+  sort.Strings(workers)
+  return workers, nil
+}
+
+type pipelineRow struct {
+  worker string
+  state string
+}
+
+// pipelineConfirm previews a change to the pipelines of workers, applying
+// it to all of them at once only when confirmed
+type pipelineConfirm struct {
+  a *client.App
+  v View
+  action client.PipelineAction
+  rows []*pipelineRow
+  failed int
+  done bool
+  list *client.List
+}
+
+func newPipelineConfirm(a *client.App, workers []string, action client.PipelineAction, v View) View {
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  var rows []*pipelineRow
+  for _, worker := range workers {
+    rows = append(rows, &pipelineRow { worker: worker })
+  }
+  return &pipelineConfirm {
+    a: a,
+    v: v,
+    action: action,
+    rows: rows,
+    list: list,
+  }
+}
+
+func (c *pipelineConfirm) apply() {
+  var workers []string
+  for _, row := range c.rows {
+    workers = append(workers, row.worker)
+  }
+  for i, err := range client.ChangePipelines(c.a, workers, c.action) {
+    if err != nil {
+      c.rows[i].state = fmt.Sprintf("[failed: %v](fg:red)", err)
+      c.failed++
+    } else {
+      c.rows[i].state = "[ok](fg:green)"
+    }
+  }
+  c.done = true
+}
+
+func (c *pipelineConfirm) Handle(e ui.Event) View {
+  switch e.ID {
+  case "<Escape>", "q", "n", "<C-c>":
+    ui.Clear()
+    return c.v
+  case "y":
+    if !c.done {
+      c.apply()
+    }
+  case "<Enter>":
+    if c.done {
+      ui.Clear()
+      return c.v
+    }
+  case "j", "<Down>":
+    c.list.ScrollDown()
+  case "k", "<Up>":
+    c.list.ScrollUp()
+  case "J", "<PageDown>":
+    c.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    c.list.ScrollPageUp()
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    c.list.ScrollAmount(wheelAmount(e))
+  }
+  return c
+}
+
+func (c *pipelineConfirm) Update() {
+}
+
+func (c *pipelineConfirm) Render(area image.Rectangle) []ui.Drawable {
+  var rows []string
+  for _, row := range c.rows {
+    rows = append(rows, fmt.Sprintf("%s %s on %s", row.state, c.action, row.worker))
+  }
+  c.list.Rows = makeStringers(rows)
+  if c.done {
+    c.list.Title = fmt.Sprintf("%s: applied on %d of %d workers, (enter) to return", c.action, len(c.rows) - c.failed, len(c.rows))
+  } else {
+    c.list.Title = fmt.Sprintf("%s: dry run on %d workers, (y) to apply, (n) to cancel", c.action, len(c.rows))
+  }
+  setRect(c.list, area)
+  return []ui.Drawable { c.list }
+}
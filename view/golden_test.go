@@ -0,0 +1,241 @@
+package view
+
+import (
+  "flag"
+  "fmt"
+  "image"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+
+  ui "github.com/gizak/termui/v3"
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  lru "github.com/hashicorp/golang-lru/v2"
+  "github.com/werkt/bf-client/client"
+  "golang.org/x/net/html"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden frames")
+
+// golden frames are kept here, relative to the package
+var goldenDir = filepath.Join("testdata", "golden")
+
+// scenario renders a view, or the widgets of one, into area without a
+// terminal, for comparison with a golden snapshot
+type scenario struct {
+  name string
+  area image.Rectangle
+  render func(area image.Rectangle) []ui.Drawable
+}
+
+// drive handles each of events in turn, updating and rendering v after
+// every one, and returns the last frame
+func drive(v View, area image.Rectangle, events ...ui.Event) []ui.Drawable {
+  var frame []ui.Drawable
+  for _, e := range events {
+    v = v.Handle(e)
+    v.Update()
+    frame = v.Render(area)
+  }
+  return frame
+}
+
+func key(id string) ui.Event {
+  return ui.Event { Type: ui.KeyboardEvent, ID: id }
+}
+
+func stages(fetch, fetchSlots, execute, executeSlots, report, reportSlots int32) []*bfpb.StageInformation {
+  return []*bfpb.StageInformation {
+    &bfpb.StageInformation { Name: "InputFetchStage", SlotsUsed: fetch, SlotsConfigured: fetchSlots },
+    &bfpb.StageInformation { Name: "ExecuteActionStage", SlotsUsed: execute, SlotsConfigured: executeSlots,
+        OperationNames: make([]string, execute) },
+    &bfpb.StageInformation { Name: "ReportResultStage", SlotsUsed: report, SlotsConfigured: reportSlots },
+  }
+}
+
+func scenarioWorkerRows(area image.Rectangle) []ui.Drawable {
+  profiles := []*profileResult {
+    &profileResult { name: "idle:8981", profile: &bfpb.WorkerProfileMessage { Stages: stages(0, 4, 0, 8, 0, 2) } },
+    &profileResult { name: "busy:8981", profile: &bfpb.WorkerProfileMessage { Stages: stages(2, 4, 5, 8, 1, 2) } },
+    &profileResult { name: "full:8981", profile: &bfpb.WorkerProfileMessage { Stages: stages(4, 4, 8, 8, 2, 2) } },
+    &profileResult { name: "wide:8981", profile: &bfpb.WorkerProfileMessage { Stages: stages(1, 2, 40, 64, 0, 1) } },
+    &profileResult { name: "stale:8981", profile: &bfpb.WorkerProfileMessage { Stages: stages(1, 4, 2, 8, 0, 2) },
+        stale: 3, message: "deadline exceeded" },
+    nil,
+  }
+  l := client.NewList()
+  l.Title = "Workers"
+  for _, view := range []int{0, 1} {
+    for _, p := range profiles {
+      l.Rows = append(l.Rows, renderWorkerRow(p, 10, view))
+    }
+  }
+  setRect(l, area)
+  return []ui.Drawable { l }
+}
+
+func scenarioDocument(area image.Rectangle) []ui.Drawable {
+  root, err := html.Parse(strings.NewReader(`
+  <html>
+    <head><title>shard/executions/0001</title></head>
+    <body>
+      <h2>Request Metadata:</h2>
+      <ul>
+        <li>Tool Invocation: <a href="#">demo-invocation-1</a></li>
+        <li>Target: //demo:GoCompile_1</li>
+      </ul>
+      <div>Stage: <span>EXECUTING</span></div>
+    </body>
+  </html>`))
+  if err != nil {
+    panic(err)
+  }
+  d := client.NewDocument()
+  d.SetRoot(root)
+  d.Update()
+  p := client.NewParagraph()
+  p.Title = d.Title()
+  p.Text = d.Render()
+  setRect(p, area)
+  return []ui.Drawable { p }
+}
+
+func scenarioList(area image.Rectangle) []ui.Drawable {
+  l := client.NewList()
+  l.Title = "List"
+  for i := 0; i < 20; i++ {
+    l.Rows = append(l.Rows, groupResult { name: fmt.Sprintf("row %d", i), count: i * i })
+  }
+  // scrolled to keep the selection visible
+  l.SelectedRow = 12
+  setRect(l, area)
+  return []ui.Drawable { l }
+}
+
+type nodeName string
+
+func (t nodeName) String() string {
+  return string(t)
+}
+
+func scenarioTree(area image.Rectangle) []ui.Drawable {
+  t := client.NewTree()
+  t.Title = "Tree"
+  t.SetNodes([]*client.TreeNode {
+    &client.TreeNode {
+      Value: nodeName("root"),
+      Expanded: true,
+      Nodes: []*client.TreeNode {
+        &client.TreeNode { Value: nodeName("BUILD") },
+        &client.TreeNode {
+          Value: nodeName("src"),
+          Expanded: true,
+          Nodes: []*client.TreeNode {
+            &client.TreeNode { Value: nodeName("main.go") },
+          },
+        },
+        &client.TreeNode {
+          Value: nodeName("collapsed"),
+          Nodes: []*client.TreeNode {
+            &client.TreeNode { Value: nodeName("hidden") },
+          },
+        },
+      },
+    },
+  })
+  t.SelectedRow = 2
+  setRect(t, area)
+  return []ui.Drawable { t }
+}
+
+// scenarioOperations renders a dispatched operation list grouped by field
+func scenarioOperations(field int) func(image.Rectangle) []ui.Drawable {
+  return func(area image.Rectangle) []ui.Drawable {
+    opcache, _ := lru.New[string, operation](16)
+    v := &operationList {
+      a: &client.App{},
+      list: client.NewList(),
+      Name: "executions",
+      mode: 3,
+      opcache: opcache,
+      field: field,
+      grouped: true,
+    }
+    targets := []string{"//a:lib", "//a:lib", "//b:test", "//a:lib", "//c:bin"}
+    mnemonics := []string{"GoCompile", "GoLink", "GoCompile", "GoCompile", "CppLink"}
+    for i, target := range targets {
+      name := fmt.Sprintf("shard/executions/%d", i)
+      v.opNames = append(v.opNames, name)
+      opcache.Add(name, operation {
+        target: target,
+        mnemonic: mnemonics[i],
+        build: fmt.Sprintf("build-%d", i % 2),
+      })
+    }
+    // an operation without metadata
+    v.opNames = append(v.opNames, "shard/executions/unknown")
+    return v.Render(area)
+  }
+}
+
+func scenarioConsole(area image.Rectangle) []ui.Drawable {
+  return drive(NewTest(nil, nil), area, key("a"), key("<Enter>"), key("<C-x>"))
+}
+
+var scenarios = []scenario {
+  scenario { name: "worker-rows", area: image.Rect(0, 0, 72, 14), render: scenarioWorkerRows },
+  scenario { name: "document", area: image.Rect(0, 0, 60, 10), render: scenarioDocument },
+  scenario { name: "list", area: image.Rect(0, 0, 30, 8), render: scenarioList },
+  scenario { name: "tree", area: image.Rect(0, 0, 30, 8), render: scenarioTree },
+  scenario { name: "operations-by-target", area: image.Rect(0, 0, 60, 8), render: scenarioOperations(1) },
+  scenario { name: "operations-by-mnemonic", area: image.Rect(0, 0, 60, 8), render: scenarioOperations(2) },
+  scenario { name: "operations-by-build", area: image.Rect(0, 0, 60, 8), render: scenarioOperations(3) },
+  scenario { name: "console", area: image.Rect(0, 0, 30, 6), render: scenarioConsole },
+}
+
+// diff lists the lines of got that differ from want
+func diff(want string, got string) string {
+  w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+  var out strings.Builder
+  for i := 0; i < len(w) || i < len(g); i++ {
+    var wl, gl string
+    if i < len(w) {
+      wl = w[i]
+    }
+    if i < len(g) {
+      gl = g[i]
+    }
+    if wl != gl {
+      fmt.Fprintf(&out, "  %3d -%s\n  %3d +%s\n", i + 1, wl, i + 1, gl)
+    }
+  }
+  return out.String()
+}
+
+// TestGolden renders every scenario headless and compares each frame with
+// its snapshot, or rewrites the snapshots with -update
+func TestGolden(t *testing.T) {
+  for _, s := range scenarios {
+    t.Run(s.name, func(t *testing.T) {
+      got := client.Snapshot(s.area, s.render(s.area)...)
+      path := filepath.Join(goldenDir, s.name + ".golden")
+      if *update {
+        if err := os.MkdirAll(goldenDir, 0755); err != nil {
+          t.Fatal(err)
+        }
+        if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+          t.Fatal(err)
+        }
+        return
+      }
+      want, err := os.ReadFile(path)
+      if err != nil {
+        t.Fatal(err)
+      }
+      if string(want) != got {
+        t.Errorf("frame differs from %s:\n%s", path, diff(string(want), got))
+      }
+    })
+  }
+}
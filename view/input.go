@@ -2,6 +2,7 @@ package view
 
 import (
   "fmt"
+  "image"
   "sort"
   "github.com/gammazero/deque"
   reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
@@ -62,9 +63,6 @@ func (v *inputView) Update() {
   sizes := make(map[string]int)
   v.nodes = createInputNodes(v.i[root], root, v.d.DigestFunction, v.i, sizes)
   t.SetNodes(v.nodes)
-  // setting this on every frame seems to jank it up
-  w, h := ui.TerminalDimensions()
-  t.SetRect(0, 0, w, h)
   v.t = t
 }
 
@@ -77,9 +75,6 @@ func (v *inputView) Handle(e ui.Event) View {
     v.t.ScrollDown()
   case "k", "<Up>":
     v.t.ScrollUp()
-  case "<Resize>":
-    w, h := ui.TerminalDimensions()
-    v.t.SetRect(0, 0, w, h)
   case "l", "<Right>":
     v.t.Expand()
   case "L", "<S-Right>":
@@ -104,17 +99,33 @@ func (v *inputView) Handle(e ui.Event) View {
     v.t.ExpandAll()
   case "C":
     v.t.CollapseAll()
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.t.RowAt(p); row != -1 {
+      v.t.SelectedRow = row
+      if double {
+        v.t.ToggleExpand()
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.t.ScrollAmount(wheelAmount(e))
   }
   return v
 }
 
-func (v inputView) Render() []ui.Drawable {
+func (v inputView) Render(area image.Rectangle) []ui.Drawable {
   var r ui.Drawable
   if v.err != nil {
     p := widgets.NewParagraph()
     p.Text = string(v.err.Error())
+    setRect(p, area)
     r = p
   } else {
+    // setting this on every frame seems to jank it up
+    if v.t.GetRect() != area {
+      setRect(v.t, area)
+    }
     r = v.t
   }
   return []ui.Drawable { r }
@@ -0,0 +1,310 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+  "sort"
+  "strings"
+  "time"
+
+  "github.com/dustin/go-humanize"
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+const (
+  // keys scanned at most
+  keyspaceLimit = 10000
+  // elements of the selected key read at most
+  contentsLimit = 100
+)
+
+type keyGroup struct {
+  prefix string
+  keys []*client.RedisKey
+  size int64
+  open bool
+}
+
+// keyRow is a group header, or a key of an open group
+type keyRow struct {
+  g *keyGroup
+  k *client.RedisKey
+}
+
+func ttl(k *client.RedisKey) string {
+  if k.TTL < 0 {
+    return ""
+  }
+  return "ttl " + k.TTL.Truncate(time.Second).String()
+}
+
+func (r keyRow) String() string {
+  if r.k == nil {
+    mark := "+"
+    if r.g.open {
+      mark = "-"
+    }
+    return fmt.Sprintf("%s [%s](mod:bold) (%d keys, %d elements)", mark, r.g.prefix, len(r.g.keys), r.g.size)
+  }
+  k := r.k
+  if k.Err != nil {
+    return fmt.Sprintf("    %s [%v](fg:red)", k.Name, k.Err)
+  }
+  return fmt.Sprintf("    %-6s %8d %10s  %s", k.Type, k.Size, ttl(k), k.Name)
+}
+
+type keyspaceView struct {
+  a *client.App
+  v View
+  pattern string
+  groups []*keyGroup
+  rows []keyRow
+  err error
+  // scan is set while the keys need scanning again
+  scan bool
+  // contents are those of shown, read again when another key is selected
+  shown *client.RedisKey
+  contents []string
+  contentsErr error
+  // focus is the list scrolled by keys, the keys or the contents
+  focus *client.List
+  keys *client.List
+  values *client.List
+}
+
+// NewKeyspace browses the keys of every shard matching a pattern, grouped by
+// their buildfarm prefix, with the contents of the selected key
+func NewKeyspace(a *client.App, v View) View {
+  keys := client.NewList()
+  keys.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  keys.WrapText = false
+  values := client.NewList()
+  values.SelectedRowStyle = ui.NewStyle(ui.ColorWhite)
+  values.WrapText = false
+  return &keyspaceView {
+    a: a,
+    v: v,
+    pattern: "*",
+    scan: true,
+    focus: keys,
+    keys: keys,
+    values: values,
+  }
+}
+
+func (v *keyspaceView) selected() keyRow {
+  if v.keys.SelectedRow < 0 || v.keys.SelectedRow >= len(v.rows) {
+    return keyRow{}
+  }
+  return v.rows[v.keys.SelectedRow]
+}
+
+// match scans for keys matching the glob pattern text
+func (v *keyspaceView) match(text string) (View, error) {
+  text = strings.TrimSpace(text)
+  if text == "" {
+    text = "*"
+  }
+  v.pattern = text
+  v.scan = true
+  v.keys.SelectedRow = 0
+  return v, nil
+}
+
+func (v *keyspaceView) toggle(g *keyGroup) {
+  g.open = !g.open
+  v.layout()
+  for i, r := range v.rows {
+    if r.g == g && r.k == nil {
+      v.keys.SelectedRow = i
+    }
+  }
+}
+
+func (v *keyspaceView) Handle(e ui.Event) View {
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.focus.ScrollDown()
+  case "k", "<Up>":
+    v.focus.ScrollUp()
+  case "J", "<PageDown>":
+    v.focus.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.focus.ScrollPageUp()
+  case "<Home>":
+    v.focus.ScrollTop()
+  case "<End>":
+    v.focus.ScrollBottom()
+  case "<Tab>":
+    if v.focus == v.keys {
+      v.focus = v.values
+      v.values.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+      v.keys.SelectedRowStyle = ui.NewStyle(ui.ColorWhite, ui.ColorClear, ui.ModifierBold)
+    } else {
+      v.focus = v.keys
+      v.keys.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+      v.values.SelectedRowStyle = ui.NewStyle(ui.ColorWhite)
+    }
+  case "/", "f":
+    return newPrompt("Scan keys matching", v.pattern, v.match, v)
+  case "r":
+    v.scan = true
+  case "<Enter>", "l", "h":
+    if r := v.selected(); r.g != nil {
+      v.toggle(r.g)
+    }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.keys.RowAt(p); row != -1 {
+      v.focus = v.keys
+      v.keys.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    } else if row := v.values.RowAt(p); row != -1 {
+      v.values.SelectedRow = row
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    if mousePoint(e).In(v.values.Inner) {
+      v.values.ScrollAmount(wheelAmount(e))
+    } else {
+      v.keys.ScrollAmount(wheelAmount(e))
+    }
+  }
+  return v
+}
+
+// layout lists the groups, with the keys of those open
+func (v *keyspaceView) layout() {
+  v.rows = nil
+  for _, g := range v.groups {
+    v.rows = append(v.rows, keyRow { g: g })
+    if g.open {
+      for _, k := range g.keys {
+        v.rows = append(v.rows, keyRow { g: g, k: k })
+      }
+    }
+  }
+}
+
+func (v *keyspaceView) group(keys []*client.RedisKey) {
+  open := make(map[string]bool)
+  for _, g := range v.groups {
+    open[g.prefix] = g.open
+  }
+  byPrefix := make(map[string]*keyGroup)
+  v.groups = nil
+  for _, k := range keys {
+    prefix := client.KeyPrefix(k.Name)
+    g, ok := byPrefix[prefix]
+    if !ok {
+      g = &keyGroup { prefix: prefix, open: open[prefix] }
+      byPrefix[prefix] = g
+      v.groups = append(v.groups, g)
+    }
+    g.keys = append(g.keys, k)
+    g.size += k.Size
+  }
+  sort.Slice(v.groups, func(i, j int) bool {
+    return v.groups[i].prefix < v.groups[j].prefix
+  })
+  // a single group is as good as open
+  if len(v.groups) == 1 {
+    v.groups[0].open = true
+  }
+  v.layout()
+}
+
+// read fetches the contents of k, decoding each element
+func (v *keyspaceView) read(k *client.RedisKey) {
+  v.shown = k
+  v.contents = nil
+  v.values.SelectedRow = 0
+  v.a.Fetches++
+  fields, err := k.Contents(context.Background(), v.a.Client, contentsLimit)
+  v.contentsErr = err
+  for _, f := range fields {
+    value := client.DecodeValue(k.Name, f.Value)
+    lines := strings.Split(value, "\n")
+    if f.Name != "" {
+      v.contents = append(v.contents, fmt.Sprintf("[%s:](fg:yellow) %s", f.Name, lines[0]))
+    } else {
+      v.contents = append(v.contents, lines[0])
+    }
+    v.contents = append(v.contents, lines[1:]...)
+  }
+}
+
+func (v *keyspaceView) Update() {
+  if v.scan {
+    v.scan = false
+    v.a.Fetches++
+    keys, err := client.ScanKeys(context.Background(), v.a.Client, v.pattern, keyspaceLimit)
+    v.err = err
+    v.group(keys)
+    // contents are read again for a key still selected
+    v.shown = nil
+  }
+  if k := v.selected().k; k != v.shown {
+    if k == nil {
+      v.shown, v.contents, v.contentsErr = nil, nil, nil
+    } else {
+      v.read(k)
+    }
+  }
+}
+
+func (v *keyspaceView) Render(area image.Rectangle) []ui.Drawable {
+  rows := make([]fmt.Stringer, len(v.rows))
+  for i, r := range v.rows {
+    rows[i] = r
+  }
+  v.keys.Rows = rows
+  if v.keys.SelectedRow >= len(rows) {
+    v.keys.SelectedRow = Max(len(rows) - 1, 0)
+  }
+  count := 0
+  for _, g := range v.groups {
+    count += len(g.keys)
+  }
+  title := fmt.Sprintf("Keys %s: %d in %d groups", v.pattern, count, len(v.groups))
+  if count == keyspaceLimit {
+    title += " [(limited)](fg:yellow)"
+  }
+  if v.err != nil {
+    title += fmt.Sprintf(" [%v](fg:red)", v.err)
+  }
+  v.keys.Title = title
+
+  v.values.Rows = makeStringers(v.contents)
+  if v.values.SelectedRow >= len(v.contents) {
+    v.values.SelectedRow = Max(len(v.contents) - 1, 0)
+  }
+  if k := v.shown; k != nil {
+    v.values.Title = fmt.Sprintf("%s %s on %s", k.Type, k.Name, k.Node)
+    if k.Type == "string" {
+      v.values.Title += " " + humanize.Bytes(uint64(k.Size))
+    } else if k.Size > contentsLimit {
+      v.values.Title += fmt.Sprintf(" (first %d of %d)", contentsLimit, k.Size)
+    }
+    if t := ttl(k); t != "" {
+      v.values.Title += " " + t
+    }
+    if v.contentsErr != nil {
+      v.values.Title += fmt.Sprintf(" [%v](fg:red)", v.contentsErr)
+    }
+  } else {
+    v.values.Title = "Contents"
+  }
+
+  rects := hsplit(area, area.Dx() * 2 / 5)
+  setRect(v.keys, rects[0])
+  setRect(v.values, rects[1])
+  return []ui.Drawable { v.keys, v.values }
+}
@@ -0,0 +1,102 @@
+package view
+
+import (
+  "image"
+
+  ui "github.com/gizak/termui/v3"
+)
+
+// Views place their widgets inside the area passed to Render, so the same
+// view works full screen, in a pane, and on any terminal size.
+
+// flex divides length among sizes. A positive size is a fixed number of
+// cells, a size of 0 takes an equal share of whatever is left over. Fixed
+// sizes are cut short when there is not enough room.
+func flex(length int, sizes []int) []int {
+  lengths := make([]int, len(sizes))
+  remaining := length
+  flexible := 0
+  for i, size := range sizes {
+    if size > 0 {
+      lengths[i] = Min(size, remaining)
+      remaining -= lengths[i]
+    } else {
+      flexible++
+    }
+  }
+  for i, size := range sizes {
+    if size <= 0 {
+      lengths[i] = remaining / flexible
+      remaining -= lengths[i]
+      flexible--
+    }
+  }
+  return lengths
+}
+
+// vsplit stacks rectangles of the given heights from the top of r
+func vsplit(r image.Rectangle, heights ...int) []image.Rectangle {
+  rects := make([]image.Rectangle, len(heights))
+  y := r.Min.Y
+  for i, h := range flex(r.Dy(), heights) {
+    rects[i] = image.Rect(r.Min.X, y, r.Max.X, y + h)
+    y += h
+  }
+  return rects
+}
+
+// hsplit lays out rectangles of the given widths from the left of r
+func hsplit(r image.Rectangle, widths ...int) []image.Rectangle {
+  rects := make([]image.Rectangle, len(widths))
+  x := r.Min.X
+  for i, w := range flex(r.Dx(), widths) {
+    rects[i] = image.Rect(x, r.Min.Y, x + w, r.Max.Y)
+    x += w
+  }
+  return rects
+}
+
+func setRect(d ui.Drawable, r image.Rectangle) {
+  d.SetRect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+}
+
+// form keeps a group of widgets at fixed positions relative to each other
+// and centers the group in the area it is rendered into
+type form struct {
+  widgets []ui.Drawable
+  offsets []image.Point
+  size image.Point
+}
+
+func newForm(widgets ...ui.Drawable) *form {
+  var bounds image.Rectangle
+  for i, w := range widgets {
+    if i == 0 {
+      bounds = w.GetRect()
+    } else {
+      bounds = bounds.Union(w.GetRect())
+    }
+  }
+  offsets := make([]image.Point, len(widgets))
+  for i, w := range widgets {
+    offsets[i] = w.GetRect().Min.Sub(bounds.Min)
+  }
+  return &form {
+    widgets: widgets,
+    offsets: offsets,
+    size: bounds.Size(),
+  }
+}
+
+func (f *form) render(area image.Rectangle) []ui.Drawable {
+  origin := image.Pt(
+      area.Min.X + Max(0, (area.Dx() - f.size.X) / 2),
+      area.Min.Y + Max(0, (area.Dy() - f.size.Y) / 3))
+  for i, w := range f.widgets {
+    // sizes can change, like an open dropdown
+    r := w.GetRect()
+    p := origin.Add(f.offsets[i])
+    w.SetRect(p.X, p.Y, p.X + r.Dx(), p.Y + r.Dy())
+  }
+  return f.widgets
+}
@@ -0,0 +1,102 @@
+package view
+
+import (
+  "sync/atomic"
+  "time"
+
+  "github.com/werkt/bf-client/client"
+)
+
+// alert rules are evaluated and the history recorded at most this often
+const alertInterval = time.Second
+
+// workers are profiled for the history at most this often, unless the alert
+// rules read them
+const historyProfileInterval = 10 * time.Second
+
+// Monitor evaluates the alert rules and records the history whichever view
+// is open. The queue views fed to it collect for it as they update, and it
+// collects for itself only while none does.
+type Monitor struct {
+  a *client.App
+  // q collects while no queue view feeds the monitor, never shown
+  q *Queue
+  last time.Time
+  // profiled is when the workers were last profiled
+  profiled time.Time
+  // probed is when the health of the workers was last probed, and probing
+  // while a probe runs
+  probed time.Time
+  probing atomic.Bool
+}
+
+func NewMonitor(a *client.App) *Monitor {
+  return &Monitor { a: a }
+}
+
+// Feed has q collect for the monitor as it updates
+func (m *Monitor) Feed(q *Queue) {
+  q.monitor = m
+}
+
+// due reports whether the next collection is taken by the monitor
+func (m *Monitor) due() bool {
+  return (m.a.Alerts != nil || m.a.History != nil) && time.Since(m.last) >= alertInterval
+}
+
+// needsProfiles reports whether the collection taken by the monitor
+// profiles the workers
+func (m *Monitor) needsProfiles() bool {
+  return (m.a.Alerts != nil && m.a.Alerts.NeedsWorkers()) ||
+      (m.a.History != nil && time.Since(m.profiled) >= historyProfileInterval)
+}
+
+// Update collects for the monitor when no queue view has
+func (m *Monitor) Update() {
+  if !m.due() {
+    return
+  }
+  if m.q == nil {
+    m.q = NewQueue(m.a, 1)
+  }
+  m.q.profileWorkers = m.needsProfiles()
+  values, profiled, err := m.q.collect()
+  m.observe(m.q, values, profiled, err)
+}
+
+// observe records the history and evaluates the alert rules from a
+// collection by q
+func (m *Monitor) observe(q *Queue, values map[string]float64, profiled bool, err error) {
+  m.last = time.Now()
+  // a tick the backplane does not answer is skipped
+  if err != nil {
+    return
+  }
+  if profiled {
+    m.profiled = m.last
+  }
+  now := m.a.Now()
+  if m.a.History != nil {
+    m.a.History.Record(now, values)
+  }
+  if m.a.Alerts != nil {
+    if m.a.Alerts.NeedsHealth() {
+      m.probe(q.s.workers)
+    }
+    m.a.Alerts.Evaluate(now, q.observation(values, profiled))
+  }
+}
+
+// probe refreshes the health of the workers for the stale rules as the
+// problems view does, beside the frames since workers are given far longer
+// to answer than a frame lasts
+func (m *Monitor) probe(workers []string) {
+  if time.Since(m.probed) < problemsInterval || !m.probing.CompareAndSwap(false, true) {
+    return
+  }
+  m.probed = time.Now()
+  go func() {
+    defer m.probing.Store(false)
+    client.ProbeWorkers(m.a, workers, probeTimeout)
+  }()
+}
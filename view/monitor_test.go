@@ -0,0 +1,79 @@
+package view
+
+import (
+  "context"
+  "testing"
+  "time"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+// updateNow runs the monitor now, however recently it last ran, waiting for
+// any probe it starts
+func updateNow(m *Monitor) {
+  m.last = time.Time{}
+  m.Update()
+  for m.probing.Load() {
+    time.Sleep(time.Millisecond)
+  }
+}
+
+func TestMonitorStaleFromHealth(t *testing.T) {
+  s, a := faketest.Start(t)
+  w, err := s.AddWorker(1)
+  if err != nil {
+    t.Fatal(err)
+  }
+  rule, err := client.ParseRule("worker stale")
+  if err != nil {
+    t.Fatal(err)
+  }
+  a.Alerts = client.NewAlerts(&client.Config { Rules: []*client.Rule { rule } })
+  m := NewMonitor(a)
+
+  // the first update finds the workers and probes them, the second profiles
+  updateNow(m)
+  if h := a.Health.Worker(w.Name); h == nil || h.Failures != 0 {
+    t.Fatalf("probed %s as %+v, want it answering", w.Name, h)
+  }
+  updateNow(m)
+  if firing := a.Alerts.Firing(); len(firing) != 0 {
+    t.Errorf("%s is stale while it answers", firing[0].Detail)
+  }
+
+  // a failed probe, not followed by another before the update
+  m.probed = time.Now()
+  a.Health.Record(w.Name, a.Now(), context.DeadlineExceeded)
+  updateNow(m)
+  if firing := a.Alerts.Firing(); len(firing) != 1 || firing[0].Detail != w.Name {
+    t.Errorf("firing %v, want %s stale", firing, w.Name)
+  }
+}
+
+func TestMonitorFedByQueue(t *testing.T) {
+  s, a := faketest.Start(t)
+  faketest.Submit(t, s, "a", "//a:1")
+  rule, err := client.ParseRule("prequeue > 0")
+  if err != nil {
+    t.Fatal(err)
+  }
+  a.Alerts = client.NewAlerts(&client.Config { Rules: []*client.Rule { rule } })
+  m := NewMonitor(a)
+  q := NewQueue(a, 3)
+  m.Feed(q)
+
+  q.Update()
+  m.Update()
+  if m.q != nil {
+    t.Error("the monitor collected beside the queue view")
+  }
+  if firing := a.Alerts.Firing(); len(firing) != 1 {
+    t.Errorf("firing %v, want the prequeue rule", firing)
+  }
+
+  // with no queue view open the monitor collects for itself
+  updateNow(m)
+  if m.q == nil {
+    t.Error("the monitor did not collect while no queue view did")
+  }
+}
@@ -0,0 +1,45 @@
+package view
+
+import (
+  "image"
+  "time"
+
+  ui "github.com/gizak/termui/v3"
+)
+
+const doubleClickInterval = 400 * time.Millisecond
+
+// there is only one pointer, so the last click is shared by every view
+var lastClick struct {
+  at time.Time
+  p image.Point
+}
+
+var enterEvent = ui.Event{ Type: ui.KeyboardEvent, ID: "<Enter>" }
+
+func mousePoint(e ui.Event) image.Point {
+  m := e.Payload.(ui.Mouse)
+  return image.Pt(m.X, m.Y)
+}
+
+func wheelAmount(e ui.Event) int {
+  if e.ID == "<MouseWheelUp>" {
+    return -1
+  }
+  return 1
+}
+
+// doubleClicked records a click at p and reports whether it completes a
+// double click on the same cell
+func doubleClicked(p image.Point) bool {
+  now := time.Now()
+  double := lastClick.p == p && now.Sub(lastClick.at) < doubleClickInterval
+  if double {
+    // a third click starts over
+    lastClick.at = time.Time{}
+  } else {
+    lastClick.at = now
+  }
+  lastClick.p = p
+  return double
+}
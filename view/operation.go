@@ -4,6 +4,7 @@ import (
   "container/list"
   "context"
   "fmt"
+  "image"
   "regexp"
   "time"
   reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
@@ -26,6 +27,8 @@ type operationView struct {
   selectableFields int
   selectionActions []func(*operationView) View
   paused bool
+  // fetched operations are kept in App.Ops for the event feed to refresh
+  fetched bool
   p *widgets.Paragraph
 }
 
@@ -79,13 +82,33 @@ func (v *operationView) fetch() (*longrunning.Operation, error) {
 }
 
 func (v *operationView) Update() {
-  if !v.paused && (v.err != nil || !v.op.Done) {
-    v.a.Fetches++
-    v.op, v.err = v.fetch()
+  if v.paused || (v.err == nil && v.op.Done) {
+    return
+  }
+  if v.fetched && v.a.Events.Live() {
+    v.a.Mutex.Lock()
+    op, ok := v.a.Ops[v.name]
+    if !ok {
+      // dropped by another view, held again until the next change
+      v.a.Ops[v.name] = v.op
+    }
+    v.a.Mutex.Unlock()
+    if ok && op != nil {
+      v.op = op
+    }
+    return
+  }
+  v.a.Fetches++
+  v.op, v.err = v.fetch()
+  if v.err == nil {
+    v.a.Mutex.Lock()
+    v.a.Ops[v.name] = v.op
+    v.a.Mutex.Unlock()
+    v.fetched = true
   }
 }
 
-func (v *operationView) Render() []ui.Drawable {
+func (v *operationView) Render(area image.Rectangle) []ui.Drawable {
   p := v.p
   p.Title = v.name
   if v.paused {
@@ -98,7 +121,7 @@ func (v *operationView) Render() []ui.Drawable {
   } else if !v.paused {
     p.Text = v.renderOperation()
   }
-  p.SetRect(0, 0, 120, 60)
+  setRect(p, area)
 
   return []ui.Drawable { p }
 }
@@ -123,7 +146,7 @@ func (v *operationView) renderOperation() string {
       } else {
         v.selectableFields = 1
       }
-      text += renderExecuteOperationMetadata(em, v.selection)
+      text += renderExecuteOperationMetadata(em, v.selection, v.a.Now())
       v.selectionActions = make([]func(*operationView) View, v.selectableFields)
       actionIndex := 0
       if ok {
@@ -140,7 +163,7 @@ func (v *operationView) renderOperation() string {
     } else {
       df = qm.ExecuteOperationMetadata.DigestFunction
       text = renderRequestMetadata(qm.RequestMetadata, v.selection)
-      text += renderExecuteOperationMetadata(qm.ExecuteOperationMetadata, v.selection)
+      text += renderExecuteOperationMetadata(qm.ExecuteOperationMetadata, v.selection, v.a.Now())
       text += fmt.Sprintf("queued operation: %s\n", xrenderDigest(*qm.QueuedOperationDigest, v.selection == 3))
       v.selectableFields = 4
       v.selectionActions = make([]func(*operationView) View, v.selectableFields)
@@ -166,7 +189,7 @@ func (v *operationView) renderOperation() string {
         text += err.Error()
         v.selectableFields = 0
       } else {
-        ex := renderExecuteResponse(er, df, v.selection)
+        ex := renderExecuteResponse(er, df, v.selection, v.a.Now())
         text += ex.text
         selectableFields := v.selectableFields
         v.selectableFields += ex.fields
@@ -191,10 +214,10 @@ type executeText struct {
   actions *list.List
 }
 
-func renderExecuteResponse(er *reapi.ExecuteResponse, df reapi.DigestFunction_Value, selection int) executeText {
+func renderExecuteResponse(er *reapi.ExecuteResponse, df reapi.DigestFunction_Value, selection int, now time.Time) executeText {
   var ex executeText
   if er.Result != nil {
-    ex = renderActionResult(er.Result, df, selection)
+    ex = renderActionResult(er.Result, df, selection, now)
   } else {
     ex = executeText {
       text: "nil action result\n",
@@ -228,7 +251,7 @@ func renderRequestMetadata(rm *reapi.RequestMetadata, selection int) string {
   return text
 }
 
-func renderActionResult(ar *reapi.ActionResult, df reapi.DigestFunction_Value, selection int) executeText {
+func renderActionResult(ar *reapi.ActionResult, df reapi.DigestFunction_Value, selection int, now time.Time) executeText {
   text := fmt.Sprintf("exit code: %d\n", ar.ExitCode)
   base := 0
   actions := list.New()
@@ -256,7 +279,7 @@ func renderActionResult(ar *reapi.ActionResult, df reapi.DigestFunction_Value, s
     actions.PushBack(func (v *operationView) View { return outputDirectoryView(v, od.TreeDigest, od.Path) })
   }
   base += len(ar.OutputDirectories)
-  text += renderExecutedActionMetadata(ar.ExecutionMetadata, selection == base)
+  text += renderExecutedActionMetadata(ar.ExecutionMetadata, selection == base, now)
   fields := base + 1
   return executeText {
     text: text,
@@ -265,7 +288,7 @@ func renderActionResult(ar *reapi.ActionResult, df reapi.DigestFunction_Value, s
   }
 }
 
-func renderExecutedActionMetadata(em *reapi.ExecutedActionMetadata, workerSelected bool) string {
+func renderExecutedActionMetadata(em *reapi.ExecutedActionMetadata, workerSelected bool, now time.Time) string {
   text := ""
   if len(em.Worker) != 0 {
     text = fmt.Sprintf("worker: %s\n", boldIfSelected(em.Worker, workerSelected))
@@ -346,7 +369,7 @@ func renderExecutedActionMetadata(em *reapi.ExecutedActionMetadata, workerSelect
     if ifct.Compare(qt) > 0 {
       text += fmt.Sprintf("input fetch complete: %s elapsed, took %s\n", ifct.Sub(qt).String(), ifct.Sub(ifst).String())
     } else {
-      text += fmt.Sprintf("input fetch running for %s\n", time.Now().Sub(wst).String())
+      text += fmt.Sprintf("input fetch running for %s\n", now.Sub(wst).String())
     }
   }
   var estall time.Duration
@@ -356,10 +379,10 @@ func renderExecutedActionMetadata(em *reapi.ExecutedActionMetadata, workerSelect
     if ect.Compare(qt) > 0 {
       text += fmt.Sprintf("execute complete: %s elapsed, took %s\n", ect.Sub(qt).String(), ect.Sub(est).String())
     } else {
-      text += fmt.Sprintf("execute running for %s\n", time.Now().Sub(est).String())
+      text += fmt.Sprintf("execute running for %s\n", now.Sub(est).String())
     }
   } else if (ifct.Compare(qt) > 0) {
-    text += fmt.Sprintf("execute stalled for %s\n", time.Now().Sub(ifct).String())
+    text += fmt.Sprintf("execute stalled for %s\n", now.Sub(ifct).String())
   }
   var oustall time.Duration
   if oust.Compare(ect) > 0 {
@@ -368,10 +391,10 @@ func renderExecutedActionMetadata(em *reapi.ExecutedActionMetadata, workerSelect
     if ouct.Compare(qt) > 0 {
       text += fmt.Sprintf("output upload complete: %s elapsed, took %s\n", ouct.Sub(qt).String(), ouct.Sub(oust).String())
     } else {
-      text += fmt.Sprintf("output upload running for %s\n", time.Now().Sub(oust).String())
+      text += fmt.Sprintf("output upload running for %s\n", now.Sub(oust).String())
     }
   } else if (oust.Compare(qt) > 0) {
-    text += fmt.Sprintf("output upload stalled for %s\n", time.Now().Sub(ect).String())
+    text += fmt.Sprintf("output upload stalled for %s\n", now.Sub(ect).String())
   }
   if wct.Compare(qt) > 0 {
     text += fmt.Sprintf(
@@ -382,14 +405,14 @@ func renderExecutedActionMetadata(em *reapi.ExecutedActionMetadata, workerSelect
   return text
 }
 
-func renderExecuteOperationMetadata(em *reapi.ExecuteOperationMetadata, selection int) string {
+func renderExecuteOperationMetadata(em *reapi.ExecuteOperationMetadata, selection int, now time.Time) string {
   stage := &reapi.ExecuteOperationMetadata {
     Stage: em.Stage,
   }
   text := proto.MarshalTextString(stage) + "\n"
   text += fmt.Sprintf("action: %s\n", renderREDigest(*em.ActionDigest, em.DigestFunction, selection == 2))
   if em.PartialExecutionMetadata != nil {
-    text += renderExecutedActionMetadata(em.PartialExecutionMetadata, selection == 4)
+    text += renderExecutedActionMetadata(em.PartialExecutionMetadata, selection == 4, now)
   }
   return text
 }
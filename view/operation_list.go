@@ -4,6 +4,7 @@ import (
   "cmp"
   "context"
   "fmt"
+  "image"
   "maps"
   "slices"
   "sync"
@@ -46,6 +47,8 @@ type operationList struct {
   queues []*client.Queue
   fetchToken string
   grouped bool
+  // message reports a refused requeue, until the next key
+  message string
 
   fetchChanged bool
   stall int
@@ -94,15 +97,25 @@ func NewOperationList(a *client.App, mode int, v View) *operationList {
   }
 }
 
-func (v operationList) fetchQueues(max int64, cb func(string) (*client.Operation, error)) []*client.Operation {
+func (v operationList) fetchQueues(max int64, cb func(string) (*client.Operation, error)) ([]*client.Operation, error) {
   var ops []*client.Operation
   for _, queue := range v.queues {
-    ops = append(ops, queue.Slice(context.Background(), v.a.Client, 0, max, cb)...)
+    // the rest of the page from the next queue
+    entries, err := queue.Slice(context.Background(), v.a.Client, 0, max - int64(len(ops)) - 1, cb)
+    if err != nil {
+      return ops, err
+    }
+    for _, entry := range entries {
+      // entries that do not decode are left out
+      if entry.Err == nil {
+        ops = append(ops, entry.Operation)
+      }
+    }
     if int64(len(ops)) >= max {
       break
     }
   }
-  return ops
+  return ops, nil
 }
 
 func (v *operationList) fetchIteration(c longrunning.OperationsClient) {
@@ -278,6 +291,7 @@ func selectField(field int, name string) map[string]string {
 }
 
 func (v *operationList) Handle(e ui.Event) View {
+  v.message = ""
   switch e.ID {
   case "<Escape>", "q", "<C-c>":
     ui.Clear()
@@ -315,8 +329,30 @@ func (v *operationList) Handle(e ui.Event) View {
       }
     }
     return v
+  case "R":
+    // push a dispatched operation back onto a queue
+    if v.mode == 3 && !v.grouped && v.Name == "executions" && len(v.opNames) > 0 {
+      next, err := requeue(v.a, v.selectedName(), v)
+      if err != nil {
+        v.message = err.Error()
+        return v
+      }
+      ui.Clear()
+      return next
+    }
   case ">", "<":
     v.reversed = !v.reversed
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.list.RowAt(p); row != -1 {
+      v.list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
   }
   return v
 }
@@ -366,7 +402,10 @@ func (v *operationList) Update() {
     if v.opcache.Contains(op.Name) {
       continue
     }
-    if o, ok := v.a.Ops[op.Name]; !ok || o == nil {
+    v.a.Mutex.Lock()
+    o, ok := v.a.Ops[op.Name]
+    v.a.Mutex.Unlock()
+    if !ok || o == nil {
       m := op.Metadata
       if m == nil {
         v.a.Fetches++
@@ -377,6 +416,9 @@ func (v *operationList) Update() {
   }
   wg.Wait()
   v.opNames = make([]string, 0)
+  // events replace Ops as they arrive
+  v.a.Mutex.Lock()
+  defer v.a.Mutex.Unlock()
   for _, op := range ops {
     v.opNames = append(v.opNames, op.Name)
     o, ok := v.a.Ops[op.Name]
@@ -400,10 +442,11 @@ func (v operationList) modeTitle() string {
   }
 }
 
-func opStringer(name string, op *operation, field func () int) *stageEx {
+func opStringer(name string, op *operation, field func () int, now time.Time) *stageEx {
   row := &stageEx{
     field: field,
     name: name,
+    now: now,
   }
   if op != nil {
     row.done = op.done
@@ -452,7 +495,7 @@ func (v operationList) renderItemized() []fmt.Stringer {
     if !ok {
       o = nil
     }
-    rows = append(rows, opStringer(name, o, func () int { return v.field }))
+    rows = append(rows, opStringer(name, o, func () int { return v.field }, v.a.Now()))
   }
   return rows
 }
@@ -477,7 +520,7 @@ func (v operationList) renderGrouped() []fmt.Stringer {
     if !ok {
       o = nil
     }
-    val := opStringer(name, o, func () int { return v.field }).label()
+    val := opStringer(name, o, func () int { return v.field }, v.a.Now()).label()
     r, ok := buckets[val]
     if !ok {
       r = &groupResult {
@@ -520,10 +563,14 @@ func fieldName(grouped bool, field int, sel map[string]string) string {
 }
 
 func (v operationList) renderTitle() string {
-  return fmt.Sprintf("%s Operations (%s) %d", v.modeTitle(), fieldName(v.grouped, v.field, v.Select), len(v.opNames))
+  title := fmt.Sprintf("%s Operations (%s) %d", v.modeTitle(), fieldName(v.grouped, v.field, v.Select), len(v.opNames))
+  if v.message != "" {
+    title += " [" + v.message + "](fg:red)"
+  }
+  return title
 }
 
-func (v operationList) Render() []ui.Drawable {
+func (v operationList) Render(area image.Rectangle) []ui.Drawable {
   v.list.Title = v.renderTitle()
 
   var rows []fmt.Stringer
@@ -531,7 +578,7 @@ func (v operationList) Render() []ui.Drawable {
     rows = v.renderGrouped()
   } else {
     rows = v.renderItemized()
-    now := time.Now()
+    now := v.a.Now()
     fence := func(e1, e2 fmt.Stringer) bool {
       stageEx1, stageEx2 := e1.(*stageEx), e2.(*stageEx)
       fence1, fence2 := now, now
@@ -555,15 +602,17 @@ func (v operationList) Render() []ui.Drawable {
   v.list.Rows = rows
   v.list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
   v.list.WrapText = false
-  v.list.SetRect(0, 0, 160, 30)
 
-  content := []ui.Drawable { v.list }
+  if !v.debug {
+    setRect(v.list, area)
+    return []ui.Drawable { v.list }
+  }
+  rects := vsplit(area, 0, 10)
+  setRect(v.list, rects[0])
   debug := client.NewParagraph()
-  debug.SetRect(30, 30, 80, 40)
+  setRect(debug, rects[1])
   debug.Text = fmt.Sprintf("token: %s, changed %v, stall: %v, stallStart: %v", v.fetchToken, v.fetchChanged, v.stall, v.stallStart)
-  if v.debug {
-    content = append(content, debug)
-  }
+  content := []ui.Drawable { v.list, debug }
 
   return content
 }
@@ -0,0 +1,58 @@
+package view
+
+import (
+  "slices"
+  "testing"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func TestOperationListUpdate(t *testing.T) {
+  s, a := faketest.Start(t)
+  first := faketest.Submit(t, s, "a", "//a:1")
+  faketest.Submit(t, s, "b", "//b:1")
+  v := NewOperationList(a, 4, nil)
+  v.Filter = "toolInvocationId=a"
+
+  v.Update()
+  if !slices.Equal(v.opNames, []string{first}) {
+    t.Fatalf("listed %v, want %v", v.opNames, []string{first})
+  }
+  op, ok := v.opcache.Get(first)
+  if !ok || op.target != "//a:1" || op.mnemonic != "Test" || op.done {
+    t.Errorf("cached %s as %+v", first, op)
+  }
+  if !slices.Contains(a.Invocations["a"], first) {
+    t.Errorf("invocation a holds %v, want %s", a.Invocations["a"], first)
+  }
+
+  // a new operation changes the list, which is fetched again
+  second := faketest.Submit(t, s, "a", "//a:2")
+  v.Update()
+  slices.Sort(v.opNames)
+  if want := []string{first, second}; !slices.Equal(v.opNames, want) {
+    t.Errorf("listed %v, want %v", v.opNames, want)
+  }
+  if op, ok := v.opcache.Get(second); !ok || op.target != "//a:2" {
+    t.Errorf("cached %s as %+v", second, op)
+  }
+}
+
+func TestOperationListStalls(t *testing.T) {
+  s, a := faketest.Start(t)
+  faketest.Submit(t, s, "a", "//a:1")
+  v := NewOperationList(a, 4, nil)
+  v.Filter = "toolInvocationId=a"
+
+  v.Update()
+  v.Update()
+  // an unchanged list is fetched less often
+  second := faketest.Submit(t, s, "a", "//a:2")
+  v.Update()
+  if slices.Contains(v.opNames, second) {
+    t.Errorf("fetched %v while stalled", v.opNames)
+  }
+  v.Update()
+  if !slices.Contains(v.opNames, second) {
+    t.Errorf("listed %v after the stall, want %s", v.opNames, second)
+  }
+}
@@ -0,0 +1,216 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+  "net"
+  "strings"
+  "time"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// the active workers are probed at most this often, each given far longer
+// to answer than the meter allows
+const problemsInterval = 2 * time.Second
+const probeTimeout = 2 * time.Second
+
+// problemRow is a worker not answering, or an operation it holds
+type problemRow struct {
+  health *client.WorkerHealth
+  d *client.Dispatched
+}
+
+type problemsView struct {
+  a *client.App
+  v View
+  active int
+  problems []*client.WorkerHealth
+  // held are the dispatched operations of each problem worker
+  held map[string][]*client.Dispatched
+  rows []problemRow
+  err error
+  // message reports a refused action, until the next key
+  message string
+  last time.Time
+  list *client.List
+}
+
+// NewProblems lists the workers the backplane counts as active that do not
+// answer for their profile, with the dispatched operations they hold
+func NewProblems(a *client.App, v View) View {
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  return &problemsView {
+    a: a,
+    v: v,
+    held: make(map[string][]*client.Dispatched),
+    list: list,
+  }
+}
+
+func (v *problemsView) selected() *problemRow {
+  if v.list.SelectedRow < 0 || v.list.SelectedRow >= len(v.rows) {
+    return nil
+  }
+  return &v.rows[v.list.SelectedRow]
+}
+
+func (v *problemsView) Handle(e ui.Event) View {
+  v.message = ""
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.list.ScrollDown()
+  case "k", "<Up>":
+    v.list.ScrollUp()
+  case "J", "<PageDown>":
+    v.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.list.ScrollPageUp()
+  case "r":
+    // probe again now
+    v.last = time.Time{}
+  case "<Enter>":
+    if r := v.selected(); r != nil && r.d != nil {
+      ui.Clear()
+      return NewOperation(v.a, r.d.Name, v)
+    }
+  case "R":
+    if r := v.selected(); r != nil && r.d != nil {
+      next, err := requeue(v.a, r.d.Name, v)
+      if err != nil {
+        v.message = err.Error()
+        return v
+      }
+      ui.Clear()
+      return next
+    }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.list.RowAt(p); row != -1 {
+      v.list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
+  }
+  return v
+}
+
+// heldBy reports whether the operations of holder are those of the worker,
+// the holder named with or without the port of the worker's endpoint
+func heldBy(holder string, worker string) bool {
+  if holder == worker {
+    return true
+  }
+  host, _, err := net.SplitHostPort(worker)
+  return err == nil && host == holder
+}
+
+func (v *problemsView) Update() {
+  if time.Since(v.last) < problemsInterval {
+    return
+  }
+  v.last = time.Now()
+  v.a.Fetches++
+  status, err := client.BackplaneStatus(context.Background(), v.a.Conn, v.a.Instance)
+  if v.err = err; err != nil {
+    return
+  }
+  active := status.ActiveExecuteWorkers
+  v.active = len(active)
+  v.a.Fetches += uint(len(active))
+  client.ProbeWorkers(v.a, active, probeTimeout)
+  v.problems = v.a.Health.Unreachable(active)
+
+  held := make(map[string][]*client.Dispatched)
+  if len(v.problems) > 0 {
+    v.a.Fetches++
+    dispatched, err := client.ScanDispatched(context.Background(), v.a.Client, 1000)
+    if err != nil {
+      v.err = err
+    }
+    fetchHolders(v.a, dispatched)
+    for _, d := range dispatched {
+      worker, _ := holder(v.a, d.Name)
+      if worker == "" {
+        continue
+      }
+      for _, p := range v.problems {
+        if heldBy(worker, p.Worker) {
+          held[p.Worker] = append(held[p.Worker], d)
+        }
+      }
+    }
+  }
+  v.held = held
+}
+
+func lastAnswered(h *client.WorkerHealth, now time.Time) string {
+  if h.LastSuccess.IsZero() {
+    return "never answered"
+  }
+  return fmt.Sprintf("answered %s ago", now.Sub(h.LastSuccess).Truncate(time.Second))
+}
+
+func (v *problemsView) renderProblem(h *client.WorkerHealth, width int, now time.Time) string {
+  name := h.Worker + strings.Repeat(" ", Max(width - len(h.Worker), 0))
+  row := fmt.Sprintf("%s [%-11s](fg:red,mod:bold) %3d failures, down %s, %s",
+      name, h.Category, h.Failures, h.Down(now).Truncate(time.Second), lastAnswered(h, now))
+  if n := len(v.held[h.Worker]); n > 0 {
+    row += fmt.Sprintf(", [holding %d](fg:yellow,mod:bold)", n)
+  }
+  if h.Err != nil {
+    row += fmt.Sprintf(" [%v](fg:white,mod:dim)", h.Err)
+  }
+  return row
+}
+
+func (v *problemsView) Render(area image.Rectangle) []ui.Drawable {
+  now := v.a.Now()
+  width, holding := 0, 0
+  for _, h := range v.problems {
+    width = Max(width, len(h.Worker))
+    holding += len(v.held[h.Worker])
+  }
+  v.rows = v.rows[:0]
+  var rows []string
+  for _, h := range v.problems {
+    v.rows = append(v.rows, problemRow { health: h })
+    rows = append(rows, v.renderProblem(h, width, now))
+    for _, d := range v.held[h.Worker] {
+      v.rows = append(v.rows, problemRow { health: h, d: d })
+      rows = append(rows, fmt.Sprintf("  %s  %s", deadline(d, now), d.Name))
+    }
+  }
+  v.list.Rows = makeStringers(rows)
+  if v.list.SelectedRow >= len(rows) {
+    v.list.SelectedRow = Max(len(rows) - 1, 0)
+  }
+  title := fmt.Sprintf("%d of %d active workers not answering", len(v.problems), v.active)
+  if holding > 0 {
+    title += fmt.Sprintf(", [holding %d dispatched](fg:yellow)", holding)
+  }
+  if v.last.IsZero() {
+    title = "Probing active workers..."
+  }
+  title += ", (r) probe, (R) requeue held"
+  if v.err != nil {
+    title += fmt.Sprintf(" [%v](fg:red)", v.err)
+  }
+  if v.message != "" {
+    title += " [" + v.message + "](fg:red)"
+  }
+  v.list.Title = title
+  setRect(v.list, area)
+  return []ui.Drawable { v.list }
+}
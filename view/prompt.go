@@ -0,0 +1,271 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// opaque blanks its area before drawing, to cover the view underneath
+type opaque struct {
+  ui.Drawable
+}
+
+func (o opaque) Draw(buf *ui.Buffer) {
+  buf.Fill(ui.NewCell(' '), o.GetRect())
+  o.Drawable.Draw(buf)
+}
+
+// overlay centers a box of height rows over area
+func overlay(area image.Rectangle, height int) image.Rectangle {
+  width := Min(area.Dx() - 4, 80)
+  x := area.Min.X + (area.Dx() - width) / 2
+  y := area.Min.Y + Max((area.Dy() - height) / 2, 0)
+  return image.Rect(x, y, x + width, y + height)
+}
+
+// prompt asks for a line of text over the view it returns to, and hands it
+// to accept for the view that follows
+type prompt struct {
+  v View
+  title string
+  text string
+  err error
+  accept func(string) (View, error)
+}
+
+func newPrompt(title string, text string, accept func(string) (View, error), v View) View {
+  return &prompt {
+    v: v,
+    title: title,
+    text: text,
+    accept: accept,
+  }
+}
+
+func (p *prompt) Handle(e ui.Event) View {
+  switch e.ID {
+  case "<Escape>", "<C-c>":
+    ui.Clear()
+    return p.v
+  case "<Enter>":
+    next, err := p.accept(p.text)
+    if err != nil {
+      p.err = err
+      return p
+    }
+    ui.Clear()
+    return next
+  case "<Backspace>", "<C-<Backspace>>":
+    if r := []rune(p.text); len(r) > 0 {
+      p.text = string(r[:len(r) - 1])
+    }
+  case "<C-u>":
+    p.text = ""
+  case "<Space>":
+    p.text += " "
+  default:
+    if e.Type == ui.KeyboardEvent && len([]rune(e.ID)) == 1 {
+      p.text += e.ID
+    }
+  }
+  p.err = nil
+  return p
+}
+
+func (p *prompt) Update() {
+  p.v.Update()
+}
+
+func (p *prompt) Render(area image.Rectangle) []ui.Drawable {
+  box := client.NewParagraph()
+  box.Title = p.title
+  box.Text = p.text + "_"
+  height := 3
+  if p.err != nil {
+    box.Text += fmt.Sprintf("\n[%s](fg:red)", p.err)
+    height++
+  }
+  setRect(box, overlay(area, height))
+  return append(p.v.Render(area), opaque { box })
+}
+
+// choice picks one of a few values over the view it returns to
+type choice struct {
+  v View
+  list *client.List
+  err error
+  accept func(int) (View, error)
+}
+
+func newChoice(title string, values []string, accept func(int) (View, error), v View) View {
+  list := client.NewList()
+  list.Title = title
+  list.Rows = makeStringers(values)
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  return &choice {
+    v: v,
+    list: list,
+    accept: accept,
+  }
+}
+
+func (c *choice) Handle(e ui.Event) View {
+  c.err = nil
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return c.v
+  case "j", "<Down>":
+    c.list.ScrollDown()
+  case "k", "<Up>":
+    c.list.ScrollUp()
+  case "<Enter>":
+    if c.list.SelectedRow >= 0 && c.list.SelectedRow < len(c.list.Rows) {
+      next, err := c.accept(c.list.SelectedRow)
+      if err != nil {
+        c.err = err
+        return c
+      }
+      ui.Clear()
+      return next
+    }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := c.list.RowAt(p); row != -1 {
+      c.list.SelectedRow = row
+      if double {
+        return c.Handle(enterEvent)
+      }
+    }
+  }
+  return c
+}
+
+func (c *choice) Update() {
+  c.v.Update()
+}
+
+func (c *choice) Render(area image.Rectangle) []ui.Drawable {
+  frame := c.v.Render(area)
+  height := Min(len(c.list.Rows), area.Dy() - 6) + 2
+  if c.err != nil {
+    p := client.NewParagraph()
+    p.Text = fmt.Sprintf("[%s](fg:red)", c.err)
+    r := overlay(area, height + 3)
+    setRect(c.list, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y - 3))
+    setRect(p, image.Rect(r.Min.X, r.Max.Y - 3, r.Max.X, r.Max.Y))
+    return append(frame, opaque { c.list }, opaque { p })
+  }
+  setRect(c.list, overlay(area, height))
+  return append(frame, opaque { c.list })
+}
+
+type commandRow struct {
+  command client.Command
+  state string
+}
+
+func (r *commandRow) String() string {
+  if r.state == "" {
+    return "  " + r.command.String()
+  }
+  return r.state + " " + r.command.String()
+}
+
+// confirm previews the commands of a change, applying them only once
+// confirmed
+type confirm struct {
+  a *client.App
+  v View
+  title string
+  commands []client.Command
+  rows []*commandRow
+  applied int
+  err error
+  done bool
+  list *client.List
+}
+
+func newConfirm(a *client.App, title string, commands []client.Command, v View) View {
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  var rows []*commandRow
+  for _, command := range commands {
+    row := &commandRow { command: command }
+    rows = append(rows, row)
+    list.Rows = append(list.Rows, row)
+  }
+  return &confirm {
+    a: a,
+    v: v,
+    title: title,
+    commands: commands,
+    rows: rows,
+    list: list,
+  }
+}
+
+func (c *confirm) apply() {
+  c.applied, c.err = client.Apply(context.Background(), c.a.Client, c.commands)
+  for i, row := range c.rows {
+    switch {
+    case i < c.applied:
+      row.state = "[ok](fg:green)"
+    case i == c.applied:
+      row.state = fmt.Sprintf("[failed: %v](fg:red)", c.err)
+    default:
+      row.state = "[skipped](fg:yellow)"
+    }
+  }
+  c.done = true
+}
+
+func (c *confirm) Handle(e ui.Event) View {
+  switch e.ID {
+  case "<Escape>", "q", "n", "<C-c>":
+    ui.Clear()
+    return c.v
+  case "y":
+    if !c.done && len(c.commands) > 0 {
+      c.apply()
+    }
+  case "<Enter>":
+    if c.done {
+      ui.Clear()
+      return c.v
+    }
+  case "j", "<Down>":
+    c.list.ScrollDown()
+  case "k", "<Up>":
+    c.list.ScrollUp()
+  case "J", "<PageDown>":
+    c.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    c.list.ScrollPageUp()
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    c.list.ScrollAmount(wheelAmount(e))
+  }
+  return c
+}
+
+func (c *confirm) Update() {
+}
+
+func (c *confirm) Render(area image.Rectangle) []ui.Drawable {
+  switch {
+  case len(c.commands) == 0:
+    c.list.Title = c.title + ": nothing to do, (n) to return"
+  case !c.done:
+    c.list.Title = fmt.Sprintf("%s: dry run of %d commands, (y) to apply, (n) to cancel", c.title, len(c.commands))
+  default:
+    c.list.Title = fmt.Sprintf("%s: applied %d of %d, (enter) to return", c.title, c.applied, len(c.commands))
+  }
+  setRect(c.list, area)
+  return []ui.Drawable { c.list }
+}
@@ -4,7 +4,9 @@ import (
   "container/list"
   "context"
   "fmt"
-  "maps"
+  "image"
+  "math"
+  "net"
   "slices"
   "strings"
   "sort"
@@ -19,14 +21,29 @@ import (
   "google.golang.org/grpc/status"
 )
 
-var workersSorts = []string{"Executions", "Name"}
+var workersSorts = []string{"Executions", "Name", "InputFetch", "ReportResult", "Free", "CAS", "Stale", "Age"}
 var workersViews = []string{"Slots", "Actions"}
 
+// workers are listed together, or in groups of those sharing a name but for
+// its ordinal, or the platform of the operations they hold
+var workersGroups = []string{"", "Pool", "Platform"}
+
+// platforms of the operations held are scanned at most this often when
+// workers are grouped by them
+const platformsInterval = 5 * time.Second
+
+// plot windows beyond the first, live one are read from the app history
+var plotWindows = []time.Duration{0, 10 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
 type profileResult struct {
   name string
   profile *bfpb.WorkerProfileMessage
   stale int
   message string
+  // paused stages, only queried for alerts
+  paused map[string]bool
+  // at is when the profile was last answered
+  at time.Time
 }
 
 type stats struct {
@@ -34,32 +51,108 @@ type stats struct {
   status bfpb.BackplaneStatus
   profiles map[string]*profileResult
   last time.Time
-  prequeueData list.List
-  prequeueSum float64
-  queueData list.List
-  queueSum float64
-  dispatchedData list.List
-  dispatchedSum float64
-  ticks float64
+  // recent averaged samples of every named series
+  series map[string]*sampled
+  rates rates
+  // platforms holds the platform of the operations of each worker, while
+  // grouped by them
+  platforms map[string]string
+  platformsAt time.Time
   mutex *sync.Mutex
 }
 
+// sampled averages the values of a series between samples
+type sampled struct {
+  data list.List
+  sum float64
+  ticks float64
+}
+
+func (s *stats) sample(name string, value float64) {
+  d := s.series[name]
+  if d == nil {
+    d = &sampled{}
+    s.series[name] = d
+  }
+  d.sum += value
+  d.ticks++
+}
+
+// push appends the averages of every series sampled since the last push,
+// keeping 60 of them
+func (s *stats) push() {
+  for _, d := range s.series {
+    if d.ticks == 0 {
+      continue
+    }
+    if d.data.Len() > 60 {
+      d.data.Remove(d.data.Back())
+    }
+    d.data.PushFront(d.sum / d.ticks)
+    d.sum = 0
+    d.ticks = 0
+  }
+}
+
+// rates are derived from successive backplane status snapshots at least a
+// second apart. Operations move from the queues, the prequeue included, to
+// the dispatched hash and leave it when completed or requeued, so between
+// snapshots the enqueues less the dispatches are the growth of the queues,
+// and the dispatches less the completions the growth of the dispatched
+// operations. Levels cannot tell flows that cancel out, so each rate is the
+// least that explains both changes, a steady flow reading as none.
+type rates struct {
+  last time.Time
+  queued int64
+  dispatched int64
+  enqueue float64
+  dispatch float64
+  completion float64
+}
+
+func (r *rates) update(queued int64, dispatched int64, now time.Time) {
+  if now.Sub(r.last) < time.Second {
+    return
+  }
+  if !r.last.IsZero() {
+    dt := now.Sub(r.last).Seconds()
+    dq := float64(queued - r.queued)
+    dd := float64(dispatched - r.dispatched)
+    r.dispatch = math.Max(math.Max(-dq, dd), 0) / dt
+    r.enqueue = r.dispatch + dq / dt
+    r.completion = r.dispatch - dd / dt
+  }
+  r.queued = queued
+  r.dispatched = dispatched
+  r.last = now
+}
+
 type numValue struct {
   fmt string
   value int
+  // rate, when set, is shown instead of value
+  rate *float64
   mode int
   parent *numValue
+  series string
 }
 
 func (nv numValue) String() string {
+  if nv.rate != nil {
+    return fmt.Sprintf(nv.fmt, *nv.rate)
+  }
+  if !strings.Contains(nv.fmt, "%") {
+    return nv.fmt
+  }
   return fmt.Sprintf(nv.fmt, nv.value)
 }
 
+const ratesMode = 4
+
 type Queue struct {
   a *client.App
   focused bool
   s stats
-  h int
   meter *client.List
   stats *client.Tree
   workers numValue
@@ -68,9 +161,28 @@ type Queue struct {
   queueNode client.TreeNode
   queue numValue
   dispatched numValue
+  ratesNode client.TreeNode
+  rates numValue
   workersSort int
   workersView int
+  workersGroup int
+  // workersFilter limits the meter to workers with names containing it
+  workersFilter string
   settings *settings
+  window int
+  offset time.Duration
+  overlay bool
+  stacked bool
+  // marks are the workers marked in the meter for a change to all of them
+  marks map[string]bool
+  // monitor takes the collections it is due, for the alert rules and the
+  // history
+  monitor *Monitor
+  // profileWorkers has the next collection profile the workers for the
+  // monitor
+  profileWorkers bool
+  // err is why the last status was not collected
+  err error
 }
 
 func statNode(nv *numValue) *client.TreeNode {
@@ -82,28 +194,45 @@ type workersTitle struct {
 }
 
 func (s workersTitle) String() string {
-  return fmt.Sprintf(">%s< %s", workersSorts[s.q.workersSort], workersViews[s.q.workersView])
+  title := fmt.Sprintf(">%s< %s", workersSorts[s.q.workersSort], workersViews[s.q.workersView])
+  if group := workersGroups[s.q.workersGroup]; group != "" {
+    title += " by " + group
+  }
+  if s.q.workersFilter != "" {
+    title += " ~" + s.q.workersFilter
+  }
+  return title
 }
 
 func NewQueue(a *client.App, selected int) *Queue {
-  _, h := ui.TerminalDimensions()
   meter := client.NewList()
   meter.SelectedRow = -1
   q := &Queue {
     a: a,
     s: stats {
       profiles: make(map[string]*profileResult),
-      last: time.Now(),
+      series: make(map[string]*sampled),
+      last: a.Now(),
       mutex: &sync.Mutex{},
     },
     meter: meter,
-    h: h,
     stats: client.NewTree(),
     workers: numValue{ fmt: "Workers: %v", },
-    prequeue: numValue{ fmt: "Prequeue: %v", mode: 1 },
-    queue: numValue{ fmt: "Queue: %v", mode: 2 },
-    dispatched: numValue{ fmt: "Dispatched: %v", mode: 3 },
+    prequeue: numValue{ fmt: "Prequeue: %v", mode: 1, series: "prequeue" },
+    queue: numValue{ fmt: "Queue: %v", mode: 2, series: "queue" },
+    dispatched: numValue{ fmt: "Dispatched: %v", mode: 3, series: "dispatched" },
+    rates: numValue{ fmt: "Rates", mode: ratesMode },
     workersSort: 0,
+    marks: make(map[string]bool),
+  }
+  r := &q.s.rates
+  for _, rate := range []struct { label string; name string; rate *float64 } {
+    { "Enqueue", "enqueue", &r.enqueue },
+    { "Dispatch", "dispatch", &r.dispatch },
+    { "Completion", "completion", &r.completion },
+  } {
+    nv := &numValue{ fmt: rate.label + ": %.1f/s", rate: rate.rate, parent: &q.rates, series: "rate/" + rate.name }
+    q.ratesNode.Nodes = append(q.ratesNode.Nodes, statNode(nv))
   }
   meter.SubTitle = &workersTitle { q: q } 
   q.stats.Focused = true
@@ -111,11 +240,13 @@ func NewQueue(a *client.App, selected int) *Queue {
   q.stats.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
   q.queueNode.Value = &q.queue
   q.prequeueNode.Value = &q.prequeue
+  q.ratesNode.Value = &q.rates
   q.stats.SetNodes([]*client.TreeNode{
     statNode(&q.workers),
     &q.prequeueNode,
     &q.queueNode,
     statNode(&q.dispatched),
+    &q.ratesNode,
   })
   return q
 }
@@ -188,11 +319,37 @@ func (v *Queue) Handle(e ui.Event) View {
   case "<Enter>":
     if v.meter.SelectedRow >= 0 {
       // get the worker out of the list
-      return NewWorker(v.a, v.meter.Rows[v.meter.SelectedRow].(Worker).w, v)
-    } else if v.stats.SelectedNode().Value.(*numValue).mode != 0 {
+      if worker := v.selectedWorker(); worker != "" {
+        return NewWorker(v.a, worker, v)
+      }
+    } else if mode := v.stats.SelectedNode().Value.(*numValue).mode; mode != 0 && mode != ratesMode {
       ui.Clear()
       return NewOperationList(v.a, v.stats.SelectedNode().Value.(*numValue).mode, v)
     }
+  case "i":
+    if v.stats.SelectedNode().Value.(*numValue) == &v.dispatched {
+      ui.Clear()
+      return NewDispatched(v.a, v)
+    }
+    if title, names := v.inspected(v.stats.SelectedNode().Value.(*numValue)); len(names) > 0 {
+      ui.Clear()
+      return NewQueueEntries(v.a, title, names, v)
+    }
+  case "e":
+    ui.Clear()
+    return NewEvents(v.a, v)
+  case "r":
+    ui.Clear()
+    return NewRedis(v.a, v)
+  case "b":
+    ui.Clear()
+    return NewKeyspace(v.a, v)
+  case "C":
+    ui.Clear()
+    return NewCasTable(v.a, v)
+  case "p":
+    ui.Clear()
+    return NewProblems(v.a, v)
   case "D":
     return NewDocument(v.a, "test", v)
   case "/":
@@ -214,6 +371,78 @@ func (v *Queue) Handle(e ui.Event) View {
       v.workersSort += len(workersSorts) - 1
       v.workersSort %= len(workersSorts)
     }
+  case "-":
+    if v.window < len(plotWindows) - 1 {
+      v.window++
+    }
+  case "+", "=":
+    if v.window > 0 {
+      v.window--
+    }
+    if v.window == 0 {
+      v.offset = 0
+    }
+  case "[":
+    if v.window > 0 {
+      v.offset += plotWindows[v.window] / 2
+    }
+  case "]":
+    v.offset = time.Duration(Max(int(v.offset - plotWindows[v.window] / 2), 0))
+  case "w":
+    v.window = (v.window + 1) % len(plotWindows)
+    if v.window == 0 {
+      v.offset = 0
+    }
+  case "o":
+    v.overlay = !v.overlay
+  case "a":
+    v.stacked = !v.stacked
+  case "f":
+    return newPrompt("Show workers with names containing (empty for all)", v.workersFilter, func(text string) (View, error) {
+      v.workersFilter = strings.TrimSpace(text)
+      ui.Clear()
+      return v, nil
+    }, v)
+  case "g":
+    if v.stats.SelectedRow == 0 {
+      v.workersGroup++
+      v.workersGroup %= len(workersGroups)
+      ui.Clear()
+    }
+  case "<Space>":
+    if worker := v.selectedWorker(); worker != "" {
+      if v.marks[worker] {
+        delete(v.marks, worker)
+      } else {
+        v.marks[worker] = true
+      }
+      v.meter.ScrollDown()
+    } else if v.meter.SelectedRow >= 0 {
+      // past a group header
+      v.meter.ScrollDown()
+    }
+  case "u":
+    v.marks = make(map[string]bool)
+  case "P":
+    if workers := v.marked(); len(workers) > 0 {
+      return changePipelines(v.a, workers, v)
+    }
+  case "d":
+    if workers := v.marked(); len(workers) > 0 {
+      return drain(v.a, workers, v)
+    }
+  case "F":
+    return newPrompt("Change the pipeline of workers matching name=glob or platform=property=glob", "name=", func(text string) (View, error) {
+      workers, err := v.matchWorkers(text)
+      if err != nil {
+        return nil, err
+      }
+      return changePipelines(v.a, workers, v), nil
+    }, v)
+  case "<MouseLeft>":
+    return v.click(mousePoint(e))
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.wheel(mousePoint(e), wheelAmount(e))
   /*
   case "S":
     return NewServerTest(v.a, v)
@@ -222,13 +451,69 @@ func (v *Queue) Handle(e ui.Event) View {
   return v
 }
 
+// inspected returns the queue names under a prequeue, queue or provision
+// node, internal queues standing for their provision
+func (v *Queue) inspected(nv *numValue) (string, []string) {
+  status := &v.s.status
+  for ; nv != nil; nv = nv.parent {
+    if nv == &v.prequeue && status.Prequeue != nil {
+      return "Prequeue", client.QueueNames(status, true)
+    } else if nv == &v.queue && status.OperationQueue != nil {
+      return "Queue", client.QueueNames(status, false)
+    } else if nv.parent == &v.queue {
+      name := strings.TrimPrefix(nv.series, "provision/")
+      return name, []string { name }
+    }
+  }
+  return "", nil
+}
+
+// selectedWorker is the worker of the selected meter row, empty on a group
+// header or without a selection
+func (v *Queue) selectedWorker() string {
+  if v.meter.SelectedRow < 0 || v.meter.SelectedRow >= len(v.meter.Rows) {
+    return ""
+  }
+  return v.meter.Rows[v.meter.SelectedRow].(Worker).w
+}
+
+func (v *Queue) click(p image.Point) View {
+  double := doubleClicked(p)
+  if row := v.stats.RowAt(p); row != -1 {
+    if (row == 0) != (v.stats.SelectedRow == 0) {
+      ui.Clear()
+    }
+    v.stats.SelectedRow = row
+    v.stats.Focused = true
+    v.meter.SelectedRow = -1
+  } else if row := v.meter.RowAt(p); row != -1 && v.stats.SelectedRow == 0 {
+    v.meter.SelectedRow = row
+    v.stats.Focused = false
+  } else {
+    return v
+  }
+  if double {
+    return v.Handle(enterEvent)
+  }
+  return v
+}
+
+func (v *Queue) wheel(p image.Point, amount int) {
+  if p.In(v.stats.Inner) {
+    v.stats.ScrollAmount(amount)
+  } else if p.In(v.meter.Inner) && v.meter.SelectedRow != -1 {
+    v.meter.ScrollAmount(amount)
+  }
+}
+
 func updateProvisionNode(node *client.TreeNode, provision *bfpb.QueueStatus) {
   size := int64(0)
   n := len(provision.InternalSizes)
   if len(node.Nodes) != n {
     node.Nodes = make([]*client.TreeNode, n)
     for i := 0; i < n; i++ {
-      node.Nodes[i] = statNode(&numValue{ fmt: "%v", parent: node.Value.(*numValue) })
+      parent := node.Value.(*numValue)
+      node.Nodes[i] = statNode(&numValue{ fmt: "%v", parent: parent, series: fmt.Sprintf("%s/%d", parent.series, i) })
     }
   }
   for i, of := range provision.InternalSizes {
@@ -244,7 +529,7 @@ func (v *Queue) updateProvisionNodes(provisions []*bfpb.QueueStatus) {
   if len(v.queueNode.Nodes) != len(provisions) {
     for _, provision := range provisions {
       node := &client.TreeNode{
-        Value: &numValue{ fmt: provision.Name + ": %v", parent: &v.queue },
+        Value: &numValue{ fmt: provision.Name + ": %v", parent: &v.queue, series: "provision/" + provision.Name },
         Nodes: make([]*client.TreeNode, 0),
       }
       v.queueNode.Nodes = append(v.queueNode.Nodes, node)
@@ -256,10 +541,42 @@ func (v *Queue) updateProvisionNodes(provisions []*bfpb.QueueStatus) {
 }
 
 func (v *Queue) Update() {
+  s := &v.s
+  feed := v.monitor != nil && v.monitor.due()
+  v.profileWorkers = feed && v.monitor.needsProfiles()
+  values, profiled, err := v.collect()
+  if feed {
+    v.monitor.observe(v, values, profiled, err)
+  }
+  // the last status is kept while the backplane does not answer
+  v.err = err
+  if err != nil {
+    return
+  }
+  now := v.a.Now()
+  for name, value := range values {
+    s.sample(name, value)
+  }
+  if s.last.Add(time.Second / 10).Before(now) {
+    s.push()
+    s.last = now
+  }
+  updateProvisionNode(&v.prequeueNode, s.status.Prequeue)
+  v.updateProvisionNodes(s.status.OperationQueue.Provisions)
+  if profiled {
+    v.recordUtilization(now)
+  }
+}
+
+// collect fetches the backplane status, and the worker profiles while the
+// meter shows them or a monitor asks for them, returning the
+// values sampled and whether the workers were profiled
+func (v *Queue) collect() (map[string]float64, bool, error) {
   s := &v.s
   c := bfpb.NewOperationQueueClient(v.a.Conn)
   var st *bfpb.BackplaneStatus
-  if v.stats.SelectedRow == 0 {
+  profiled := false
+  if v.stats.SelectedRow == 0 || v.profileWorkers {
     if s.workers != nil {
       var wg sync.WaitGroup
       for _, worker := range s.workers {
@@ -267,6 +584,10 @@ func (v *Queue) Update() {
         go fetchProfile(v, worker, v.a.GetWorkerConn(worker, v.a.CA), &wg)
       }
       wg.Wait()
+      profiled = true
+    }
+    if v.stats.SelectedRow == 0 && workersGroups[v.workersGroup] == "Platform" {
+      v.groupPlatforms()
     }
   }
   start := time.Now()
@@ -274,51 +595,86 @@ func (v *Queue) Update() {
     InstanceName: "shard",
   })
   v.a.LastReapiLatency = time.Since(start)
-  if err == nil {
-    s.status = *st
-    s.workers = st.ActiveExecuteWorkers;
-  } else {
-    panic(err)
-    st, ok := status.FromError(err)
-    if !ok || (st.Code() != codes.Unknown && st.Code() != codes.Unavailable) {
-      panic(err)
-    }
-    return
+  if err != nil {
+    return nil, false, err
   }
+  s.status = *st
+  s.workers = st.ActiveExecuteWorkers
   v.workers.value = len(s.workers)
   v.prequeue.value = int(s.status.Prequeue.Size)
   v.queue.value = int(s.status.OperationQueue.Size)
   v.dispatched.value = int(s.status.DispatchedSize)
-  now := time.Now()
-  if s.last.Add(time.Second / 10).Before(now) {
-    if s.prequeueData.Len() > 60 {
-      s.prequeueData.Remove(s.prequeueData.Back())
-      s.queueData.Remove(s.queueData.Back())
-      s.dispatchedData.Remove(s.dispatchedData.Back())
-    }
-    if s.ticks > 0 {
-      s.prequeueData.PushFront(s.prequeueSum / s.ticks)
-      s.queueData.PushFront(s.queueSum / s.ticks)
-      s.dispatchedData.PushFront(s.dispatchedSum / s.ticks)
-    } else {
-      s.prequeueData.PushFront(float64(0));
-      s.queueData.PushFront(float64(0));
-      s.dispatchedData.PushFront(float64(0));
-    }
-    s.prequeueSum = 0
-    s.queueSum = 0
-    s.dispatchedSum = 0
-    s.ticks = 0
-    s.last = now
+  s.rates.update(s.status.Prequeue.Size + s.status.OperationQueue.Size, s.status.DispatchedSize, v.a.Now())
+  // the last latency stands while redis does not answer
+  start = time.Now()
+  if v.a.Client.Ping(context.Background()).Err() == nil {
+    v.a.LastRedisLatency = time.Since(start)
   }
-  if st != nil {
-    s.prequeueSum += float64(s.status.Prequeue.Size)
-    s.queueSum = float64(s.status.OperationQueue.Size)
-    s.dispatchedSum += float64(s.status.DispatchedSize)
+  return v.samples(profiled), profiled, nil
+}
+
+// recordUtilization remembers the slots of every worker that answered
+func (v *Queue) recordUtilization(now time.Time) {
+  v.s.mutex.Lock()
+  defer v.s.mutex.Unlock()
+  for name, p := range v.s.profiles {
+    if p.stale == 0 {
+      v.a.Utilization.Record(name, now, p.profile)
+    }
   }
-  updateProvisionNode(&v.prequeueNode, s.status.Prequeue)
-  v.updateProvisionNodes(s.status.OperationQueue.Provisions)
-  s.ticks++
+}
+
+func (v *Queue) observation(values map[string]float64, profiled bool) client.Observation {
+  o := client.Observation { Values: values }
+  if profiled {
+    o.Workers = make(map[string]*client.WorkerObservation)
+    v.s.mutex.Lock()
+    for name, p := range v.s.profiles {
+      // the profile deadline is too short to judge staleness by
+      h := v.a.Health.Worker(name)
+      o.Workers[name] = &client.WorkerObservation {
+        Stale: h != nil && h.Failures > 0,
+        Profile: p.profile,
+        Paused: p.paused,
+      }
+    }
+    v.s.mutex.Unlock()
+  }
+  return o
+}
+
+// samples names the values recorded in the history, worker slots only when
+// they were profiled in this update
+func (v *Queue) samples(profiled bool) map[string]float64 {
+  s := &v.s
+  values := map[string]float64 {
+    "prequeue": float64(s.status.Prequeue.Size),
+    "queue": float64(s.status.OperationQueue.Size),
+    "dispatched": float64(s.status.DispatchedSize),
+    "rate/enqueue": s.rates.enqueue,
+    "rate/dispatch": s.rates.dispatch,
+    "rate/completion": s.rates.completion,
+  }
+  for i, size := range s.status.Prequeue.InternalSizes {
+    values[fmt.Sprintf("prequeue/%d", i)] = float64(size)
+  }
+  for _, provision := range s.status.OperationQueue.Provisions {
+    values["provision/" + provision.Name] = float64(provision.Size)
+    for i, size := range provision.InternalSizes {
+      values[fmt.Sprintf("provision/%s/%d", provision.Name, i)] = float64(size)
+    }
+  }
+  if profiled {
+    s.mutex.Lock()
+    for _, p := range s.profiles {
+      for _, stage := range p.profile.Stages {
+        values["workers/" + stage.Name + "/used"] += float64(stage.SlotsUsed)
+        values["workers/" + stage.Name + "/configured"] += float64(stage.SlotsConfigured)
+      }
+    }
+    s.mutex.Unlock()
+  }
+  return values
 }
 
 type dims struct {
@@ -353,73 +709,204 @@ func treeDimensions(t *client.Tree) dims {
   return d
 }
 
-func (v Queue) Render() []ui.Drawable {
+func (v Queue) Render(area image.Rectangle) []ui.Drawable {
   s := v.s
   p := widgets.NewParagraph()
   p.Text = fmt.Sprintf("%v: %v\n%v: %v\n%v", v.a.RedisHost, v.a.LastRedisLatency, v.a.ReapiHost, v.a.LastReapiLatency, formatTime(s.last))
-  p.SetRect(0, 0, 80, 5)
+  if v.err != nil {
+    p.Title = v.err.Error()
+    p.TitleStyle = ui.NewStyle(ui.ColorRed)
+  }
+  setRect(p, vsplit(hsplit(area, 80, 0)[0], 5, 0)[0])
 
+  // the stats and info panels share the bottom border of the header
+  body := image.Rect(area.Min.X, area.Min.Y + 4, area.Max.X, area.Max.Y)
+  var banner ui.Drawable
+  if v.a.Alerts != nil {
+    if firing := v.a.Alerts.Firing(); len(firing) > 0 {
+      var b ui.Drawable
+      b, body = alertBanner(firing, v.a.Alerts.LastError(), body, v.a.Now())
+      banner = b
+    }
+  }
   d := treeDimensions(v.stats)
-  d.width += 4
-  d.height += 6
-  v.stats.SetRect(0, 4, d.width, d.height)
+  panels := hsplit(body, d.width + 4, 0)
+  setRect(v.stats, vsplit(panels[0], d.height + 2, 0)[0])
 
   var info ui.Drawable
   if v.stats.SelectedRow == 0 {
-    info = renderWorkersInfo(&s, v.meter, d.width, v.h, v.workersSort, v.workersView)
+    info = renderWorkersInfo(&s, v.meter, panels[1], v.workersSort, v.workersView, v.marks, v.a.Utilization, v.workersFilter, v.workersGroup, v.a.Now())
   } else {
-    plot := widgets.NewPlot()
-    plot.Data = make([][]float64, 1)
-    var chartValue *numValue
-    for chartValue, _ = v.stats.SelectedNode().Value.(*numValue);
-        chartValue.parent != nil;
-        chartValue = chartValue.parent { }
-    mode := chartValue.mode
-    var container list.List
-    switch mode {
-    case 1:
-      plot.Title = "Prequeue"
-      plot.LineColors[0] = ui.ColorRed
-      container = s.prequeueData
-    case 2:
-      plot.Title = "Queue"
-      plot.LineColors[0] = ui.ColorYellow
-      container = s.queueData
-    case 3:
-      plot.Title = "Dispatched"
-      plot.LineColors[0] = ui.ColorCyan
-      container = s.dispatchedData
-    }
-    plot.Data[0] = make([]float64, 60)
-    n := 0
-    for e := container.Front(); n < 60 && n < container.Len(); n++ {
-      plot.Data[0][59 - n] = e.Value.(float64)
-      e = e.Next()
-    }
-    for ; n < 60; n++ {
-      plot.Data[0][59 - n] = float64(0)
-    }
-    plot.SetRect(d.width, 4, d.width + 71, 30)
-    plot.AxesColor = ui.ColorWhite
-    plot.Marker = widgets.MarkerBraille
-    plot.PlotType = widgets.ScatterPlot
-
-    info = plot
+    info = v.chart(panels[1])
   }
 
+  if banner != nil {
+    return []ui.Drawable{ p, banner, v.stats, info }
+  }
   return []ui.Drawable{ p, v.stats, info }
 }
 
+// alertBanner lists the firing alerts across the top of area, returning the
+// rest of it
+func alertBanner(firing []*client.Alert, lastError error, area image.Rectangle, now time.Time) (ui.Drawable, image.Rectangle) {
+  b := widgets.NewParagraph()
+  b.Title = "Alerts"
+  if lastError != nil {
+    b.Title += " (" + lastError.Error() + ")"
+  }
+  b.BorderStyle = ui.NewStyle(ui.ColorRed)
+  b.TitleStyle = ui.NewStyle(ui.ColorRed, ui.ColorClear, ui.ModifierBold)
+  var lines []string
+  for _, alert := range firing {
+    lines = append(lines, fmt.Sprintf("[%s](fg:red,mod:bold) for %v: %s", alert.Rule.Text, now.Sub(alert.Since).Truncate(time.Second), alert.Detail))
+  }
+  b.Text = strings.Join(lines, "\n")
+  rows := vsplit(area, len(lines) + 2, 0)
+  setRect(b, rows[0])
+  // the rest shares the bottom border of the banner
+  return b, image.Rect(area.Min.X, rows[0].Max.Y - 1, area.Max.X, area.Max.Y)
+}
+
+var seriesColors = []ui.Color{ui.ColorYellow, ui.ColorGreen, ui.ColorCyan, ui.ColorMagenta, ui.ColorBlue, ui.ColorRed, ui.ColorWhite}
+
+// seriesData samples the named series into n points ending now, from the
+// recent samples in the live window
+func (v Queue) seriesData(name string, n int) []float64 {
+  if v.window > 0 && v.a.History != nil {
+    window := plotWindows[v.window]
+    to := v.a.Now().Add(-v.offset)
+    return v.a.History.Range(name, to.Add(-window), to, n)
+  }
+  data := make([]float64, 60)
+  d := v.s.series[name]
+  if d == nil {
+    return data
+  }
+  e := d.data.Front()
+  for n := 0; n < 60 && e != nil; n++ {
+    data[59 - n] = e.Value.(float64)
+    e = e.Next()
+  }
+  return data
+}
+
+func (v Queue) chart(area image.Rectangle) ui.Drawable {
+  chart := client.NewChart()
+  setRect(chart, area)
+  // two braille dots per cell, less the borders and y axis labels
+  n := Max(2 * (area.Dx() - 8), 2)
+
+  node := v.stats.SelectedNode()
+  selected := node.Value.(*numValue)
+  top := selected
+  for ; top.parent != nil; top = top.parent { }
+  modeColors := map[int]ui.Color{ 1: ui.ColorRed, 2: ui.ColorYellow, 3: ui.ColorCyan, ratesMode: ui.ColorGreen }
+
+  if top.mode == ratesMode {
+    // rates share a unit, and are compared on one chart
+    chart.Title = "Rates (ops/s)"
+    for i, child := range node.Nodes {
+      nv := child.Value.(*numValue)
+      chart.Series = append(chart.Series, client.Series {
+        Name: strings.TrimPrefix(nv.series, "rate/"),
+        Data: v.seriesData(nv.series, n),
+        Color: seriesColors[i % len(seriesColors)],
+      })
+    }
+    if selected != top {
+      chart.Title = strings.SplitN(selected.fmt, ":", 2)[0] + " (ops/s)"
+      chart.Series = []client.Series {
+        { Name: strings.TrimPrefix(selected.series, "rate/"), Data: v.seriesData(selected.series, n), Color: modeColors[ratesMode] },
+      }
+    }
+  } else if v.overlay {
+    chart.Title = "Prequeue, Queue, Dispatched"
+    for _, nv := range []*numValue{ &v.prequeue, &v.queue, &v.dispatched } {
+      chart.Series = append(chart.Series, client.Series {
+        Name: strings.TrimSuffix(nv.fmt, ": %v"),
+        Data: v.seriesData(nv.series, n),
+        Color: modeColors[nv.mode],
+      })
+    }
+  } else if v.stacked && len(node.Nodes) > 0 {
+    // composition of the selected queue by its provisions or internal queues
+    by := " by internal queue"
+    if selected == &v.queue {
+      by = " by provision"
+    }
+    chart.Title = strings.TrimSuffix(selected.fmt, ": %v") + by
+    chart.Stacked = true
+    for i, child := range node.Nodes {
+      nv := child.Value.(*numValue)
+      name := strings.TrimSuffix(nv.fmt, ": %v")
+      if name == "%v" {
+        name = fmt.Sprint(i)
+      }
+      chart.Series = append(chart.Series, client.Series {
+        Name: name,
+        Data: v.seriesData(nv.series, n),
+        Color: seriesColors[i % len(seriesColors)],
+      })
+    }
+  } else {
+    chart.Title = strings.TrimSuffix(top.fmt, ": %v")
+    name := chart.Title
+    if selected != top {
+      name = strings.TrimSuffix(strings.TrimPrefix(selected.series, "provision/"), ": %v")
+      chart.Title += " " + name
+    }
+    chart.Series = []client.Series {
+      { Name: name, Data: v.seriesData(selected.series, n), Color: modeColors[top.mode] },
+    }
+  }
+
+  if v.window == 0 || v.a.History == nil {
+    chart.Title += " live"
+    chart.XLabels = []string{ "", "now" }
+  } else {
+    window := plotWindows[v.window]
+    chart.Title += " " + formatWindow(window)
+    ago := func(d time.Duration) string {
+      if d == 0 {
+        return "now"
+      }
+      return "-" + formatWindow(d)
+    }
+    chart.XLabels = []string{ ago(v.offset + window), ago(v.offset + window / 2), ago(v.offset) }
+  }
+  return chart
+}
+
+func formatWindow(d time.Duration) string {
+  if d >= 24 * time.Hour && d % (24 * time.Hour) == 0 {
+    return fmt.Sprintf("%dd", d / (24 * time.Hour))
+  }
+  s := d.String()
+  if strings.HasSuffix(s, "m0s") {
+    s = s[:len(s) - 2]
+  }
+  if strings.HasSuffix(s, "h0m") {
+    s = s[:len(s) - 2]
+  }
+  return s
+}
+
 func fetchProfile(v *Queue, worker string, conn *grpc.ClientConn, wg *sync.WaitGroup) {
   defer wg.Done()
 
   workerProfile := bfpb.NewWorkerProfileClient(conn)
   clientDeadline := time.Now().Add(time.Millisecond * 30)
-  ctx, _ := context.WithDeadline(context.Background(), clientDeadline)
+  ctx, cancel := context.WithDeadline(context.Background(), clientDeadline)
+  defer cancel()
+  // too short a deadline to judge health by, the problems view probes for it
   profile, err := workerProfile.GetWorkerProfile(ctx, &bfpb.WorkerProfileRequest {})
   if err == nil {
+    var paused map[string]bool
+    if v.profileWorkers && v.a.Alerts != nil && v.a.Alerts.NeedsPipeline() {
+      paused = fetchPaused(ctx, conn)
+    }
     v.s.mutex.Lock()
-    v.s.profiles[worker] = &profileResult {name: worker, profile: profile, stale: 0, message: ""}
+    v.s.profiles[worker] = &profileResult {name: worker, profile: profile, stale: 0, message: "", paused: paused, at: v.a.Now()}
     v.s.mutex.Unlock()
   } else {
     st, ok := status.FromError(err)
@@ -444,6 +931,20 @@ func fetchProfile(v *Queue, worker string, conn *grpc.ClientConn, wg *sync.WaitG
   }
 }
 
+// fetchPaused queries the pipeline with an empty change
+func fetchPaused(ctx context.Context, conn *grpc.ClientConn) map[string]bool {
+  c := bfpb.NewWorkerControlClient(conn)
+  r, err := c.PipelineChange(ctx, &bfpb.WorkerPipelineChangeRequest {})
+  if err != nil {
+    return nil
+  }
+  paused := make(map[string]bool)
+  for _, change := range r.Changes {
+    paused[change.Stage] = change.Paused
+  }
+  return paused
+}
+
 type byProfile func(w1, w2 *profileResult) bool
 
 func (by byProfile) Sort(workers []*profileResult) {
@@ -483,7 +984,7 @@ func (w Worker) String() string {
   return w.row
 }
 
-func sortWorkers(profiles []*profileResult, sort int) []*profileResult {
+func sortWorkers(profiles []*profileResult, sort int, now time.Time) []*profileResult {
   exec_used := func(profileResult *profileResult) int {
     if profileResult == nil {
       return -1
@@ -527,20 +1028,129 @@ func sortWorkers(profiles []*profileResult, sort int) []*profileResult {
     }
     return w1name > w2name
   }
-  if sort == 0 {
+  // the other sorts put the largest first, then by name
+  by := func(key func(*profileResult) float64) func(w1, w2 *profileResult) bool {
+    return func(w1, w2 *profileResult) bool {
+      if k1, k2 := key(w1), key(w2); k1 != k2 {
+        return k1 < k2
+      }
+      return name(w1, w2)
+    }
+  }
+  used := func(stage string) func(*profileResult) float64 {
+    return func(r *profileResult) float64 {
+      used, _ := stageSlots(r.profile, stage)
+      return float64(used)
+    }
+  }
+  switch workersSorts[sort] {
+  case "Executions":
     byProfile(exec).Sort(profiles)
-  } else {
+  case "Name":
     byProfile(name).Sort(profiles)
+  case "InputFetch":
+    byProfile(by(used("InputFetchStage"))).Sort(profiles)
+  case "ReportResult":
+    byProfile(by(used("ReportResultStage"))).Sort(profiles)
+  case "Free":
+    byProfile(by(func(r *profileResult) float64 {
+      return float64(freeSlots(r.profile))
+    })).Sort(profiles)
+  case "CAS":
+    byProfile(by(func(r *profileResult) float64 {
+      return client.NewCasUsage(r.at, r.profile).Fill()
+    })).Sort(profiles)
+  case "Stale":
+    byProfile(by(func(r *profileResult) float64 {
+      return float64(r.stale)
+    })).Sort(profiles)
+  case "Age":
+    byProfile(by(func(r *profileResult) float64 {
+      return now.Sub(r.at).Seconds()
+    })).Sort(profiles)
   }
 
   return profiles
 }
 
+// stageSlots are the slots used and configured in the named stage
+func stageSlots(profile *bfpb.WorkerProfileMessage, name string) (int, int) {
+  for _, stage := range profile.Stages {
+    if stage.Name == name {
+      return int(stage.SlotsUsed), int(stage.SlotsConfigured)
+    }
+  }
+  return 0, 0
+}
+
+func freeSlots(profile *bfpb.WorkerProfileMessage) int {
+  used, configured := stageSlots(profile, "ExecuteActionStage")
+  return Max(configured - used, 0)
+}
+
+// sortColumn shows the value sorted by, where the bars of a row do not
+func sortColumn(r *profileResult, sort int, now time.Time) string {
+  switch workersSorts[sort] {
+  case "Free":
+    return fmt.Sprintf("%3d free", freeSlots(r.profile))
+  case "CAS":
+    fill := client.NewCasUsage(r.at, r.profile).Fill()
+    return fmt.Sprintf("[%3d%% CAS](fg:%s)", int(fill * 100), fillColor(fill))
+  case "Age":
+    if r.at.IsZero() {
+      return "   never"
+    }
+    return fmt.Sprintf("%8s", now.Sub(r.at).Truncate(time.Second))
+  }
+  return ""
+}
+
+// workerPool is the name of a worker without its port and any ordinal, the
+// subnet of a worker named by its address
+func workerPool(name string) string {
+  host := name
+  if i := strings.LastIndex(host, ":"); i != -1 {
+    host = host[:i]
+  }
+  if net.ParseIP(host) != nil {
+    if i := strings.LastIndex(host, "."); i != -1 {
+      return host[:i] + ".*"
+    }
+    return host
+  }
+  label, _, _ := strings.Cut(host, ".")
+  if pool := strings.TrimRight(label, "0123456789-_"); pool != "" {
+    return pool
+  }
+  return label
+}
+
+// workerGroup is the group of the worker in the meter, by pool or by the
+// platforms of the operations it holds
+func workerGroup(s *stats, r *profileResult, group int) string {
+  switch workersGroups[group] {
+  case "Pool":
+    if len(r.profile.Name) > 0 {
+      return workerPool(r.profile.Name)
+    }
+    return workerPool(r.name)
+  case "Platform":
+    if platform := s.platforms[r.name]; platform != "" {
+      return platform
+    }
+    return "idle"
+  }
+  return ""
+}
+
+func workerFiltered(r *profileResult, filter string) bool {
+  filter = strings.ToLower(filter)
+  return strings.Contains(strings.ToLower(r.name), filter) || strings.Contains(strings.ToLower(r.profile.Name), filter)
+}
+
 // List needs work on draw, flip for only background, etc
-func renderWorkersInfo(s *stats, meter *client.List, x int, h int, sort int, view int) ui.Drawable {
+func renderWorkersInfo(s *stats, meter *client.List, area image.Rectangle, sort int, view int, marks map[string]bool, util *client.Utilization, filter string, group int, now time.Time) ui.Drawable {
   meter.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
-  height := Min(len(s.profiles), h - 6)
-  meter.SetRect(x, 4, x + 161, 4 + height + 2)
   meter.Title = "Workers";
 
   wl := 0
@@ -551,20 +1161,81 @@ func renderWorkersInfo(s *stats, meter *client.List, x int, h int, sort int, vie
     }
   }
 
-  profiles := sortWorkers(slices.Collect(maps.Values(s.profiles)), sort)
+  var profiles []*profileResult
+  for _, p := range s.profiles {
+    if filter == "" || workerFiltered(p, filter) {
+      profiles = append(profiles, p)
+    }
+  }
+  profiles = sortWorkers(profiles, sort, now)
 
-  n := 0
-  plen := len(profiles)
-  rows := make([]fmt.Stringer, plen)
+  // a column of utilization history once any worker has some
+  history := make(map[string]string)
+  utilized := false
   for _, p := range profiles {
-    rows[n] = renderWorkerRow(p, wl, view)
-    n++
+    history[p.name] = utilizationColumn(util, p.name)
+    utilized = utilized || history[p.name] != ""
+  }
+
+  // groups are listed by name, each under a header, keeping the sort within
+  groups := map[string][]*profileResult { "": profiles }
+  var labels []string
+  if group != 0 {
+    groups = make(map[string][]*profileResult)
+    for _, p := range profiles {
+      label := workerGroup(s, p, group)
+      if groups[label] == nil {
+        labels = append(labels, label)
+      }
+      groups[label] = append(groups[label], p)
+    }
+    slices.Sort(labels)
+  }
+
+  var rows []fmt.Stringer
+  for _, label := range append(labels, "") {
+    if label != "" {
+      rows = append(rows, Worker { row: fmt.Sprintf("[%s (%d)](mod:bold)", label, len(groups[label])) })
+    }
+    for _, p := range groups[label] {
+      rows = append(rows, meterRow(p, wl, view, sort, marks, history, utilized, now))
+    }
   }
   meter.Rows = rows
+  setRect(meter, vsplit(area, len(rows) + 2, 0)[0])
+  if len(marks) > 0 {
+    meter.Title = fmt.Sprintf("Workers (%d marked)", len(marks))
+  }
+  if filter != "" {
+    meter.Title += fmt.Sprintf(" %d of %d", len(profiles), len(s.profiles))
+  }
 
   return meter
 }
 
+// meterRow lays the columns of marks, utilization history and the value
+// sorted by before the bars of the worker
+func meterRow(p *profileResult, wl int, view int, sort int, marks map[string]bool, history map[string]string, utilized bool, now time.Time) Worker {
+  w := renderWorkerRow(p, wl, view)
+  if column := sortColumn(p, sort, now); column != "" {
+    w.row = column + " " + w.row
+  }
+  if utilized {
+    column := history[p.name]
+    if column == "" {
+      column = strings.Repeat(" ", sparkWidth + 5)
+    }
+    w.row = column + " " + w.row
+  }
+  // a column of marks only while any are
+  if marks[w.w] {
+    w.row = "[*](fg:yellow,mod:bold) " + w.row
+  } else if len(marks) > 0 {
+    w.row = "  " + w.row
+  }
+  return w
+}
+
 func countBar(used int, slots int) string {
   // # used/slots #
   if slots == 0 {
@@ -0,0 +1,372 @@
+package view
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "image"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+
+  bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// entries read for a page of each queue
+const inspectCount = 1000
+
+type queueEntries struct {
+  a *client.App
+  v View
+  title string
+  queues []*client.Queue
+  // cursors page through each queue in dequeue order, turned forward or
+  // back by turn on the next update
+  cursors []*client.Cursor
+  turn int
+  entries []*client.Entry
+  err error
+  // message reports an admin action refused, until the next key
+  message string
+  list *client.List
+  detail *client.Paragraph
+}
+
+// NewQueueEntries inspects the entries of the named queues straight from
+// redis
+func NewQueueEntries(a *client.App, title string, names []string, v View) View {
+  var queues []*client.Queue
+  var cursors []*client.Cursor
+  for _, name := range names {
+    q := client.NewQueue(context.Background(), a.Client, name)
+    queues = append(queues, q)
+    cursors = append(cursors, q.Cursor(inspectCount))
+  }
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  detail := client.NewParagraph()
+  detail.Title = "Entry"
+  return &queueEntries {
+    a: a,
+    v: v,
+    title: title,
+    queues: queues,
+    cursors: cursors,
+    list: list,
+    detail: detail,
+  }
+}
+
+type entryRow struct {
+  e *client.Entry
+  now time.Time
+}
+
+func age(t time.Time, now time.Time) string {
+  if t.IsZero() {
+    return "?"
+  }
+  return now.Sub(t).Truncate(time.Second).String()
+}
+
+func queuedTime(qe *bfpb.QueueEntry) time.Time {
+  if ts := qe.ExecuteEntry.QueuedTimestamp; ts != nil && ts.IsValid() {
+    return ts.AsTime()
+  }
+  return time.Time{}
+}
+
+func (r entryRow) String() string {
+  if r.e.Err != nil {
+    return fmt.Sprintf("[undecodable entry in %s: %v](fg:red)", r.e.Key, r.e.Err)
+  }
+  ee := r.e.QueueEntry.ExecuteEntry
+  row := fmt.Sprintf("%8s  %s", age(queuedTime(r.e.QueueEntry), r.now), ee.OperationName)
+  if m := ee.RequestMetadata; m != nil {
+    row += fmt.Sprintf("  %s %s", m.ActionMnemonic, m.TargetId)
+  }
+  if r.e.Priority {
+    row += fmt.Sprintf("  priority %g", r.e.Score)
+  }
+  if n := r.e.QueueEntry.RequeueAttempts; n > 0 {
+    row += fmt.Sprintf("  [requeued %d](fg:yellow)", n)
+  }
+  return row
+}
+
+func actionDigest(ee *bfpb.ExecuteEntry) bfpb.Digest {
+  return bfpb.Digest {
+    Hash: ee.ActionDigest.GetHash(),
+    Size: ee.ActionDigest.GetSizeBytes(),
+    DigestFunction: ee.DigestFunction,
+  }
+}
+
+func renderEntry(e *client.Entry, now time.Time) string {
+  var lines []string
+  field := func(name string, value interface{}) {
+    lines = append(lines, fmt.Sprintf("[%s:](mod:bold) %v", name, value))
+  }
+  field("Key", e.Key)
+  if e.Shard != "" {
+    field("Shard", e.Shard)
+  }
+  if e.Priority {
+    field("Priority", e.Score)
+  }
+  if e.Err != nil {
+    field("Error", e.Err)
+    field("Value", e.Value)
+    return strings.Join(lines, "\n")
+  }
+  qe := e.QueueEntry
+  ee := qe.ExecuteEntry
+  field("Operation", ee.OperationName)
+  if t := queuedTime(qe); !t.IsZero() {
+    field("Queued", fmt.Sprintf("%s (%s ago)", formatTime(t), age(t, now)))
+  }
+  field("Action", client.DigestString(actionDigest(ee)) + " [(enter)](fg:blue)")
+  if qe.QueuedOperationDigest != nil {
+    field("Queued Operation", client.DigestString(*qe.QueuedOperationDigest))
+  }
+  if qe.Platform != nil {
+    var properties []string
+    for _, p := range qe.Platform.Properties {
+      properties = append(properties, p.Name + "=" + p.Value)
+    }
+    sort.Strings(properties)
+    field("Platform", strings.Join(properties, ", "))
+  }
+  if ee.StdoutStreamName != "" {
+    field("Stdout", ee.StdoutStreamName)
+  }
+  if ee.StderrStreamName != "" {
+    field("Stderr", ee.StderrStreamName)
+  }
+  field("Requeue Attempts", qe.RequeueAttempts)
+  field("Skip Cache Lookup", ee.SkipCacheLookup)
+  if m := ee.RequestMetadata; m != nil {
+    field("Tool Invocation", m.ToolInvocationId)
+    field("Correlated Invocations", m.CorrelatedInvocationsId)
+    field("Target", m.TargetId)
+    field("Mnemonic", m.ActionMnemonic)
+  }
+  return strings.Join(lines, "\n")
+}
+
+func (v *queueEntries) selected() *client.Entry {
+  if v.list.SelectedRow < 0 || v.list.SelectedRow >= len(v.entries) {
+    return nil
+  }
+  return v.entries[v.list.SelectedRow]
+}
+
+// move offers the provisions of the operation queue other than that of entry
+func (v *queueEntries) move(entry *client.Entry) (View, error) {
+  status, err := client.BackplaneStatus(context.Background(), v.a.Conn, v.a.Instance)
+  if err != nil {
+    return nil, err
+  }
+  if entry.Queue == status.Prequeue.Name {
+    return nil, errors.New("prequeued entries have no provision to move between")
+  }
+  var names []string
+  for _, name := range client.QueueNames(status, false) {
+    if name != entry.Queue {
+      names = append(names, name)
+    }
+  }
+  if len(names) == 0 {
+    return nil, errors.New("there is no other provision")
+  }
+  return newChoice("Move " + entry.String() + " to", names, func(i int) (View, error) {
+    ctx := context.Background()
+    to := client.NewQueue(ctx, v.a.Client, names[i])
+    commands, err := client.MoveEntry(ctx, v.a.Client, entry, to)
+    if err != nil {
+      return nil, err
+    }
+    return newConfirm(v.a, "Move to " + names[i], commands, v), nil
+  }, v), nil
+}
+
+func (v *queueEntries) reprioritize(entry *client.Entry) (View, error) {
+  if !entry.Priority {
+    return nil, errors.New(entry.Queue + " is not a priority queue")
+  }
+  return newPrompt("Priority of " + entry.String(), strconv.FormatFloat(entry.Score, 'g', -1, 64), func(text string) (View, error) {
+    score, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+    if err != nil {
+      return nil, err
+    }
+    command, err := client.ReprioritizeEntry(entry, score)
+    if err != nil {
+      return nil, err
+    }
+    return newConfirm(v.a, "Reprioritize", []client.Command { command }, v), nil
+  }, v), nil
+}
+
+// removeMatching removes the listed entries matching a metadata filter
+func (v *queueEntries) removeMatching() View {
+  return newPrompt("Remove listed entries matching field=value", "toolInvocationId=", func(text string) (View, error) {
+    filter, err := client.ParseEntryFilter(strings.TrimSpace(text))
+    if err != nil {
+      return nil, err
+    }
+    var commands []client.Command
+    for _, entry := range v.entries {
+      if filter.Matches(entry) {
+        commands = append(commands, client.RemoveEntry(entry))
+      }
+    }
+    return newConfirm(v.a, "Remove " + text, commands, v), nil
+  }, v)
+}
+
+func (v *queueEntries) Handle(e ui.Event) View {
+  v.message = ""
+  admin := func(next View, err error) View {
+    if err != nil {
+      v.message = err.Error()
+      return v
+    }
+    ui.Clear()
+    return next
+  }
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.list.ScrollDown()
+  case "k", "<Up>":
+    v.list.ScrollUp()
+  case "J", "<PageDown>":
+    v.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.list.ScrollPageUp()
+  case "<Home>":
+    v.list.ScrollTop()
+  case "<End>":
+    v.list.ScrollBottom()
+  case "<Enter>":
+    // drill down into the action
+    if entry := v.selected(); entry != nil && entry.Err == nil {
+      ui.Clear()
+      return NewAction(v.a, actionDigest(entry.QueueEntry.ExecuteEntry), v)
+    }
+  case "o":
+    if entry := v.selected(); entry != nil && entry.Err == nil {
+      ui.Clear()
+      return NewOperation(v.a, entry.QueueEntry.ExecuteEntry.OperationName, v)
+    }
+  case "d":
+    if entry := v.selected(); entry != nil {
+      return admin(newConfirm(v.a, "Remove", []client.Command { client.RemoveEntry(entry) }, v), nil)
+    }
+  case "m":
+    if entry := v.selected(); entry != nil {
+      return admin(v.move(entry))
+    }
+  case "p":
+    if entry := v.selected(); entry != nil {
+      return admin(v.reprioritize(entry))
+    }
+  case "X":
+    return admin(v.removeMatching(), nil)
+  case "n":
+    v.turn = 1
+  case "N":
+    v.turn = -1
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := v.list.RowAt(p); row != -1 {
+      v.list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
+  }
+  return v
+}
+
+func (v *queueEntries) Update() {
+  var entries []*client.Entry
+  var err error
+  ctx := context.Background()
+  turn := v.turn
+  v.turn = 0
+  for _, cur := range v.cursors {
+    var e []*client.Entry
+    v.a.Fetches++
+    switch turn {
+    case 1:
+      e, err = cur.Next(ctx, v.a.Client)
+    case -1:
+      e, err = cur.Previous(ctx, v.a.Client)
+    default:
+      e, err = cur.Read(ctx, v.a.Client)
+    }
+    entries = append(entries, e...)
+    if err != nil {
+      break
+    }
+  }
+  if turn != 0 {
+    v.list.SelectedRow = 0
+  }
+  v.entries, v.err = entries, err
+}
+
+// page is the page shown of the queues, with whether any has more
+func (v *queueEntries) page() (int, bool) {
+  page, more := 0, false
+  for _, cur := range v.cursors {
+    page = Max(page, cur.Page())
+    more = more || cur.More()
+  }
+  return page, more
+}
+
+func (v *queueEntries) Render(area image.Rectangle) []ui.Drawable {
+  v.list.Title = fmt.Sprintf("%s Entries %d", v.title, len(v.entries))
+  if page, more := v.page(); page > 1 || more {
+    v.list.Title += fmt.Sprintf(" page %d", page)
+    if more {
+      v.list.Title += " (n for more)"
+    }
+  }
+  if v.err != nil {
+    v.list.Title += " (" + v.err.Error() + ")"
+  }
+  if v.message != "" {
+    v.list.Title += " [" + v.message + "](fg:red)"
+  }
+  now := v.a.Now()
+  rows := make([]fmt.Stringer, len(v.entries))
+  for i, e := range v.entries {
+    rows[i] = entryRow { e: e, now: now }
+  }
+  v.list.Rows = rows
+  if v.list.SelectedRow >= len(rows) {
+    v.list.SelectedRow = Max(len(rows) - 1, 0)
+  }
+  if entry := v.selected(); entry != nil {
+    v.detail.Text = renderEntry(entry, v.a.Now())
+  } else {
+    v.detail.Text = ""
+  }
+
+  rects := vsplit(area, 0, 16)
+  setRect(v.list, rects[0])
+  setRect(v.detail, rects[1])
+  return []ui.Drawable { v.list, v.detail }
+}
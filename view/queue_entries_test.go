@@ -0,0 +1,59 @@
+package view
+
+import (
+  "image"
+  "slices"
+  "strings"
+  "testing"
+  "github.com/werkt/bf-client/client"
+  "github.com/werkt/bf-client/fake"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func entryNames(entries []*client.Entry) []string {
+  var names []string
+  for _, e := range entries {
+    names = append(names, e.String())
+  }
+  return names
+}
+
+func TestQueueEntriesUpdate(t *testing.T) {
+  s, a := faketest.Start(t)
+  names := []string { faketest.Submit(t, s, "a", "//a:1"), faketest.Submit(t, s, "a", "//a:2") }
+  v := NewQueueEntries(a, "Prequeue", []string{fake.PrequeueName}, nil).(*queueEntries)
+
+  v.Update()
+  if v.err != nil {
+    t.Fatal(v.err)
+  }
+  if got := entryNames(v.entries); !slices.Equal(got, names) {
+    t.Errorf("prequeue holds %v, want %v", got, names)
+  }
+  v.Render(image.Rect(0, 0, 80, 40))
+  if want := "Prequeue Entries 2"; v.list.Title != want {
+    t.Errorf("titled %q, want %q", v.list.Title, want)
+  }
+
+  // the server moves every entry into the queue
+  s.Step()
+  v.Update()
+  if len(v.entries) != 0 {
+    t.Errorf("prequeue holds %v after a step, want none", entryNames(v.entries))
+  }
+}
+
+func TestQueueEntriesError(t *testing.T) {
+  s, a := faketest.Start(t)
+  s.Redis.HSet("notaqueue", "field", "value")
+  v := NewQueueEntries(a, "Broken", []string{"notaqueue"}, nil).(*queueEntries)
+
+  v.Update()
+  if v.err == nil {
+    t.Fatal("read a hash as a queue")
+  }
+  v.Render(image.Rect(0, 0, 80, 40))
+  if !strings.Contains(v.list.Title, v.err.Error()) {
+    t.Errorf("titled %q, want the error %v", v.list.Title, v.err)
+  }
+}
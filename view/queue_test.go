@@ -0,0 +1,49 @@
+package view
+
+import (
+  "testing"
+  "github.com/werkt/bf-client/fake/faketest"
+)
+
+func TestQueueCollect(t *testing.T) {
+  s, a := faketest.Start(t)
+  faketest.Submit(t, s, "a", "//a:1")
+  faketest.Submit(t, s, "a", "//a:2")
+  v := NewQueue(a, 1)
+
+  if _, _, err := v.collect(); err != nil {
+    t.Fatal(err)
+  }
+  if v.prequeue.value != 2 || v.queue.value != 0 {
+    t.Errorf("prequeue %d, queue %d, want 2 and 0", v.prequeue.value, v.queue.value)
+  }
+  if a.LastReapiLatency <= 0 || a.LastRedisLatency <= 0 {
+    t.Errorf("latencies reapi %v, redis %v, want both timed", a.LastReapiLatency, a.LastRedisLatency)
+  }
+}
+
+func TestQueueProfilesWithoutHealth(t *testing.T) {
+  s, a := faketest.Start(t)
+  w, err := s.AddWorker(2)
+  if err != nil {
+    t.Fatal(err)
+  }
+  v := NewQueue(a, 1)
+  v.profileWorkers = true
+
+  // the first collection finds the workers, the second profiles them
+  for i := 0; i < 2; i++ {
+    if _, _, err := v.collect(); err != nil {
+      t.Fatal(err)
+    }
+  }
+  v.s.mutex.Lock()
+  p := v.s.profiles[w.Name]
+  v.s.mutex.Unlock()
+  if p == nil {
+    t.Fatalf("%s was not profiled", w.Name)
+  }
+  if h := a.Health.Worker(w.Name); h != nil {
+    t.Errorf("recorded the health of %s from the queue view", w.Name)
+  }
+}
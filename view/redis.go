@@ -0,0 +1,182 @@
+package view
+
+import (
+  "context"
+  "fmt"
+  "image"
+  "sort"
+  "strconv"
+  "strings"
+  "time"
+
+  "github.com/dustin/go-humanize"
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// the topology is probed at most this often
+const topologyInterval = 2 * time.Second
+
+type redisView struct {
+  a *client.App
+  v View
+  shards []*client.RedisShard
+  // queues names the queue of each key
+  queues map[string]string
+  err error
+  last time.Time
+  list *client.List
+}
+
+// NewRedis shows the shards of the redis cluster, the health of their nodes
+// and the buildfarm keys each holds
+func NewRedis(a *client.App, v View) View {
+  list := client.NewList()
+  list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
+  list.WrapText = false
+  return &redisView {
+    a: a,
+    v: v,
+    list: list,
+  }
+}
+
+func (v *redisView) Handle(e ui.Event) View {
+  switch e.ID {
+  case "<Escape>", "q", "<C-c>":
+    ui.Clear()
+    return v.v
+  case "j", "<Down>":
+    v.list.ScrollDown()
+  case "k", "<Up>":
+    v.list.ScrollUp()
+  case "J", "<PageDown>":
+    v.list.ScrollPageDown()
+  case "K", "<PageUp>":
+    v.list.ScrollPageUp()
+  case "r":
+    // probe again now
+    v.last = time.Time{}
+  case "b":
+    ui.Clear()
+    return NewKeyspace(v.a, v)
+  case "<MouseLeft>":
+    if row := v.list.RowAt(mousePoint(e)); row != -1 {
+      v.list.SelectedRow = row
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.list.ScrollAmount(wheelAmount(e))
+  }
+  return v
+}
+
+// keys are those of every queue and of the dispatched hash, by queue
+func (v *redisView) keys(ctx context.Context) (map[string]string, error) {
+  status, err := client.BackplaneStatus(context.Background(), v.a.Conn, v.a.Instance)
+  if err != nil {
+    return nil, err
+  }
+  queues := map[string]string { client.DispatchedOperationsHash: "dispatched" }
+  names := append(client.QueueNames(status, true), client.QueueNames(status, false)...)
+  for _, name := range names {
+    for _, key := range client.NewQueue(ctx, v.a.Client, name).Keys() {
+      queues[key] = name
+    }
+  }
+  return queues, nil
+}
+
+func (v *redisView) Update() {
+  if time.Since(v.last) < topologyInterval {
+    return
+  }
+  v.last = time.Now()
+  ctx := context.Background()
+  v.a.Fetches++
+  queues, err := v.keys(ctx)
+  if err != nil {
+    // the topology holds without the keys
+    queues = make(map[string]string)
+  }
+  var keys []string
+  for key := range queues {
+    keys = append(keys, key)
+  }
+  sort.Strings(keys)
+  v.shards, v.err = client.RedisTopology(ctx, v.a.Client, keys)
+  if v.err == nil {
+    v.err = err
+  }
+  v.queues = queues
+}
+
+func slotRanges(shard *client.RedisShard) string {
+  var ranges []string
+  count := int64(0)
+  for _, r := range shard.Slots {
+    if r.Start == r.End {
+      ranges = append(ranges, strconv.FormatInt(r.Start, 10))
+    } else {
+      ranges = append(ranges, fmt.Sprintf("%d-%d", r.Start, r.End))
+    }
+    count += r.End - r.Start + 1
+  }
+  return fmt.Sprintf("%s (%d slots)", strings.Join(ranges, ", "), count)
+}
+
+func renderNode(n *client.RedisNode) string {
+  role := n.Role
+  switch role {
+  case "master":
+    role = "[primary](fg:green)"
+  case "replica", "slave":
+    role = "[replica](fg:cyan)"
+  }
+  row := fmt.Sprintf("  %s %s", role, n.Addr)
+  if n.Health != "" && n.Health != "online" {
+    row += fmt.Sprintf(" [%s](fg:red)", n.Health)
+  }
+  if n.Err != nil {
+    return row + fmt.Sprintf(" [%v](fg:red)", n.Err)
+  }
+  row += fmt.Sprintf("  rtt %s", n.Latency.Round(10 * time.Microsecond))
+  if used, err := strconv.ParseUint(n.Info["used_memory"], 10, 64); err == nil {
+    row += "  mem " + humanize.Bytes(used)
+  }
+  if ops, ok := n.Info["instantaneous_ops_per_sec"]; ok {
+    row += "  ops/s " + ops
+  }
+  if clients, ok := n.Info["connected_clients"]; ok {
+    row += "  clients " + clients
+  }
+  return row
+}
+
+func (v *redisView) Render(area image.Rectangle) []ui.Drawable {
+  var rows []string
+  nodes := 0
+  for i, shard := range v.shards {
+    rows = append(rows, fmt.Sprintf("[Shard %d:](mod:bold) slots %s", i + 1, slotRanges(shard)))
+    for _, n := range shard.Nodes {
+      rows = append(rows, renderNode(n))
+      nodes++
+    }
+    for _, key := range shard.Keys {
+      if name := v.queues[key]; name != key {
+        rows = append(rows, fmt.Sprintf("    %s [(%s)](fg:yellow)", key, name))
+      } else {
+        rows = append(rows, "    " + key)
+      }
+    }
+  }
+  v.list.Rows = makeStringers(rows)
+  if v.list.SelectedRow >= len(rows) {
+    v.list.SelectedRow = Max(len(rows) - 1, 0)
+  }
+  v.list.Title = fmt.Sprintf("Redis %s: %d shards, %d nodes", v.a.RedisHost, len(v.shards), nodes)
+  if v.err != nil {
+    v.list.Title += fmt.Sprintf(" [%v](fg:red)", v.err)
+  }
+  setRect(v.list, area)
+  return []ui.Drawable { v.list }
+}
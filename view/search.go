@@ -2,6 +2,8 @@ package view
 
 import (
   "fmt"
+  "image"
+
   ui "github.com/gizak/termui/v3"
   "github.com/gizak/termui/v3/widgets"
   "github.com/werkt/bf-client/client"
@@ -55,6 +57,7 @@ type search struct {
   text *widgets.Paragraph
   layout []ui.Drawable
   t []ui.Drawable
+  form *form
 }
 
 type stringer struct {
@@ -92,6 +95,7 @@ func NewSearch(a *client.App, v View) View {
     text: text,
     t: []ui.Drawable{text, resource, filter},
     layout: []ui.Drawable{resource, filter, text},
+    form: newForm(resource, filter, text),
   }
 }
 
@@ -179,6 +183,6 @@ func (s *search) Handle(e ui.Event) View {
   return s;
 }
 
-func (v search) Render() []ui.Drawable {
-  return v.layout
+func (v search) Render(area image.Rectangle) []ui.Drawable {
+  return v.form.render(area)
 }
@@ -3,6 +3,7 @@ package view
 import (
   "context"
   "fmt"
+  "image"
   "sort"
   "strings"
   "time"
@@ -34,9 +35,6 @@ type searchResults struct {
 func NewSearchResults(resource string, filter string, value string, a *client.App, v View) View {
   list := client.NewList()
   list.SelectedRowStyle = ui.NewStyle(ui.ColorBlack, ui.ColorWhite)
-  // move to app
-  w, h := ui.TerminalDimensions()
-  list.SetRect(0, 0, w, h)
   return &searchResults{
     v: v,
     a: a,
@@ -316,10 +314,24 @@ func (s *searchResults) Handle(e ui.Event) View {
     } else if s.resource == "correlatedInvocations" {
       return NewSearchResults("toolInvocations", "correlatedInvocationsId", s.selectedName, s.a, s)
     }
+  case "<MouseLeft>":
+    p := mousePoint(e)
+    double := doubleClicked(p)
+    if row := s.list.RowAt(p); row != -1 {
+      s.list.SelectedRow = row
+      s.updateSelectedName()
+      if double {
+        return s.Handle(enterEvent)
+      }
+    }
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    s.list.ScrollAmount(wheelAmount(e))
+    s.updateSelectedName()
   }
   return s
 }
 
-func (s searchResults) Render() []ui.Drawable {
+func (s searchResults) Render(area image.Rectangle) []ui.Drawable {
+  setRect(s.list, area)
   return []ui.Drawable { s.list }
 }
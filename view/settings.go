@@ -2,6 +2,7 @@ package view
 
 import (
   "fmt"
+  "image"
   "strconv"
   ui "github.com/gizak/termui/v3"
   "github.com/gizak/termui/v3/widgets"
@@ -13,7 +14,7 @@ type settings struct {
   v View
   limitEntry *entry
   skipFramesEntry *entry
-  layout []ui.Drawable
+  form *form
 }
 
 type entry struct {
@@ -85,7 +86,7 @@ func newSettings(a *client.App, v View) View {
     v: v,
     limitEntry: limitEntry,
     skipFramesEntry: skipEntry,
-    layout: []ui.Drawable{limit, skip, limitEntry, skipEntry},
+    form: newForm(limit, skip, limitEntry, skipEntry),
   }
 
   limitEntry.onEnter = func (s string) string {
@@ -128,6 +129,6 @@ func (s *settings) Handle(e ui.Event) View {
   return s;
 }
 
-func (v settings) Render() []ui.Drawable {
-  return v.layout
+func (v settings) Render(area image.Rectangle) []ui.Drawable {
+  return v.form.render(area)
 }
@@ -1,6 +1,8 @@
 package view
 
 import (
+  "image"
+
   ui "github.com/gizak/termui/v3"
   "github.com/gizak/termui/v3/widgets"
   "github.com/werkt/bf-client/client"
@@ -14,7 +16,6 @@ type testView struct {
 
 func NewTest(a *client.App, v View) View {
   console := widgets.NewParagraph()
-  console.SetRect(0, 0, 120, 40)
   console.Title = "Console"
   console.WrapText = true
   return &testView {
@@ -24,7 +25,8 @@ func NewTest(a *client.App, v View) View {
   }
 }
 
-func (v *testView) Render() []ui.Drawable {
+func (v *testView) Render(area image.Rectangle) []ui.Drawable {
+  setRect(v.console, area)
   return []ui.Drawable { v.console }
 }
 
@@ -6,3 +6,10 @@ func Min(x, y int) int {
   }
   return x
 }
+
+func Max(x, y int) int {
+  if x < y {
+    return y
+  }
+  return x
+}
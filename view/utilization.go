@@ -0,0 +1,94 @@
+package view
+
+import (
+  "fmt"
+  "math"
+  "strings"
+  "time"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/werkt/bf-client/client"
+)
+
+// width of the utilization sparkline in the meter
+const sparkWidth = 12
+
+// workers using at least saturated of their execute slots on average are
+// highlighted, as are those using less than underused
+const saturated = 0.9
+const underused = 0.1
+
+var sparks = []rune{'▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// sparkline draws fractions of 1 in width cells, each averaging an equal
+// share of the values, a gap where there were none
+func sparkline(values []float64, width int) string {
+  chunk := Max((len(values) + width - 1) / width, 1)
+  var line []rune
+  for i := 0; i < len(values); i += chunk {
+    mean, ok := client.Mean(values[i:Min(i + chunk, len(values))])
+    if !ok {
+      line = append(line, ' ')
+      continue
+    }
+    level := int(math.Min(math.Max(mean, 0), 1) * float64(len(sparks) - 1) + 0.5)
+    line = append(line, sparks[level])
+  }
+  return strings.Repeat(" ", Max(width - len(line), 0)) + string(line)
+}
+
+// utilizationColumn is the worker's history of execute utilization and its
+// average, empty until a step has passed
+func utilizationColumn(u *client.Utilization, worker string) string {
+  ratios := u.Ratios(worker, "ExecuteActionStage")
+  if len(ratios) == 0 {
+    return ""
+  }
+  mean, ok := client.Mean(ratios)
+  if !ok {
+    return strings.Repeat(" ", sparkWidth) + "    -"
+  }
+  column := fmt.Sprintf("%s %3d%%", sparkline(ratios, sparkWidth), int(mean * 100))
+  switch {
+  case mean >= saturated:
+    return "[" + column + "](fg:red,mod:bold)"
+  case mean < underused:
+    return "[" + column + "](fg:white,mod:dim)"
+  }
+  return column
+}
+
+// utilizationChart plots the percentage of its slots the worker has used in
+// each stage over its history, nil without two steps to draw
+func utilizationChart(u *client.Utilization, worker string) *client.Chart {
+  chart := client.NewChart()
+  colors := map[string]ui.Color {
+    "InputFetchStage": ui.ColorBlue,
+    "ExecuteActionStage": ui.ColorRed,
+    "ReportResultStage": ui.ColorGreen,
+  }
+  n := 0
+  for _, stage := range client.UtilizedStages {
+    ratios := u.Ratios(worker, stage)
+    data := make([]float64, len(ratios))
+    for i, r := range ratios {
+      // gaps are drawn as idle
+      if !math.IsNaN(r) {
+        data[i] = r * 100
+      }
+    }
+    n = Max(n, len(data))
+    chart.Series = append(chart.Series, client.Series {
+      Name: strings.TrimSuffix(stage, "Stage"),
+      Data: data,
+      Color: colors[stage],
+    })
+  }
+  if n < 2 {
+    return nil
+  }
+  span := time.Duration(n) * u.Step()
+  chart.Title = "Utilization % over " + formatWindow(span)
+  chart.XLabels = []string { "-" + formatWindow(span), "now" }
+  return chart
+}
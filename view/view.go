@@ -1,12 +1,15 @@
 package view
 
 import (
+  "image"
+
   ui "github.com/gizak/termui/v3"
 )
 
 type View interface {
   Handle(ui.Event) View
   Update()
-  Render() []ui.Drawable
+  // Render lays out the view's widgets within area
+  Render(area image.Rectangle) []ui.Drawable
 }
 
@@ -4,6 +4,7 @@ import (
   "context"
   "errors"
   "fmt"
+  "image"
   "sort"
   "sync"
   "time"
@@ -11,7 +12,6 @@ import (
   reapi "github.com/bazelbuild/remote-apis/build/bazel/remote/execution/v2"
   bfpb "github.com/buildfarm/buildfarm/build/buildfarm/v1test"
   ui "github.com/gizak/termui/v3"
-  "github.com/dustin/go-humanize"
   "github.com/gizak/termui/v3/widgets"
   "github.com/golang/protobuf/ptypes"
   "github.com/werkt/bf-client/client"
@@ -39,6 +39,8 @@ type worker struct {
   field int
   reversed bool
   fetches map[string]time.Time
+  cas *client.CasTrend
+  casPanel *client.List
 }
 
 func NewStageList() *client.List {
@@ -55,6 +57,9 @@ func NewWorker(a *client.App, w string, v View) *worker {
   execute := NewStageList()
   reportResult := NewStageList()
   execute.SelectedRow = 0
+  casPanel := client.NewList()
+  casPanel.WrapText = false
+  casPanel.SelectedRow = -1
   return &worker {
     a: a,
     v: v,
@@ -66,12 +71,17 @@ func NewWorker(a *client.App, w string, v View) *worker {
     reportResult: reportResult,
     fetches: make(map[string]time.Time),
     profile: &bfpb.WorkerProfileMessage { },
+    cas: client.NewCasTrend(w),
+    casPanel: casPanel,
   }
 }
 
+func (v *worker) stageLists() []*client.List {
+  return []*client.List { v.match, v.inputFetch, v.execute, v.reportResult }
+}
+
 func (v *worker) currentOperationName() string {
-  lists := []*client.List { v.match, v.inputFetch, v.execute, v.reportResult }
-  for _, list := range lists {
+  for _, list := range v.stageLists() {
     if list.SelectedRow != -1 && list.SelectedRow < len(list.Rows) {
       return list.Rows[list.SelectedRow].(*stageEx).name
     }
@@ -97,8 +107,7 @@ func (v *worker) cancelOperation() {
 }
 
 func (v *worker) selectedList() *client.List {
-  lists := []*client.List { v.match, v.inputFetch, v.execute, v.reportResult }
-  for _, list := range lists {
+  for _, list := range v.stageLists() {
     if list.SelectedRow != -1 {
       return list
     }
@@ -144,25 +153,14 @@ func (v *worker) selectedStage() (string, bool) {
 
 func (v *worker) togglePause() {
   conn := v.a.GetWorkerConn(v.w, v.a.CA)
-  c := bfpb.NewWorkerControlClient(conn)
   stage, paused := v.selectedStage()
-  paused = !paused
-  r, err := c.PipelineChange(context.Background(), &bfpb.WorkerPipelineChangeRequest {
-    Changes: []*bfpb.PipelineChange {
-      &bfpb.PipelineChange {
-        Stage: stage,
-        Paused: paused,
-      },
-    },
+  err := client.ChangePipeline(context.Background(), conn, client.PipelineAction {
+    Stage: stage,
+    Paused: !paused,
   })
   if err != nil {
     panic(err)
   }
-  for _, change := range r.Changes {
-    if change.Stage == stage && change.Paused != paused {
-      panic("pipeline close not effective")
-    }
-  }
 }
 
 func (v *worker) increaseWidth() {
@@ -178,25 +176,14 @@ func (v *worker) decreaseWidth() {
 
 func (v *worker) changeWidth(width int32) {
   conn := v.a.GetWorkerConn(v.w, v.a.CA)
-  c := bfpb.NewWorkerControlClient(conn)
-  stage, paused := v.selectedStage()
-  r, err := c.PipelineChange(context.Background(), &bfpb.WorkerPipelineChangeRequest {
-    Changes: []*bfpb.PipelineChange {
-      &bfpb.PipelineChange {
-        Stage: stage,
-        Paused: paused,
-        Width: width,
-      },
-    },
+  stage, _ := v.selectedStage()
+  err := client.ChangePipeline(context.Background(), conn, client.PipelineAction {
+    Stage: stage,
+    Width: width,
   })
   if err != nil {
     panic(err)
   }
-  for _, change := range r.Changes {
-    if change.Stage == stage && change.Paused != paused {
-      panic("pipeline width change not effective")
-    }
-  }
 }
 
 func (v *worker) Handle(e ui.Event) View {
@@ -236,14 +223,57 @@ func (v *worker) Handle(e ui.Event) View {
     v.reversed = !v.reversed
   case "P":
     v.togglePause()
+  case "d":
+    return drain(v.a, []string { v.w }, v)
+  case "C":
+    ui.Clear()
+    return NewCasTable(v.a, v)
   case "+":
     v.increaseWidth()
   case "-":
     v.decreaseWidth()
+  case "<MouseLeft>":
+    return v.click(mousePoint(e))
+  case "<MouseWheelUp>", "<MouseWheelDown>":
+    v.wheel(mousePoint(e), wheelAmount(e))
+  }
+  return v
+}
+
+// selectList moves the selection to l, keeping its row if it has one
+func (v *worker) selectList(l *client.List) {
+  if l.SelectedRow != -1 {
+    return
+  }
+  for _, list := range v.stageLists() {
+    list.SelectedRow = -1
+  }
+  l.SelectedRow = 0
+}
+
+func (v *worker) click(p image.Point) View {
+  double := doubleClicked(p)
+  for _, list := range v.stageLists() {
+    if row := list.RowAt(p); row != -1 {
+      v.selectList(list)
+      list.SelectedRow = row
+      if double {
+        return v.Handle(enterEvent)
+      }
+    }
   }
   return v
 }
 
+func (v *worker) wheel(p image.Point, amount int) {
+  for _, list := range v.stageLists() {
+    if p.In(list.Inner) {
+      v.selectList(list)
+      list.ScrollAmount(amount)
+    }
+  }
+}
+
 type stageEx struct {
   field func () int
   name string
@@ -255,6 +285,8 @@ type stageEx struct {
   mnemonic string
   build string
   done bool
+  // now is when the row is shown, running stages measured up to it
+  now time.Time
 }
 
 func (e stageEx) label() string {
@@ -277,10 +309,9 @@ func (e stageEx) String() string {
   if e.errored {
     return fmt.Sprintf("[%s](fg:black,bg:red)", e.label())
   }
-  // needs a clock inject
   label := e.label()
   if !e.fence.IsZero() {
-    end := time.Now()
+    end := e.now
     if e.done {
       end = e.final
     }
@@ -342,8 +373,10 @@ func (v *worker) populateExecutions(l *client.List, stage string, r []string) {
   r = filterEmpty(r)
   rows := make([]fmt.Stringer, len(r))
   for i, name := range r {
-    ex := &stageEx{field: func() int { return v.field }, name: name}
+    ex := &stageEx{field: func() int { return v.field }, name: name, now: v.a.Now()}
+    v.a.Mutex.Lock()
     op, ok := v.a.Ops[name]
+    v.a.Mutex.Unlock()
     if ok {
       stalled, fence, err := stageFenced(op, stage)
       if err != nil {
@@ -438,21 +471,17 @@ func getExecution(a *client.App, name string, conn *grpc.ClientConn, wg *sync.Wa
   }
 }
 
-func (v worker) Render() []ui.Drawable {
-  v.title.Text = fmt.Sprintf(
-      "%s CAS Count: %d Size: %s (%d%%) Unref: %d%%",
-      v.w, v.profile.CasEntryCount, humanize.Bytes(uint64(v.profile.CasSize)),
-      int((float64(v.profile.CasSize) / float64(v.profile.CasMaxSize)) * 100),
-      int((float64(v.profile.CasUnreferencedEntryCount) / float64(v.profile.CasEntryCount)) * 100))
+func (v worker) Render(area image.Rectangle) []ui.Drawable {
+  v.title.Text = v.w + ", (C) fleet CAS"
   v.title.Border = false
-  v.title.SetRect(0, -1, 80, 2)
+  v.title.SetRect(area.Min.X, area.Min.Y - 1, area.Max.X, area.Min.Y + 2)
   v.match.Title = selectedTitle(v.match.SelectedRow != -1, "Match")
 
   v.reportResult.Title = selectedTitle(v.reportResult.SelectedRow != -1, "ReportResult")
 
   // need some expander logic
 
-  now := time.Now()
+  now := v.a.Now()
 
   v.a.Fetches = 0
 
@@ -465,7 +494,10 @@ func (v worker) Render() []ui.Drawable {
       if name == "" {
         continue
       }
-      if op, ok := v.a.Ops[name]; ok && op != nil {
+      v.a.Mutex.Lock()
+      op, ok := v.a.Ops[name]
+      v.a.Mutex.Unlock()
+      if ok && op != nil {
         match, err := opMatchesStage(op, stage)
         if err != nil {
           panic(err)
@@ -522,22 +554,29 @@ func (v worker) Render() []ui.Drawable {
     }
   }
 
-  row := 1
-  v.match.SetRect(0, row, 80, row + 3)
-  row += 3
-  // inputFetchRows := len(v.inputFetch.Rows)
-  inputFetchHeight := 5
-  v.inputFetch.SetRect(0, row, 80, row + 2 + inputFetchHeight)
-  row += 2 + inputFetchHeight
-  // executeRows := len(v.execute.Rows)
-  executeHeight := 5
-  v.execute.SetRect(0, row, 80, row + 2 + executeHeight)
-  row += 2 + executeHeight
-  // reportResultRows := len(v.reportResult.Rows)
-  reportResultHeight := 5
-  v.reportResult.SetRect(0, row, 80, row + 2 + reportResultHeight)
+  body := image.Rect(area.Min.X, area.Min.Y + 1, area.Max.X, area.Max.Y)
+  // match holds a single row, the rest share what is left
+  chart := utilizationChart(v.a.Utilization, v.w)
+  rects := vsplit(body, 3, 0, 0, 0, 8)
+  if chart != nil {
+    bottom := hsplit(rects[4], 0, 0)
+    setRect(chart, bottom[0])
+    setRect(v.casPanel, bottom[1])
+  } else {
+    setRect(v.casPanel, rects[4])
+  }
+  v.casPanel.Title = "CAS"
+  v.casPanel.Rows = makeStringers(casRows(v.cas))
+  setRect(v.match, rects[0])
+  setRect(v.inputFetch, rects[1])
+  setRect(v.execute, rects[2])
+  setRect(v.reportResult, rects[3])
 
-  return []ui.Drawable { v.title, v.match, v.inputFetch, v.execute, v.reportResult }
+  drawables := []ui.Drawable { v.title, v.match, v.inputFetch, v.execute, v.reportResult, v.casPanel }
+  if chart != nil {
+    drawables = append(drawables, chart)
+  }
+  return drawables
 }
 
 func pausedStyle(p bool) ui.Style {
@@ -554,6 +593,11 @@ func (v *worker) Update() {
   profile, err := workerProfile.GetWorkerProfile(context.Background(), &bfpb.WorkerProfileRequest {})
   if err == nil {
     v.profile = profile
+    now := v.a.Now()
+    v.a.Utilization.Record(v.w, now, profile)
+    v.cas.Record(now, profile)
+  } else {
+    v.cas.Err = err
   }
   c := bfpb.NewWorkerControlClient(conn)
   r, err := c.PipelineChange(context.Background(), &bfpb.WorkerPipelineChangeRequest {})
@@ -0,0 +1,265 @@
+package view
+
+import (
+  "fmt"
+  "image"
+  "strconv"
+
+  ui "github.com/gizak/termui/v3"
+  "github.com/gizak/termui/v3/widgets"
+  "github.com/werkt/bf-client/client"
+)
+
+// pane is a leaf holding a view, or a split holding two panes
+type pane struct {
+  v View
+  parent *pane
+  children []*pane
+  // children side by side rather than stacked
+  horizontal bool
+  area image.Rectangle
+}
+
+func (p *pane) leaves() []*pane {
+  if p.children == nil {
+    return []*pane { p }
+  }
+  var leaves []*pane
+  for _, c := range p.children {
+    leaves = append(leaves, c.leaves()...)
+  }
+  return leaves
+}
+
+type tab struct {
+  root *pane
+  focus *pane
+}
+
+// workspace hosts views in tabs of split panes. All panes share one App,
+// only the focused pane receives key events, and every pane on the current
+// tab is updated and rendered.
+type workspace struct {
+  a *client.App
+  newView func() View
+  tabs []*tab
+  current int
+  // <C-w> was pressed, the next key is a workspace command
+  command bool
+}
+
+func newTab(v View) *tab {
+  root := &pane { v: v }
+  return &tab {
+    root: root,
+    focus: root,
+  }
+}
+
+// NewWorkspace starts with a single tab showing v. newView creates the view
+// for new panes and tabs.
+func NewWorkspace(a *client.App, v View, newView func() View) View {
+  return &workspace {
+    a: a,
+    newView: newView,
+    tabs: []*tab { newTab(v) },
+  }
+}
+
+func (w *workspace) tab() *tab {
+  return w.tabs[w.current]
+}
+
+func (w *workspace) split(horizontal bool) {
+  t := w.tab()
+  p := t.focus
+  // the focused pane becomes a split holding its old view and a new one
+  first := &pane { v: p.v, parent: p }
+  second := &pane { v: w.newView(), parent: p }
+  p.v = nil
+  p.children = []*pane { first, second }
+  p.horizontal = horizontal
+  t.focus = second
+}
+
+func (w *workspace) close() {
+  t := w.tab()
+  p := t.focus
+  if p.parent == nil {
+    if len(w.tabs) == 1 {
+      return
+    }
+    w.tabs = append(w.tabs[:w.current], w.tabs[w.current + 1:]...)
+    w.current = Min(w.current, len(w.tabs) - 1)
+    return
+  }
+  // the sibling takes the place of the parent split
+  parent := p.parent
+  sibling := parent.children[0]
+  if sibling == p {
+    sibling = parent.children[1]
+  }
+  parent.v = sibling.v
+  parent.children = sibling.children
+  parent.horizontal = sibling.horizontal
+  for _, c := range parent.children {
+    c.parent = parent
+  }
+  t.focus = parent.leaves()[0]
+}
+
+func (w *workspace) cycleFocus(amount int) {
+  t := w.tab()
+  leaves := t.root.leaves()
+  for i, p := range leaves {
+    if p == t.focus {
+      n := len(leaves)
+      t.focus = leaves[(i + amount + n) % n]
+      return
+    }
+  }
+}
+
+func (w *workspace) cycleTab(amount int) {
+  n := len(w.tabs)
+  w.current = (w.current + amount + n) % n
+}
+
+func (w *workspace) panes() int {
+  n := 0
+  for _, t := range w.tabs {
+    n += len(t.root.leaves())
+  }
+  return n
+}
+
+func (w *workspace) handleCommand(e ui.Event) {
+  switch e.ID {
+  case "v":
+    w.split(true)
+  case "s":
+    w.split(false)
+  case "w", "<Tab>":
+    w.cycleFocus(1)
+  case "W":
+    w.cycleFocus(-1)
+  case "c", "q":
+    w.close()
+  case "t":
+    w.tabs = append(w.tabs, newTab(w.newView()))
+    w.current = len(w.tabs) - 1
+  case "n":
+    w.cycleTab(1)
+  case "p":
+    w.cycleTab(-1)
+  case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+    i, _ := strconv.Atoi(e.ID)
+    if i <= len(w.tabs) {
+      w.current = i - 1
+    }
+  }
+}
+
+func (w *workspace) paneAt(p image.Point) *pane {
+  for _, l := range w.tab().root.leaves() {
+    if p.In(l.area) {
+      return l
+    }
+  }
+  return nil
+}
+
+func (w *workspace) Handle(e ui.Event) View {
+  if w.command {
+    w.command = false
+    w.handleCommand(e)
+    ui.Clear()
+    return w
+  }
+  t := w.tab()
+  target := t.focus
+  switch e.ID {
+  case "<C-w>":
+    w.command = true
+    return w
+  case "<MouseLeft>", "<MouseWheelUp>", "<MouseWheelDown>":
+    target = w.paneAt(mousePoint(e))
+    if target == nil {
+      return w
+    }
+    if e.ID == "<MouseLeft>" {
+      t.focus = target
+    }
+  }
+  target.v = target.v.Handle(e)
+  // quitting the last view of a pane closes the pane instead of the client
+  if w.a.Done && w.panes() > 1 {
+    w.a.Done = false
+    t.focus = target
+    w.close()
+    ui.Clear()
+  }
+  return w
+}
+
+func (w *workspace) Update() {
+  for _, p := range w.tab().root.leaves() {
+    p.v.Update()
+  }
+}
+
+func (w *workspace) renderPane(p *pane, area image.Rectangle) []ui.Drawable {
+  p.area = area
+  if p.children == nil {
+    return p.v.Render(area)
+  }
+  var areas []image.Rectangle
+  if p.horizontal {
+    areas = hsplit(area, 0, 0)
+  } else {
+    areas = vsplit(area, 0, 0)
+  }
+  return append(w.renderPane(p.children[0], areas[0]), w.renderPane(p.children[1], areas[1])...)
+}
+
+func (w *workspace) renderTabs() ui.Drawable {
+  tabs := widgets.NewParagraph()
+  tabs.Border = false
+  for i := range w.tabs {
+    label := fmt.Sprintf(" %d ", i + 1)
+    if i == w.current {
+      label = "[" + label + "](fg:black,bg:white)"
+    }
+    tabs.Text += label
+  }
+  return tabs
+}
+
+func (w *workspace) Render(area image.Rectangle) []ui.Drawable {
+  var drawables []ui.Drawable
+  if len(w.tabs) > 1 {
+    bar := w.renderTabs()
+    bar.SetRect(area.Min.X - 1, area.Min.Y - 1, area.Max.X + 1, area.Min.Y + 2)
+    // drawn first, its buffer would clear the row below it
+    drawables = append(drawables, bar)
+    area.Min.Y++
+  }
+  t := w.tab()
+  drawables = append(drawables, w.renderPane(t.root, area)...)
+  if t.root.children != nil {
+    marker := &focusMarker { Block: *ui.NewBlock() }
+    min := t.focus.area.Min
+    marker.SetRect(min.X, min.Y, min.X + 1, min.Y + 1)
+    drawables = append(drawables, marker)
+  }
+  return drawables
+}
+
+// focusMarker overwrites the top left corner of the focused pane
+type focusMarker struct {
+  ui.Block
+}
+
+func (m *focusMarker) Draw(buf *ui.Buffer) {
+  buf.SetCell(ui.NewCell('*', ui.NewStyle(ui.ColorYellow, ui.ColorClear, ui.ModifierBold)), m.Min)
+}